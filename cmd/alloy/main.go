@@ -1,17 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/jharvey10/test-repo/internal/runner"
+	"github.com/jharvey10/test-repo/internal/alloycli"
+	_ "github.com/jharvey10/test-repo/internal/component/all" // Register all components
 )
 
 func main() {
-	fmt.Println("Starting Alloy wow \\{^_^}/")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	r := runner.New()
-	if err := r.Run(); err != nil {
+	if err := alloycli.Command().ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
@@ -2,37 +2,99 @@ package baseengine
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	BaseEngineConfig BaseEngineConfig  `mapstructure:"config"`
-	Flags            map[string]string `mapstructure:"flags"`
+	BaseEngineConfig BaseEngineConfig `mapstructure:"config"`
+	Flags            Flags            `mapstructure:"flags"`
 }
 
 // This type represents the incoming format of the BaseEngine configuration
 // This is a one-of type, and it is expected that only one of the fields will be set (ie, we cannot define multiple config sources of different types)
 type BaseEngineConfig struct {
+	// File is the path of a configuration file.
 	File string `mapstructure:"file"`
+	// Inline is configuration embedded in the collector's configuration.
+	Inline string `mapstructure:"inline"`
+	// Directory is the path of a directory whose .alloy files are loaded
+	// together as one configuration.
+	Directory string `mapstructure:"directory"`
+	// URL is fetched for the configuration, and polled for changes every
+	// PollFrequency.
+	URL           string        `mapstructure:"url"`
+	PollFrequency time.Duration `mapstructure:"poll_frequency"`
 }
 
-func (cfg *Config) flagsAsSlice() []string {
-	flags := []string{}
-	for k, v := range cfg.Flags {
-		flags = append(flags, fmt.Sprintf("--%s=%s", k, v))
+// sources returns the names of the config sources which are set.
+func (cfg *BaseEngineConfig) sources() []string {
+	var names []string
+	for _, src := range []struct {
+		name string
+		set  bool
+	}{
+		{"config.file", cfg.File != ""},
+		{"config.inline", cfg.Inline != ""},
+		{"config.directory", cfg.Directory != ""},
+		{"config.url", cfg.URL != ""},
+	} {
+		if src.set {
+			names = append(names, src.name)
+		}
 	}
-	return flags
+	return names
 }
 
 func (cfg *Config) Validate() error {
-	if cfg.BaseEngineConfig.File == "" {
-		return fmt.Errorf("config.file is required")
+	if err := cfg.BaseEngineConfig.Validate(); err != nil {
+		return err
 	}
+	return cfg.Flags.Validate()
+}
 
-	_, err := os.Stat(cfg.BaseEngineConfig.File)
-	if err != nil {
-		return fmt.Errorf("provided config path %s does not exist or is not readable: %w", cfg.BaseEngineConfig.File, err)
+func (cfg *BaseEngineConfig) Validate() error {
+	switch sources := cfg.sources(); len(sources) {
+	case 0:
+		return fmt.Errorf("one of config.file, config.inline, config.directory or config.url is required")
+	case 1:
+	default:
+		return fmt.Errorf("only one config source may be set, got %s", strings.Join(sources, ", "))
 	}
 
+	switch {
+	case cfg.File != "":
+		_, err := os.Stat(cfg.File)
+		if err != nil {
+			return fmt.Errorf("provided config path %s does not exist or is not readable: %w", cfg.File, err)
+		}
+
+	case cfg.Directory != "":
+		fi, err := os.Stat(cfg.Directory)
+		if err != nil {
+			return fmt.Errorf("provided config directory %s does not exist or is not readable: %w", cfg.Directory, err)
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("provided config directory %s is not a directory", cfg.Directory)
+		}
+
+	case cfg.URL != "":
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			return fmt.Errorf("invalid config.url: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("config.url %s must be an http or https URL", cfg.URL)
+		}
+	}
+
+	if cfg.PollFrequency < 0 {
+		return fmt.Errorf("config.poll_frequency must not be negative")
+	}
+	if cfg.PollFrequency != 0 && cfg.URL == "" {
+		return fmt.Errorf("config.poll_frequency can only be set with config.url")
+	}
 	return nil
 }
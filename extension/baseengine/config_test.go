@@ -0,0 +1,46 @@
+package baseengine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBaseEngineConfig_Validate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.alloy")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name   string
+		cfg    BaseEngineConfig
+		expect string // Substring of the expected error, if any.
+	}{
+		{"file", BaseEngineConfig{File: file}, ""},
+		{"missing file", BaseEngineConfig{File: filepath.Join(dir, "missing.alloy")}, "does not exist"},
+		{"inline", BaseEngineConfig{Inline: `prometheus.scrape "default" {}`}, ""},
+		{"directory", BaseEngineConfig{Directory: dir}, ""},
+		{"directory is a file", BaseEngineConfig{Directory: file}, "is not a directory"},
+		{"url", BaseEngineConfig{URL: "https://example.com/config.alloy", PollFrequency: time.Minute}, ""},
+		{"url without scheme", BaseEngineConfig{URL: "example.com/config.alloy"}, "must be an http or https URL"},
+		{"url with other scheme", BaseEngineConfig{URL: "ftp://example.com/config.alloy"}, "must be an http or https URL"},
+		{"negative poll frequency", BaseEngineConfig{URL: "https://example.com", PollFrequency: -time.Second}, "must not be negative"},
+		{"poll frequency without url", BaseEngineConfig{File: file, PollFrequency: time.Minute}, "can only be set with config.url"},
+		{"no source", BaseEngineConfig{}, "one of config.file, config.inline, config.directory or config.url is required"},
+		{"two sources", BaseEngineConfig{File: file, Inline: "x"}, "got config.file, config.inline"},
+		{"all sources", BaseEngineConfig{File: file, Inline: "x", Directory: dir, URL: "https://example.com"}, "got config.file, config.inline, config.directory, config.url"},
+	}
+	for _, tc := range tt {
+		err := tc.cfg.Validate()
+		switch {
+		case tc.expect == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		case tc.expect != "" && (err == nil || !strings.Contains(err.Error(), tc.expect)):
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.expect, err)
+		}
+	}
+}
@@ -27,7 +27,7 @@ func NewFactory() extension.Factory {
 
 // createDefaultConfig creates the default configuration for the extension.
 func createDefaultConfig() component.Config {
-	return &Config{}
+	return &Config{Flags: defaultFlags()}
 }
 
 // createExtension creates an baseengine extension instance.
@@ -0,0 +1,83 @@
+package baseengine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jharvey10/test-repo/internal/alloycli"
+)
+
+// Flags are the flags of the run command which the runner is started with.
+type Flags struct {
+	StoragePath    string `mapstructure:"storage.path"`
+	StabilityLevel string `mapstructure:"stability.level"`
+	HTTPListenAddr string `mapstructure:"server.http.listen-addr"`
+	LogLevel       string `mapstructure:"log.level"`
+
+	// Unknown holds any flags which aren't supported, so that Validate can
+	// report them along with the supported ones.
+	Unknown map[string]any `mapstructure:",remain"`
+}
+
+// defaultFlags returns the run command's defaults.
+func defaultFlags() Flags {
+	opts, err := alloycli.RunnerOptions(nil)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default flags: %v", err))
+	}
+	return Flags{
+		StoragePath:    opts.StoragePath,
+		StabilityLevel: opts.MinStability.String(),
+		HTTPListenAddr: opts.HTTPListenAddr,
+		LogLevel:       opts.LogLevel.String(),
+	}
+}
+
+// flag is a flag and its value.
+type flag struct {
+	name, value string
+}
+
+// list returns the supported flags sorted by name.
+func (f *Flags) list() []flag {
+	return []flag{
+		{"log.level", f.LogLevel},
+		{"server.http.listen-addr", f.HTTPListenAddr},
+		{"stability.level", f.StabilityLevel},
+		{"storage.path", f.StoragePath},
+	}
+}
+
+// args renders the flags as command line arguments, in the order of list.
+func (f *Flags) args() []string {
+	list := f.list()
+	args := make([]string, 0, len(list))
+	for _, fl := range list {
+		args = append(args, fmt.Sprintf("--%s=%s", fl.name, fl.value))
+	}
+	return args
+}
+
+// Validate checks that only supported flags are set, and that the run
+// command accepts their values.
+func (f *Flags) Validate() error {
+	if len(f.Unknown) > 0 {
+		unknown := make([]string, 0, len(f.Unknown))
+		for name := range f.Unknown {
+			unknown = append(unknown, fmt.Sprintf("%q", name))
+		}
+		sort.Strings(unknown)
+
+		valid := make([]string, 0, len(f.list()))
+		for _, fl := range f.list() {
+			valid = append(valid, fl.name)
+		}
+		return fmt.Errorf("unknown flags %s, valid flags are: %s", strings.Join(unknown, ", "), strings.Join(valid, ", "))
+	}
+
+	if _, err := alloycli.RunnerOptions(f.args()); err != nil {
+		return fmt.Errorf("invalid flags: %w", err)
+	}
+	return nil
+}
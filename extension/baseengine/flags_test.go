@@ -0,0 +1,92 @@
+package baseengine
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestFlags_Args(t *testing.T) {
+	flags := Flags{
+		StoragePath:    "/var/lib/alloy",
+		StabilityLevel: "public-preview",
+		HTTPListenAddr: "0.0.0.0:12345",
+		LogLevel:       "debug",
+	}
+	expect := "--log.level=debug --server.http.listen-addr=0.0.0.0:12345 --stability.level=public-preview --storage.path=/var/lib/alloy"
+
+	// Rendering must not depend on map iteration order.
+	for i := 0; i < 10; i++ {
+		if actual := strings.Join(flags.args(), " "); actual != expect {
+			t.Fatalf("expected %s, got %s", expect, actual)
+		}
+	}
+}
+
+func TestFlags_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		modify func(f *Flags)
+		expect string // Substring of the expected error, if any.
+	}{
+		{"defaults", func(*Flags) {}, ""},
+		{"disabled HTTP server", func(f *Flags) { f.HTTPListenAddr = "" }, ""},
+		{"experimental", func(f *Flags) { f.StabilityLevel = "experimental" }, ""},
+		{"invalid stability level", func(f *Flags) { f.StabilityLevel = "beta" }, `invalid stability level "beta", must be one of experimental, public-preview, generally-available`},
+		{"invalid log level", func(f *Flags) { f.LogLevel = "verbose" }, `invalid --log.level "verbose"`},
+		{"invalid listen address", func(f *Flags) { f.HTTPListenAddr = "12345" }, "invalid --server.http.listen-addr"},
+		{
+			"unknown flags",
+			func(f *Flags) { f.Unknown = map[string]any{"storage-path": "x", "config.file": "y"} },
+			`unknown flags "config.file", "storage-path", valid flags are: log.level, server.http.listen-addr, stability.level, storage.path`,
+		},
+	}
+	for _, tc := range tt {
+		flags := defaultFlags()
+		tc.modify(&flags)
+
+		err := flags.Validate()
+		switch {
+		case tc.expect == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		case tc.expect != "" && (err == nil || !strings.Contains(err.Error(), tc.expect)):
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.expect, err)
+		}
+	}
+}
+
+func TestConfig_Unmarshal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.alloy")
+	newConfig := func(flags map[string]any) (*Config, error) {
+		cfg := createDefaultConfig().(*Config)
+		err := confmap.NewFromStringMap(map[string]any{
+			"config": map[string]any{"inline": `prometheus.scrape "default" {}`},
+			"flags":  flags,
+		}).Unmarshal(cfg)
+		return cfg, err
+	}
+
+	cfg, err := newConfig(map[string]any{"log.level": "debug", "storage.path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	defaults := defaultFlags()
+	if cfg.Flags.LogLevel != "debug" || cfg.Flags.StoragePath != path || cfg.Flags.HTTPListenAddr != defaults.HTTPListenAddr {
+		t.Errorf("expected set flags to override the defaults, got %+v", cfg.Flags)
+	}
+
+	// Unknown flags are reported by Validate rather than Unmarshal, so that
+	// the error can list the valid ones.
+	cfg, err = newConfig(map[string]any{"log-level": "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown flags "log-level", valid flags are:`) {
+		t.Errorf("expected an error for the unknown flag, got %v", err)
+	}
+}
@@ -2,14 +2,38 @@ package baseengine
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"github.com/jharvey10/test-repo/internal/alloycli"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/internal/importsource"
+	"github.com/jharvey10/test-repo/internal/runner"
+	"github.com/jharvey10/test-repo/syntax/diag"
 )
 
 // baseEngineExtension is the extension for the baseengine.
 type baseEngineExtension struct {
 	config            *Config
 	telemetrySettings component.TelemetrySettings
+
+	// cancel stops the runner, and done receives the result of its Run
+	// method. Both are nil until Start is called.
+	cancel context.CancelFunc
+	done   chan error
+
+	// statusInterval is how often component health is reported to the
+	// collector.
+	statusInterval time.Duration
+	// reloadDelay is how long a watched config must stay unchanged before
+	// it is reloaded.
+	reloadDelay time.Duration
 }
 
 // newBaseEngineExtension creates a new baseengine extension.
@@ -17,15 +41,303 @@ func newBaseEngineExtension(config *Config, telemetrySettings component.Telemetr
 	return &baseEngineExtension{
 		config:            config,
 		telemetrySettings: telemetrySettings,
+		statusInterval:    defaultStatusInterval,
+		reloadDelay:       defaultReloadDelay,
 	}
 }
 
-// Start starts the baseengine extension.
-func (e *baseEngineExtension) Start(ctx context.Context, host component.Host) error {
+// Start starts the baseengine extension. It runs the native runner with
+// the configuration from the configured source and flags in the
+// background, until Shutdown is called, and reports the health of its
+// components to host. Invalid flags are reported as a permanent error, and
+// the runner isn't started.
+func (e *baseEngineExtension) Start(_ context.Context, host component.Host) error {
+	status := newStatusReporter(host)
+	opts, err := alloycli.RunnerOptions(e.config.Flags.args())
+	if err != nil {
+		e.telemetrySettings.Logger.Error("invalid base engine flags, not starting", zap.Error(err))
+		status.reportPermanent(fmt.Errorf("invalid flags: %w", err))
+		return nil
+	}
+	// The runner's logs and metrics go through the collector's own
+	// telemetry pipeline.
+	opts.Logger = e.telemetrySettings.Logger
+	opts.MeterProvider = e.telemetrySettings.MeterProvider
+
+	// The context passed to Start only covers starting the extension, so
+	// the runner gets its own context which Shutdown cancels.
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel, e.done = cancel, make(chan error, 1)
+
+	go func() { e.done <- e.run(ctx, opts, status) }()
 	return nil
 }
 
-// Shutdown shuts down the baseengine extension.
+// run keeps the runner running with the latest valid config until ctx is
+// canceled, and returns the result of the runner's Run method.
+//
+// The config is reloaded when polling a URL, or watching a config file or
+// directory, finds a change, and when a module it imports changes. The
+// runner is restarted with a changed config once it loads, and also with an
+// unchanged one if it failed or its imported modules changed, so a source
+// which is unavailable at first or a runner which fails is retried on the
+// next change or poll. Configs which fail to load leave the current config
+// running. Errors are logged and reported as the extension's status, along
+// with the health of the runner's components. An invalid inline config is
+// a permanent error, since it can't change.
+func (e *baseEngineExtension) run(ctx context.Context, opts runner.Options, status *statusReporter) error {
+	source, sourceName := e.config.BaseEngineConfig.source()
+	_, isInline := source.(*inlineSource)
+	logger := e.telemetrySettings.Logger.With(zap.String("source", sourceName))
+
+	// Changes are watched before loading the config, so that none are
+	// missed in between.
+	var (
+		events    <-chan fsnotify.Event
+		watchErrs <-chan error
+	)
+	if dir := e.config.BaseEngineConfig.watchDir(); dir != "" {
+		w, err := newWatcher(dir)
+		if err != nil {
+			logger.Error("failed to watch the base engine config, changes won't be reloaded", zap.Error(err))
+		} else {
+			defer w.Close()
+			events, watchErrs = w.Events, w.Errors
+		}
+	}
+
+	var (
+		// eng is the last runner started, which keeps reporting the health
+		// of its components after it exits. done is nil unless it is
+		// running.
+		eng  *runner.Engine
+		done <-chan struct{}
+		hash [sha256.Size]byte
+
+		// loadErr is the error loading the latest config, and runErr the
+		// error the runner exited with. permanent is set when loadErr
+		// can't be fixed by retrying.
+		loadErr, runErr error
+		permanent       bool
+	)
+
+	reportStatus := func() {
+		switch {
+		case loadErr != nil && permanent:
+			status.reportPermanent(loadErr)
+		case loadErr != nil:
+			status.reportError(loadErr)
+		case runErr != nil:
+			status.reportError(runErr)
+		case eng != nil:
+			status.reportHealth(eng.Components())
+		}
+	}
+
+	// reload starts loading the current config in the background, so that
+	// a slow source doesn't hold up the loop. The runner is restarted with
+	// the config once it loads if it changed and is valid, or if the
+	// runner failed. force restarts it regardless, to load the latest
+	// content of imported modules. Reloads requested while one is loading
+	// are merged into a single reload which starts once it completes.
+	var (
+		loaded  = make(chan loadResult, 1)
+		loading bool
+		// pending is set when a reload was requested while loading, and
+		// pendingForce when any of those reloads was forced.
+		pending, pendingForce bool
+	)
+	// The config is parsed and decoded through a cache, so that reloading
+	// it only parses and decodes the blocks which changed. Loads never
+	// overlap, so the cache is never used concurrently.
+	opts.ConfigCache = config.NewCache()
+	reload := func(force bool) {
+		if loading {
+			pending, pendingForce = true, pendingForce || force
+			return
+		}
+		loading = true
+		skipUnchanged := eng != nil && runErr == nil && !force
+		go func(prev [sha256.Size]byte) {
+			loaded <- load(ctx, source, opts, prev, skipUnchanged)
+		}(hash)
+	}
+
+	// apply restarts the runner with a loaded config.
+	apply := func(res loadResult) {
+		if res.unchanged {
+			loadErr = nil
+			return
+		}
+		if res.err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			loadErr = fmt.Errorf("loading %s: %w", sourceName, res.err)
+			permanent = res.invalid && isInline
+			if eng == nil {
+				logger.Error("failed to load the base engine config, retrying on the next change", zap.Error(res.err))
+			} else {
+				logger.Warn("failed to reload the base engine config, keeping the current config", zap.Error(res.err))
+			}
+			return
+		}
+
+		if done != nil {
+			_ = eng.Stop()
+		}
+		started := eng == nil
+		eng, hash, loadErr, runErr = res.eng, res.hash, nil, nil
+		eng.Start(ctx)
+		done = eng.Done()
+		if started {
+			logger.Info("started the base engine")
+		} else {
+			logger.Info("reloaded the base engine config")
+		}
+	}
+
+	statusTicker := time.NewTicker(e.statusInterval)
+	defer statusTicker.Stop()
+
+	var poll <-chan time.Time
+	if freq := e.config.BaseEngineConfig.pollFrequency(); freq > 0 {
+		pollTicker := time.NewTicker(freq)
+		defer pollTicker.Stop()
+		poll = pollTicker.C
+	}
+
+	var (
+		debounce  *time.Timer
+		debounced <-chan time.Time
+	)
+	defer func() {
+		if debounce != nil {
+			debounce.Stop()
+		}
+	}()
+
+	reload(false)
+	for {
+		var changed <-chan struct{}
+		if eng != nil {
+			changed = eng.Changed()
+		}
+
+		select {
+		case <-ctx.Done():
+			if done == nil {
+				return nil
+			}
+			return eng.Err()
+
+		case <-done:
+			err := eng.Err()
+			done = nil
+			if ctx.Err() != nil {
+				return err
+			}
+			if err != nil {
+				runErr = fmt.Errorf("base engine stopped: %w", err)
+				logger.Error("base engine stopped unexpectedly, retrying on the next change", zap.Error(err))
+			} else {
+				logger.Info("base engine components completed")
+			}
+			reportStatus()
+
+		case res := <-loaded:
+			loading = false
+			apply(res)
+			reportStatus()
+			if pending {
+				force := pendingForce
+				pending, pendingForce = false, false
+				reload(force)
+			}
+
+		case <-statusTicker.C:
+			reportStatus()
+
+		case <-poll:
+			reload(false)
+
+		case <-changed:
+			reload(true)
+
+		case ev := <-events:
+			if !isChange(ev) {
+				continue
+			}
+			if debounce == nil {
+				debounce = time.NewTimer(e.reloadDelay)
+			} else {
+				debounce.Reset(e.reloadDelay)
+			}
+			debounced = debounce.C
+
+		case <-debounced:
+			debounced = nil
+			reload(false)
+
+		case err := <-watchErrs:
+			logger.Warn("error watching the base engine config", zap.Error(err))
+		}
+	}
+}
+
+// loadResult is the result of loading the config in the background.
+type loadResult struct {
+	eng  *runner.Engine
+	hash [sha256.Size]byte
+	err  error
+	// unchanged is set instead of eng when the config didn't change and
+	// the runner doesn't need restarting.
+	unchanged bool
+	// invalid is set when err comes from the config itself rather than
+	// from fetching it or the modules it imports.
+	invalid bool
+}
+
+// load fetches and parses the config of source through opts.ConfigCache
+// and creates an engine for it, unless skipUnchanged is set and its hash is
+// prev.
+func load(ctx context.Context, source importsource.Source, opts runner.Options, prev [sha256.Size]byte, skipUnchanged bool) loadResult {
+	f, hash, err := loadConfig(ctx, source, opts.ConfigCache)
+	if err == nil && skipUnchanged && hash == prev {
+		return loadResult{hash: hash, unchanged: true}
+	}
+	if err == nil {
+		err = opts.ConfigCache.ValidateFile(f).Err()
+	}
+	if err != nil {
+		// Syntax and validation errors are diagnostics, unlike errors
+		// fetching the config.
+		var ds diag.Diagnostics
+		return loadResult{err: err, invalid: errors.As(err, &ds)}
+	}
+	eng, err := runner.NewEngine(opts, f)
+	if err != nil {
+		return loadResult{err: err}
+	}
+	return loadResult{eng: eng, hash: hash}
+}
+
+// Shutdown shuts down the baseengine extension. It stops the runner and
+// waits for it to exit, or for ctx to be done.
 func (e *baseEngineExtension) Shutdown(ctx context.Context) error {
-	return nil
+	if e.cancel == nil {
+		return nil
+	}
+	e.cancel()
+
+	select {
+	case err := <-e.done:
+		e.cancel = nil
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for the base engine to stop: %w", ctx.Err())
+	}
 }
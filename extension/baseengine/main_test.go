@@ -0,0 +1,385 @@
+package baseengine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	_ "github.com/jharvey10/test-repo/internal/component/prometheus"
+)
+
+// testFlags returns the default flags with the given HTTP listen address,
+// storing data in a temporary directory.
+func testFlags(t *testing.T, httpListenAddr string) Flags {
+	flags := defaultFlags()
+	flags.HTTPListenAddr = httpListenAddr
+	flags.StoragePath = t.TempDir()
+	return flags
+}
+
+func newTestExtension(t *testing.T, config string, flags Flags) *baseEngineExtension {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.alloy")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{BaseEngineConfig: BaseEngineConfig{File: path}, Flags: flags}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	e := newBaseEngineExtension(cfg, component.TelemetrySettings{Logger: zap.NewNop()})
+	e.statusInterval = 10 * time.Millisecond
+	return e
+}
+
+// waitForStatuses waits for host to have been reported expect.
+func waitForStatuses(t *testing.T, host *statusHost, expect string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for host.statuses() != expect {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected statuses %q, got %q", expect, host.statuses())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForLog waits for n log lines with msg to have been written.
+func waitForLog(t *testing.T, logs *observer.ObservedLogs, msg string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage(msg).Len() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d log lines %q, got %v", n, msg, logs.All())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartShutdown(t *testing.T) {
+	e := newTestExtension(t, "prometheus.scrape \"default\" {\n\ttargets = []\n}\n", testFlags(t, "127.0.0.1:0"))
+	host := &statusHost{}
+	if err := e.Start(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+
+	// The runner keeps serving HTTP until Shutdown stops it.
+	select {
+	case err := <-e.done:
+		t.Fatalf("runner exited before Shutdown: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// The collector reports OK itself, so there's no change to report.
+	if statuses := host.statuses(); statuses != "" {
+		t.Errorf("expected no statuses to be reported, got %q", statuses)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("second Shutdown: %v", err)
+	}
+}
+
+func TestShutdownWithoutStart(t *testing.T) {
+	e := newTestExtension(t, "", testFlags(t, ""))
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStart_InvalidFlags(t *testing.T) {
+	e := newTestExtension(t, "", testFlags(t, ""))
+	e.config.Flags.LogLevel = "verbose"
+	host := &statusHost{}
+	if err := e.Start(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatuses(t, host, "StatusPermanentError")
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestStart_InvalidInlineConfig(t *testing.T) {
+	cfg := &Config{
+		BaseEngineConfig: BaseEngineConfig{Inline: `prometheus.scrape "a" { job_name = 12 }`},
+		Flags:            testFlags(t, ""),
+	}
+	e := newBaseEngineExtension(cfg, component.TelemetrySettings{Logger: zap.NewNop()})
+	e.statusInterval = 10 * time.Millisecond
+	host := &statusHost{}
+	if err := e.Start(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+
+	// The inline config can't change, so the error is permanent.
+	waitForStatuses(t, host, "StatusPermanentError")
+	host.mut.Lock()
+	err := host.events[0].Err()
+	host.mut.Unlock()
+	if err == nil || !strings.Contains(err.Error(), "inline.alloy:1:36: expected string, got number") {
+		t.Errorf("expected the error loading the config, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+// writeFile writes config to path, failing the test on errors.
+func writeFile(t *testing.T, path, config string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStart_InvalidConfig(t *testing.T) {
+	e := newTestExtension(t, "prometheus.scrap \"default\" {}\n", testFlags(t, ""))
+	e.reloadDelay = 10 * time.Millisecond
+	host := &statusHost{}
+	if err := e.Start(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatuses(t, host, "StatusRecoverableError")
+	host.mut.Lock()
+	err := host.events[0].Err()
+	host.mut.Unlock()
+	if err == nil || !strings.Contains(err.Error(), `unrecognized component name "prometheus.scrap"`) {
+		t.Errorf("expected the error loading the config, got %v", err)
+	}
+
+	// The config keeps being watched, so fixing it starts the runner.
+	writeFile(t, e.config.BaseEngineConfig.File, "prometheus.scrape \"default\" { targets = [] }\n")
+	waitForStatuses(t, host, "StatusRecoverableError,StatusOK")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestStart_ReloadAfterExit(t *testing.T) {
+	// Without an HTTP server, the runner exits once its components
+	// complete.
+	e := newTestExtension(t, `prometheus.scrape "a" { targets = [] }`, testFlags(t, ""))
+	e.reloadDelay = 10 * time.Millisecond
+	core, logs := observer.New(zap.InfoLevel)
+	e.telemetrySettings.Logger = zap.New(core)
+	host := &statusHost{}
+	if err := e.Start(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+	waitForLog(t, logs, "base engine components completed", 1)
+
+	// Changes are still reloaded, and reported.
+	writeFile(t, e.config.BaseEngineConfig.File, `prometheus.scrap "a" {}`)
+	waitForStatuses(t, host, "StatusRecoverableError")
+	writeFile(t, e.config.BaseEngineConfig.File, `prometheus.scrape "b" { targets = [] }`)
+	waitForStatuses(t, host, "StatusRecoverableError,StatusOK")
+	waitForLog(t, logs, "reloaded the base engine config", 1)
+	waitForLog(t, logs, "base engine components completed", 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestStart_ImportReload(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "module.alloy")
+	writeFile(t, module, `prometheus.scrape "a" { targets = [] }`)
+	e := newTestExtension(t, fmt.Sprintf(`import.file "shared" {
+	filename       = %q
+	poll_frequency = "10ms"
+}
+`, module), testFlags(t, ""))
+	core, logs := observer.New(zap.InfoLevel)
+	e.telemetrySettings.Logger = zap.New(core)
+	if err := e.Start(context.Background(), &statusHost{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForLog(t, logs, "base engine components completed", 1)
+
+	// A changed module restarts the runner, although the config itself is
+	// unchanged.
+	writeFile(t, module, `prometheus.scrape "b" { targets = [] }`)
+	waitForLog(t, logs, "reloaded the base engine config", 1)
+	waitForLog(t, logs, "base engine components completed", 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestStart_URLReload(t *testing.T) {
+	var (
+		mut     sync.Mutex
+		config  = `prometheus.scrape "a" { targets = [] }`
+		fetched = make(chan struct{}, 1)
+	)
+	setConfig := func(s string) {
+		mut.Lock()
+		defer mut.Unlock()
+		config = s
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		_, _ = w.Write([]byte(config))
+		select {
+		case fetched <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := &Config{
+		BaseEngineConfig: BaseEngineConfig{URL: srv.URL + "/config.alloy", PollFrequency: 10 * time.Millisecond},
+		Flags:            testFlags(t, "127.0.0.1:0"),
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	e := newBaseEngineExtension(cfg, component.TelemetrySettings{Logger: zap.New(core)})
+	if err := e.Start(context.Background(), &statusHost{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the initial config to be fetched before changing it.
+	<-fetched
+	setConfig(`prometheus.scrap "a" {}`)
+	waitForLog(t, logs, "failed to reload the base engine config, keeping the current config", 1)
+	if n := logs.FilterMessage("reloaded the base engine config").Len(); n != 0 {
+		t.Errorf("expected an invalid config not to be reloaded, got %d reloads", n)
+	}
+
+	setConfig(`prometheus.scrape "b" { targets = [] }`)
+	waitForLog(t, logs, "reloaded the base engine config", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestStart_URLUnavailable(t *testing.T) {
+	var mut sync.Mutex
+	available := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		if !available {
+			http.Error(w, "starting up", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`prometheus.scrape "a" { targets = [] }`))
+	}))
+	defer srv.Close()
+
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := &Config{
+		BaseEngineConfig: BaseEngineConfig{URL: srv.URL + "/config.alloy", PollFrequency: 10 * time.Millisecond},
+		Flags:            testFlags(t, "127.0.0.1:0"),
+	}
+	e := newBaseEngineExtension(cfg, component.TelemetrySettings{Logger: zap.New(core)})
+	host := &statusHost{}
+	if err := e.Start(context.Background(), host); err != nil {
+		t.Fatal(err)
+	}
+
+	// The endpoint is retried on every poll until it serves the config.
+	waitForStatuses(t, host, "StatusRecoverableError")
+	mut.Lock()
+	available = true
+	mut.Unlock()
+	waitForStatuses(t, host, "StatusRecoverableError,StatusOK")
+	waitForLog(t, logs, "started the base engine", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestStart_FileReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.alloy")
+	writeConfig := func(config string) {
+		t.Helper()
+		// Replace the file like editors do, to check that the watch
+		// survives it.
+		tmp := filepath.Join(dir, "config.alloy.tmp")
+		if err := os.WriteFile(tmp, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(`prometheus.scrape "a" { targets = [] }`)
+
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := &Config{
+		BaseEngineConfig: BaseEngineConfig{File: path},
+		Flags:            testFlags(t, "127.0.0.1:0"),
+	}
+	e := newBaseEngineExtension(cfg, component.TelemetrySettings{Logger: zap.New(core)})
+	e.reloadDelay = 200 * time.Millisecond
+	if err := e.Start(context.Background(), &statusHost{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForLog(t, logs, "started the base engine", 1)
+
+	writeConfig(`prometheus.scrap "a" {}`)
+	waitForLog(t, logs, "failed to reload the base engine config, keeping the current config", 1)
+
+	// A burst of changes is reloaded once.
+	for _, label := range []string{"b", "c", "d"} {
+		writeConfig(`prometheus.scrape "` + label + `" { targets = [] }`)
+	}
+	waitForLog(t, logs, "reloaded the base engine config", 1)
+
+	// Changes which leave the content the same aren't reloaded.
+	writeConfig(`prometheus.scrape "d" { targets = [] }`)
+	if err := os.WriteFile(filepath.Join(dir, "unrelated.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if n := logs.FilterMessage("reloaded the base engine config").Len(); n != 1 {
+		t.Errorf("expected 1 reload, got %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package baseengine
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/internal/importsource"
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+// inlineFilename is the name positions in inline configuration refer to.
+const inlineFilename = "inline.alloy"
+
+// inlineSource is an importsource.Source for configuration embedded in the
+// collector's configuration.
+type inlineSource struct {
+	config string
+}
+
+var _ importsource.Source = (*inlineSource)(nil)
+
+// Name implements importsource.Source.
+func (s *inlineSource) Name() string { return "config.inline" }
+
+// Fetch implements importsource.Source.
+func (s *inlineSource) Fetch(_ context.Context) (importsource.Files, error) {
+	return importsource.Files{inlineFilename: []byte(s.config)}, nil
+}
+
+// source returns the Source the configuration is loaded from, and the name
+// of the field which configures it. cfg must be valid.
+func (cfg *BaseEngineConfig) source() (importsource.Source, string) {
+	switch {
+	case cfg.Inline != "":
+		return &inlineSource{config: cfg.Inline}, "config.inline"
+	case cfg.Directory != "":
+		return &importsource.File{Path: cfg.Directory}, "config.directory"
+	case cfg.URL != "":
+		return &importsource.HTTP{URL: cfg.URL}, "config.url"
+	default:
+		return &importsource.File{Path: cfg.File}, "config.file"
+	}
+}
+
+// pollFrequency returns how often the source is polled for changes, or 0
+// if it isn't polled.
+func (cfg *BaseEngineConfig) pollFrequency() time.Duration {
+	switch {
+	case cfg.URL == "":
+		return 0
+	case cfg.PollFrequency == 0:
+		return importsource.DefaultPollFrequency
+	default:
+		return cfg.PollFrequency
+	}
+}
+
+// loadConfig fetches the files of source and parses them as a single
+// configuration with cache. It also returns a hash of the files, which
+// changes whenever they do.
+func loadConfig(ctx context.Context, source importsource.Source, cache *config.Cache) (*ast.File, [sha256.Size]byte, error) {
+	files, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	f, err := cache.ParseFiles(files)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	return f, files.Hash(), nil
+}
@@ -0,0 +1,122 @@
+package baseengine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+// blockLabels returns the labels of the blocks in f, separated by commas.
+func blockLabels(f *ast.File) string {
+	labels := make([]string, 0, len(f.Body))
+	for _, stmt := range f.Body {
+		labels = append(labels, stmt.(*ast.BlockStmt).Label)
+	}
+	return strings.Join(labels, ",")
+}
+
+func TestLoadConfig_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.alloy")
+	if err := os.WriteFile(path, []byte(`prometheus.scrape "a" { targets = [] }`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, _ := (&BaseEngineConfig{File: path}).source()
+	f, _, err := loadConfig(context.Background(), source, config.NewCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := blockLabels(f); labels != "a" {
+		t.Errorf("expected blocks a, got %s", labels)
+	}
+}
+
+func TestLoadConfig_Inline(t *testing.T) {
+	source, _ := (&BaseEngineConfig{Inline: "prometheus.scrape \"a\" {}\nprometheus.scrape \"b\" {}\n"}).source()
+	f, _, err := loadConfig(context.Background(), source, config.NewCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := blockLabels(f); labels != "a,b" {
+		t.Errorf("expected blocks a,b, got %s", labels)
+	}
+
+	source, _ = (&BaseEngineConfig{Inline: "prometheus.scrape \"a\" {\n"}).source()
+	if _, _, err := loadConfig(context.Background(), source, config.NewCache()); err == nil || !strings.HasPrefix(err.Error(), inlineFilename+":") {
+		t.Errorf("expected a syntax error in %s, got %v", inlineFilename, err)
+	}
+}
+
+func TestLoadConfig_Directory(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"b.alloy":    `prometheus.scrape "b" {}`,
+		"a.alloy":    `prometheus.scrape "a" {}`,
+		"ignored.md": `not configuration`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source, _ := (&BaseEngineConfig{Directory: dir}).source()
+	f, hash, err := loadConfig(context.Background(), source, config.NewCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := blockLabels(f); labels != "a,b" {
+		t.Errorf("expected the files to be merged in name order, got blocks %s", labels)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "c.alloy"), []byte(`prometheus.scrape "c" {}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, next, err := loadConfig(context.Background(), source, config.NewCache()); err != nil || next == hash {
+		t.Errorf("expected the hash to change when a file is added, got error %v", err)
+	}
+}
+
+func TestLoadConfig_URL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config.alloy":
+			_, _ = w.Write([]byte(`prometheus.scrape "a" {}`))
+		case "/config.json":
+			_, _ = w.Write([]byte(`[{"block": "prometheus.scrape", "label": "b"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tt := []struct {
+		path   string
+		expect string
+	}{
+		{"/config.alloy", "a"},
+		{"/config.json", "b"},
+	}
+	for _, tc := range tt {
+		source, _ := (&BaseEngineConfig{URL: srv.URL + tc.path}).source()
+		f, _, err := loadConfig(context.Background(), source, config.NewCache())
+		if err != nil {
+			t.Errorf("%s: %v", tc.path, err)
+			continue
+		}
+		if labels := blockLabels(f); labels != tc.expect {
+			t.Errorf("%s: expected blocks %s, got %s", tc.path, tc.expect, labels)
+		}
+	}
+
+	source, _ := (&BaseEngineConfig{URL: srv.URL + "/missing.alloy"}).source()
+	if _, _, err := loadConfig(context.Background(), source, config.NewCache()); err == nil {
+		t.Error("expected an error for a missing config")
+	}
+}
@@ -0,0 +1,83 @@
+package baseengine
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+
+	internalcomponent "github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/runner"
+)
+
+// defaultStatusInterval is how often the health of the runner's components
+// is checked for status changes.
+const defaultStatusInterval = 5 * time.Second
+
+// statusReporter reports the health of the runner's components as status
+// events to the collector.
+//
+// The collector reports the Starting, Stopping and Stopped statuses itself
+// around Start and Shutdown, and rejects reporting the current status
+// again, so statusReporter only reports changes of status or error.
+// Errors which the extension can recover from by retrying, such as a config
+// file which fails to load, are RecoverableError. Errors which can't be
+// fixed without restarting the collector, such as invalid flags or an
+// invalid inline config, are PermanentError, and no further changes are
+// reported after them.
+type statusReporter struct {
+	host component.Host
+	last *componentstatus.Event
+}
+
+func newStatusReporter(host component.Host) *statusReporter {
+	// The collector reports OK once Start returns.
+	return &statusReporter{host: host, last: componentstatus.NewEvent(componentstatus.StatusOK)}
+}
+
+// reportHealth reports the aggregate health of components.
+func (s *statusReporter) reportHealth(components []runner.ComponentInfo) {
+	s.report(healthEvent(components))
+}
+
+// reportError reports that the config couldn't be loaded, or that the
+// runner failed.
+func (s *statusReporter) reportError(err error) {
+	s.report(componentstatus.NewRecoverableErrorEvent(err))
+}
+
+// reportPermanent reports an error which retrying can't fix.
+func (s *statusReporter) reportPermanent(err error) {
+	s.report(componentstatus.NewPermanentErrorEvent(err))
+}
+
+func (s *statusReporter) report(ev *componentstatus.Event) {
+	if s.last.Status() == componentstatus.StatusPermanentError {
+		return
+	}
+	if ev.Status() == s.last.Status() && errorText(ev.Err()) == errorText(s.last.Err()) {
+		return
+	}
+	s.last = ev
+	componentstatus.ReportStatus(s.host, ev)
+}
+
+// errorText returns the message of err, or an empty string if it is nil.
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// healthEvent returns the status event for the health of components.
+// Unhealthy components are recoverable errors.
+func healthEvent(components []runner.ComponentInfo) *componentstatus.Event {
+	for _, c := range components {
+		if c.Health.Health == internalcomponent.HealthTypeUnhealthy {
+			return componentstatus.NewRecoverableErrorEvent(fmt.Errorf("component %s is unhealthy: %s", c.Name, c.Health.Message))
+		}
+	}
+	return componentstatus.NewEvent(componentstatus.StatusOK)
+}
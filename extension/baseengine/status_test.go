@@ -0,0 +1,77 @@
+package baseengine
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+
+	internalcomponent "github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/runner"
+)
+
+// statusHost is a component.Host which records the status events reported
+// to it.
+type statusHost struct {
+	mut    sync.Mutex
+	events []*componentstatus.Event
+}
+
+var _ componentstatus.Reporter = (*statusHost)(nil)
+
+func (h *statusHost) GetExtensions() map[component.ID]component.Component { return nil }
+
+func (h *statusHost) Report(ev *componentstatus.Event) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.events = append(h.events, ev)
+}
+
+// statuses returns the reported statuses, separated by commas.
+func (h *statusHost) statuses() string {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	names := make([]string, 0, len(h.events))
+	for _, ev := range h.events {
+		names = append(names, ev.Status().String())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestStatusReporter(t *testing.T) {
+	var (
+		healthy   = runner.ComponentInfo{Name: "a", Health: internalcomponent.Health{Health: internalcomponent.HealthTypeHealthy}}
+		exited    = runner.ComponentInfo{Name: "b", Health: internalcomponent.Health{Health: internalcomponent.HealthTypeExited}}
+		unhealthy = runner.ComponentInfo{Name: "c", Health: internalcomponent.Health{Health: internalcomponent.HealthTypeUnhealthy, Message: "oops"}}
+	)
+
+	host := &statusHost{}
+	s := newStatusReporter(host)
+
+	s.reportHealth(nil)
+	s.reportHealth([]runner.ComponentInfo{healthy, exited})
+	s.reportHealth([]runner.ComponentInfo{healthy, unhealthy})
+	s.reportHealth([]runner.ComponentInfo{unhealthy})
+	s.reportHealth([]runner.ComponentInfo{healthy})
+	s.reportError(errors.New("component failed"))
+	s.reportError(errors.New("component failed"))
+	s.reportError(errors.New("still failing"))
+	s.reportHealth([]runner.ComponentInfo{healthy})
+	s.reportPermanent(errors.New("invalid config"))
+	s.reportHealth([]runner.ComponentInfo{healthy})
+
+	expect := "StatusRecoverableError,StatusOK,StatusRecoverableError,StatusRecoverableError,StatusOK,StatusPermanentError"
+	if actual := host.statuses(); actual != expect {
+		t.Errorf("expected statuses %s, got %s", expect, actual)
+	}
+	if err := host.events[0].Err(); err == nil || err.Error() != "component c is unhealthy: oops" {
+		t.Errorf("unexpected recoverable error %v", err)
+	}
+	if err := host.events[3].Err(); err == nil || err.Error() != "still failing" {
+		t.Errorf("unexpected recoverable error %v", err)
+	}
+}
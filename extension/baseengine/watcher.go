@@ -0,0 +1,51 @@
+package baseengine
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultReloadDelay is how long the config must stay unchanged after a
+// change is seen before it is reloaded, so that a burst of writes causes a
+// single reload.
+const defaultReloadDelay = time.Second
+
+// watchDir returns the directory to watch for changes to the config, or ""
+// if the config isn't read from the local filesystem.
+//
+// A config file is watched through its directory, since editors and
+// Kubernetes ConfigMaps replace files instead of writing to them, which
+// would stop a watch on the file itself.
+func (cfg *BaseEngineConfig) watchDir() string {
+	switch {
+	case cfg.File != "":
+		return filepath.Dir(cfg.File)
+	case cfg.Directory != "":
+		return cfg.Directory
+	default:
+		return ""
+	}
+}
+
+// newWatcher watches dir for changes.
+func newWatcher(dir string) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// isChange reports whether ev may have changed the content of the config.
+// Changes to other files in the watched directory are included, since the
+// reload compares the content before applying it.
+func isChange(ev fsnotify.Event) bool {
+	return ev.Has(fsnotify.Create) || ev.Has(fsnotify.Write) ||
+		ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename)
+}
@@ -0,0 +1,37 @@
+// Package alloycli implements the alloy command line interface.
+package alloycli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Command returns the root alloy command.
+func Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "alloy",
+		Short:         "Run and manage Alloy pipelines",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		// Running alloy without a subcommand starts the runner with default
+		// flags, as it did before subcommands existed.
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			opts, err := RunnerOptions(nil)
+			if err != nil {
+				return err
+			}
+			return run(cmd.Context(), opts)
+		},
+	}
+	cmd.AddCommand(
+		runCommand(),
+		debugCommand(),
+		fmtCommand(),
+		validateCommand(),
+		convertCommand(),
+		toolsCommand(),
+		testCommand(),
+	)
+
+	return cmd
+}
@@ -0,0 +1,79 @@
+package alloycli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jharvey10/test-repo/internal/convert"
+	"github.com/jharvey10/test-repo/syntax/diag"
+)
+
+func convertCommand() *cobra.Command {
+	var (
+		sourceFormat string
+		output       string
+	)
+
+	formats := make([]string, len(convert.Formats))
+	for i, f := range convert.Formats {
+		formats[i] = string(f)
+	}
+
+	cmd := &cobra.Command{
+		Use:   "convert [file]",
+		Short: "Convert configuration files from other tools",
+		Long: `convert reads a configuration file written for another tool and prints the
+equivalent native configuration. With no file, or a file named -, convert
+reads from standard input.
+
+Fields which could not be converted are reported as warnings on standard
+error. If the file could not be converted at all, for example because it
+needs components which are not available, the errors are reported and
+nothing is printed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(formats, sourceFormat) {
+				return fmt.Errorf("unsupported source format %q: expected one of %s", sourceFormat, strings.Join(formats, ", "))
+			}
+
+			filename := "-"
+			if len(args) > 0 {
+				filename = args[0]
+			}
+			src, err := readSource(cmd, filename)
+			if err != nil {
+				return err
+			}
+
+			out, ds := convert.Convert(filename, src, convert.Format(sourceFormat))
+			if len(ds) > 0 {
+				p := diag.NewPrinter(diag.PrinterConfig{
+					Color:              isTerminal(cmd.ErrOrStderr()),
+					ContextLinesBefore: 1,
+				})
+				if err := p.Fprint(cmd.ErrOrStderr(), map[string][]byte{filename: src}, ds); err != nil {
+					return err
+				}
+			}
+			if ds.HasErrors() {
+				return errors.New("configuration could not be converted")
+			}
+
+			if output != "" {
+				return os.WriteFile(output, out, 0o644)
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&sourceFormat, "source-format", "f", "", "Format of the file to convert: "+strings.Join(formats, ", "))
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the converted configuration to this file instead of standard output")
+	_ = cmd.MarkFlagRequired("source-format")
+
+	return cmd
+}
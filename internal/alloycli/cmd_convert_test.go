@@ -0,0 +1,24 @@
+package alloycli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert_Stdin(t *testing.T) {
+	in := "scrape_configs:\n  - job_name: app\n    static_configs:\n      - targets: [app:80]\n"
+
+	out, err := runAlloy(t, in, "convert", "--source-format=prometheus")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if !strings.Contains(out, `prometheus.scrape "app" {`) {
+		t.Errorf("expected a prometheus.scrape component, got:\n%s", out)
+	}
+}
+
+func TestConvert_UnknownFormat(t *testing.T) {
+	if _, err := runAlloy(t, "", "convert", "--source-format=nginx"); err == nil {
+		t.Fatal("expected an error for an unknown source format")
+	}
+}
@@ -0,0 +1,46 @@
+package alloycli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jharvey10/test-repo/internal/livedebugging"
+)
+
+func debugCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Debug a running instance",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Usage()
+		},
+	}
+	cmd.AddCommand(
+		debugSupportCommand(),
+		debugTailCommand(),
+	)
+
+	return cmd
+}
+
+func debugTailCommand() *cobra.Command {
+	var (
+		addr string
+		opts livedebugging.SubscribeOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tail <component>",
+		Short: "Stream the data flowing through a component",
+		Long: `tail connects to a running instance and prints the data handled by the
+given component as it flows through it, until interrupted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return livedebugging.Tail(cmd.Context(), addr, args[0], opts, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().StringVar(&addr, "server", "http://"+debugHTTPListenAddr, "Address of the instance's HTTP server, set with its --server.http.listen-addr flag")
+	cmd.Flags().Float64Var(&opts.SampleRate, "sample", 0, "Fraction of entries to stream, between 0 and 1. 0 streams every entry")
+	cmd.Flags().IntVar(&opts.BufferSize, "buffer", 0, "Number of entries buffered on the server before new entries are dropped, up to 100000")
+
+	return cmd
+}
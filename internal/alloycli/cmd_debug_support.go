@@ -0,0 +1,49 @@
+package alloycli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jharvey10/test-repo/internal/supportbundle"
+)
+
+func debugSupportCommand() *cobra.Command {
+	var (
+		addr     string
+		duration time.Duration
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "support",
+		Short: "Generate a support bundle from a running instance",
+		Long: `support downloads a zip archive from a running instance containing its
+redacted configuration, component health, recent logs, profiles, build
+information and runtime environment. Attach it to issues filed upstream.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if err := supportbundle.Fetch(cmd.Context(), addr, duration, f); err != nil {
+				f.Close()
+				os.Remove(output)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Support bundle written to %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&addr, "server", "http://"+debugHTTPListenAddr, "Address of the instance's HTTP server, set with its --server.http.listen-addr flag")
+	cmd.Flags().DurationVar(&duration, "duration", supportbundle.DefaultCPUProfileDuration, "How long to collect the CPU profile for")
+	cmd.Flags().StringVarP(&output, "output", "o", supportbundle.DefaultFilename, "File to write the support bundle to")
+
+	return cmd
+}
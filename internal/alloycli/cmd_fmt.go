@@ -0,0 +1,98 @@
+package alloycli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jharvey10/test-repo/syntax/printer"
+)
+
+type fmtFlags struct {
+	write bool
+	check bool
+}
+
+func fmtCommand() *cobra.Command {
+	var f fmtFlags
+
+	cmd := &cobra.Command{
+		Use:   "fmt [file ...]",
+		Short: "Format configuration files",
+		Long: `fmt rewrites configuration files in their canonical format and prints the
+result. With no files, or a file named -, fmt reads from standard input.
+
+With --write, files are formatted in place. With --check, nothing is printed
+or written; the names of files which are not formatted are listed and fmt
+exits with a non-zero status, which makes it suitable for CI.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			return f.run(cmd, args)
+		},
+	}
+	cmd.Flags().BoolVarP(&f.write, "write", "w", false, "Write the formatted result back to each file")
+	cmd.Flags().BoolVarP(&f.check, "check", "c", false, "Report files which are not formatted and exit with a non-zero status")
+	cmd.MarkFlagsMutuallyExclusive("write", "check")
+
+	return cmd
+}
+
+func (f *fmtFlags) run(cmd *cobra.Command, files []string) error {
+	var unformatted int
+
+	for _, name := range files {
+		src, err := readSource(cmd, name)
+		if err != nil {
+			return err
+		}
+
+		out, err := printer.Format(name, src)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case f.check:
+			if !bytes.Equal(src, out) {
+				fmt.Fprintln(cmd.OutOrStdout(), name)
+				unformatted++
+			}
+		case f.write:
+			if name == "-" {
+				return errors.New("cannot use --write when reading from standard input")
+			}
+			if bytes.Equal(src, out) {
+				continue
+			}
+			fi, err := os.Stat(name)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(name, out, fi.Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			if _, err := cmd.OutOrStdout().Write(out); err != nil {
+				return err
+			}
+		}
+	}
+
+	if unformatted > 0 {
+		return fmt.Errorf("%d file(s) are not formatted", unformatted)
+	}
+	return nil
+}
+
+func readSource(cmd *cobra.Command, name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(cmd.InOrStdin())
+	}
+	return os.ReadFile(name)
+}
@@ -0,0 +1,63 @@
+package alloycli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	unformattedConfig = "logging {\nlevel=\"debug\"\n}\n"
+	formattedConfig   = "logging {\n\tlevel = \"debug\"\n}\n"
+)
+
+func runAlloy(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	cmd := Command()
+	cmd.SetArgs(args)
+	cmd.SetIn(bytes.NewBufferString(stdin))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestFmt_Stdin(t *testing.T) {
+	out, err := runAlloy(t, unformattedConfig, "fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != formattedConfig {
+		t.Errorf("expected %q, got %q", formattedConfig, out)
+	}
+}
+
+func TestFmt_CheckAndWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.alloy")
+	if err := os.WriteFile(path, []byte(unformattedConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runAlloy(t, "", "fmt", "--check", path)
+	if err == nil {
+		t.Fatalf("expected --check to fail for an unformatted file, output: %s", out)
+	}
+
+	if _, err := runAlloy(t, "", "fmt", "--write", path); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != formattedConfig {
+		t.Errorf("expected file to be rewritten as %q, got %q", formattedConfig, written)
+	}
+
+	if out, err := runAlloy(t, "", "fmt", "--check", path); err != nil {
+		t.Fatalf("expected --check to pass after --write: %v\n%s", err, out)
+	}
+}
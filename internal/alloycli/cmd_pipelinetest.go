@@ -0,0 +1,103 @@
+package alloycli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/internal/pipelinetest"
+	"github.com/jharvey10/test-repo/syntax/diag"
+)
+
+func testCommand() *cobra.Command {
+	f := &runFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "test <config-file> <test-file>",
+		Short: "Test a configuration against fixtures",
+		Long: `test runs the components of a configuration file with the fixtures of a
+test file, and checks the data they produce against the test file's
+expectations. Scrape targets are served from the test file's target blocks,
+and the log entries of each input block are sent to its component before
+the components run. The samples and log entries produced by each component
+named by an expect block must match the block's metrics, logs or otlp
+exactly:
+
+	target {
+		address = "localhost:9090"
+		metrics = ` + "`up 1`" + `
+	}
+
+	input {
+		component = "loki.process.default"
+		logs      = "level=info msg=started"
+	}
+
+	expect {
+		component = "prometheus.scrape.default"
+		metrics   = ` + "`" + `up{instance="localhost:9090",job="prometheus.scrape.default"} 1` + "`" + `
+	}
+
+Log entries are written as log lines, one entry per line, or as OTLP JSON
+logs with the otlp attribute, whose attributes become the entries' labels.
+
+The differences are printed for each expectation which isn't met, and test
+exits with a non-zero status.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := f.options()
+			if err != nil {
+				return err
+			}
+
+			configFile, testFile := args[0], args[1]
+			src, err := readSource(cmd, configFile)
+			if err != nil {
+				return err
+			}
+			file, err := config.Parse(configFile, src)
+			ds := diag.FromError(err)
+			ds.Merge(config.ValidateFile(file))
+			if ds.HasErrors() {
+				return fmt.Errorf("loading config file: %w", ds)
+			}
+
+			src, err = readSource(cmd, testFile)
+			if err != nil {
+				return err
+			}
+			test, err := pipelinetest.ParseTest(testFile, src)
+			if err != nil {
+				return fmt.Errorf("loading test file: %w", err)
+			}
+
+			failures, err := pipelinetest.Run(cmd.Context(), opts, file, test)
+			if err != nil {
+				return err
+			}
+			for _, failure := range failures {
+				fmt.Fprintf(cmd.OutOrStdout(), "FAIL %s\n%s\n", failure.Component, indent(failure.Diff))
+			}
+			if len(failures) > 0 {
+				return fmt.Errorf("%d of %d expectations failed", len(failures), len(test.Expects))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "ok %s: %d expectations met\n", testFile, len(test.Expects))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&f.stabilityLevel, "stability.level", component.StabilityGenerallyAvailable.String(),
+		"Minimum stability level of the components which may be used: experimental, public-preview or generally-available")
+	cmd.Flags().StringVar(&f.logLevel, "log.level", zapcore.WarnLevel.String(),
+		"Minimum level of the messages which are logged: debug, info, warn or error")
+
+	return cmd
+}
+
+// indent indents every line of s with a tab.
+func indent(s string) string {
+	return "\t" + strings.ReplaceAll(s, "\n", "\n\t")
+}
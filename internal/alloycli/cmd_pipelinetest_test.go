@@ -0,0 +1,57 @@
+package alloycli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTest(t *testing.T) {
+	dir := t.TempDir()
+	config, test := filepath.Join(dir, "config.alloy"), filepath.Join(dir, "test.alloy")
+	writeFile(t, config, `prometheus.scrape "a" {
+	targets = [{"__address__" = "a:9090"}]
+}
+`)
+
+	writeFile(t, test, `
+target {
+	address = "a:9090"
+	metrics = "up 1"
+}
+
+expect {
+	component = "prometheus.scrape.a"
+	metrics   = "up{instance=\"a:9090\",job=\"prometheus.scrape.a\"} 1"
+}
+`)
+	out, err := runAlloy(t, "", "test", config, test)
+	if err != nil {
+		t.Fatalf("expected the test to pass, got %v: %s", err, out)
+	}
+	if expect := "ok " + test + ": 1 expectations met\n"; out != expect {
+		t.Errorf("expected %q, got %q", expect, out)
+	}
+
+	writeFile(t, test, `
+target {
+	address = "a:9090"
+	metrics = "up 0"
+}
+
+expect {
+	component = "prometheus.scrape.a"
+	metrics   = "up{instance=\"a:9090\",job=\"prometheus.scrape.a\"} 1"
+}
+`)
+	out, err = runAlloy(t, "", "test", config, test)
+	if err == nil || err.Error() != "1 of 1 expectations failed" {
+		t.Errorf("expected the test to fail, got %v", err)
+	}
+	expect := "FAIL prometheus.scrape.a\n" +
+		"\t- up{instance=\"a:9090\",job=\"prometheus.scrape.a\"} 1\n" +
+		"\t+ up{instance=\"a:9090\",job=\"prometheus.scrape.a\"} 0\n"
+	if !strings.HasPrefix(out, expect) {
+		t.Errorf("expected output starting with %q, got %q", expect, out)
+	}
+}
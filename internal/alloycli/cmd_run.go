@@ -0,0 +1,238 @@
+package alloycli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/internal/remotecfg"
+	"github.com/jharvey10/test-repo/internal/runner"
+)
+
+// debugHTTPListenAddr is the address the debug commands connect to by
+// default. The runner's HTTP server is disabled unless
+// --server.http.listen-addr is set, for example to this address.
+const debugHTTPListenAddr = "127.0.0.1:12345"
+
+type runFlags struct {
+	httpListenAddr string
+	storagePath    string
+	stabilityLevel string
+	logLevel       string
+}
+
+// remoteFlags configure fetching the configuration from a remote endpoint.
+// They only apply to the run command, so they aren't part of runFlags.
+type remoteFlags struct {
+	url           string
+	pollFrequency time.Duration
+}
+
+// remoteCacheDir is the directory in the storage path the last remote
+// configuration which applied is cached in.
+const remoteCacheDir = "remotecfg"
+
+func runCommand() *cobra.Command {
+	f := &runFlags{}
+	rf := &remoteFlags{}
+
+	cmd := &cobra.Command{
+		Use:   "run [config-file]",
+		Short: "Start the runner",
+		Long: `run starts the runner. When a configuration file is given, every
+component it declares is run, including the components of the modules it
+imports with import.file, import.git and import.http blocks. Imported
+modules are polled for changes, which restart the runner with their latest
+content. run exits once every component has completed.
+
+Setting --server.http.listen-addr, for example to 127.0.0.1:12345, starts an
+HTTP server used by the debug commands, for live debugging and support
+bundles. run then keeps serving it after the components complete, until
+interrupted.
+
+Setting --remotecfg.url runs the configuration served by that URL instead
+of a configuration file. The URL is polled every --remotecfg.poll-frequency,
+and the runner is restarted whenever the configuration changes and is
+valid. The last configuration which applied is cached under --storage.path,
+which is required, so that run can start while the URL is unavailable. run
+then keeps polling until interrupted.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := f.options()
+			if err != nil {
+				return err
+			}
+			if rf.url != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("a config file can't be used with --remotecfg.url")
+				}
+				if opts.StoragePath == "" {
+					return fmt.Errorf("--remotecfg.url requires --storage.path, where the remote config is cached")
+				}
+				return runRemote(cmd.Context(), opts, rf)
+			}
+			if len(args) > 0 {
+				opts.ConfigFile = args[0]
+			}
+			return run(cmd.Context(), opts)
+		},
+	}
+	f.register(cmd.Flags())
+	cmd.Flags().StringVar(&rf.url, "remotecfg.url", "",
+		"URL to fetch the configuration from instead of a config file")
+	cmd.Flags().DurationVar(&rf.pollFrequency, "remotecfg.poll-frequency", remotecfg.DefaultPollFrequency,
+		"How often --remotecfg.url is polled for changes")
+
+	return cmd
+}
+
+func (f *runFlags) register(fs *pflag.FlagSet) {
+	fs.StringVar(&f.httpListenAddr, "server.http.listen-addr", "",
+		"Address the HTTP server for the debug commands listens on, such as "+debugHTTPListenAddr+". The HTTP server is disabled when it is empty")
+	fs.StringVar(&f.storagePath, "storage.path", "",
+		"Directory where data which persists across restarts is stored. Nothing is stored when it is empty")
+	fs.StringVar(&f.stabilityLevel, "stability.level", component.StabilityGenerallyAvailable.String(),
+		"Minimum stability level of the components which may be used: experimental, public-preview or generally-available")
+	fs.StringVar(&f.logLevel, "log.level", zapcore.InfoLevel.String(),
+		"Minimum level of the messages which are logged: debug, info, warn or error")
+}
+
+func (f *runFlags) options() (runner.Options, error) {
+	if f.httpListenAddr != "" {
+		if _, _, err := net.SplitHostPort(f.httpListenAddr); err != nil {
+			return runner.Options{}, fmt.Errorf("invalid --server.http.listen-addr: %w", err)
+		}
+	}
+	stability, err := component.ParseStability(f.stabilityLevel)
+	if err != nil {
+		return runner.Options{}, fmt.Errorf("invalid --stability.level: %w", err)
+	}
+	level, err := zapcore.ParseLevel(f.logLevel)
+	if err != nil || level > zapcore.ErrorLevel {
+		return runner.Options{}, fmt.Errorf("invalid --log.level %q, must be one of debug, info, warn, error", f.logLevel)
+	}
+
+	return runner.Options{
+		HTTPListenAddr: f.httpListenAddr,
+		StoragePath:    f.storagePath,
+		MinStability:   stability,
+		LogLevel:       level,
+	}, nil
+}
+
+// RunnerOptions parses flags accepted by the run command, such as
+// --server.http.listen-addr=127.0.0.1:8080, into runner options. It lets
+// other ways of starting the runner accept the same flags.
+func RunnerOptions(args []string) (runner.Options, error) {
+	f := &runFlags{}
+	fs := pflag.NewFlagSet("run", pflag.ContinueOnError)
+	f.register(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return runner.Options{}, err
+	}
+	if fs.NArg() > 0 {
+		return runner.Options{}, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	return f.options()
+}
+
+func run(ctx context.Context, opts runner.Options) error {
+	fmt.Println("Starting Alloy wow \\{^_^}/")
+
+	if opts.ConfigFile == "" {
+		return runner.New(opts).Run(ctx)
+	}
+	f, err := runner.ReadConfig(opts.ConfigFile)
+	if err != nil {
+		return err
+	}
+	// The engine runs the parsed config, so that it can be restarted with
+	// the latest content of the modules it imports.
+	opts.ConfigFile = ""
+	if opts.Logger == nil {
+		opts.Logger = runner.NewLogger(opts.LogLevel)
+	}
+
+	rl := &reloader{ctx: ctx, opts: opts, logger: opts.Logger}
+	if err := rl.load(f); err != nil {
+		return err
+	}
+	for {
+		eng := rl.engine()
+		<-eng.Done()
+		// Engines which were replaced are done too.
+		if rl.engine() == eng {
+			return rl.stop()
+		}
+	}
+}
+
+// runRemote runs the configuration served at rf.url until ctx is canceled.
+// Each valid configuration fetched replaces the running one by restarting
+// the runner, as do changes to the modules it imports. A configuration
+// which fails to load leaves the current one running.
+func runRemote(ctx context.Context, opts runner.Options, rf *remoteFlags) error {
+	if opts.Logger == nil {
+		opts.Logger = runner.NewLogger(opts.LogLevel)
+	}
+	logger := opts.Logger.With(zap.String("url", rf.url))
+
+	rl := &reloader{ctx: ctx, opts: opts, logger: logger}
+	defer func() { _ = rl.stop() }()
+
+	// applied is the config which was last applied. It is only accessed by
+	// the service's Run goroutine, which calls Apply.
+	var applied []byte
+
+	svc, err := remotecfg.New(remotecfg.Options{
+		URL:           rf.url,
+		PollFrequency: rf.pollFrequency,
+		CacheDir:      filepath.Join(opts.StoragePath, remoteCacheDir),
+		Validate: func(src []byte) error {
+			if ds := config.Validate(rf.url, src); ds.HasErrors() {
+				return ds
+			}
+			return nil
+		},
+		Apply: func(src []byte) error {
+			// Endpoints which don't support ETags serve the config in full
+			// on every poll.
+			if applied != nil && bytes.Equal(src, applied) {
+				return nil
+			}
+			f, err := config.Parse(rf.url, src)
+			if err != nil {
+				return err
+			}
+			if err := rl.load(f); err != nil {
+				return err
+			}
+			applied = src
+			go func(eng *runner.Engine) {
+				if err := eng.Err(); err != nil {
+					logger.Error("runner stopped unexpectedly, retrying on the next config change", zap.Error(err))
+				}
+			}(rl.engine())
+			logger.Info("applied the remote config")
+			return nil
+		},
+		OnError: func(err error) {
+			logger.Warn("failed to update the remote config, keeping the current config", zap.Error(err))
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return svc.Run(ctx)
+}
@@ -0,0 +1,183 @@
+package alloycli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	_ "github.com/jharvey10/test-repo/internal/component/prometheus" // Import prometheus.scrape
+	"github.com/jharvey10/test-repo/internal/runner"
+)
+
+func TestRunnerOptions(t *testing.T) {
+	opts, err := RunnerOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := runner.Options{
+		MinStability: component.StabilityGenerallyAvailable,
+		LogLevel:     zapcore.InfoLevel,
+	}
+	if opts != expect {
+		t.Errorf("expected default options %+v, got %+v", expect, opts)
+	}
+
+	opts, err = RunnerOptions([]string{
+		"--server.http.listen-addr=127.0.0.1:8080",
+		"--storage.path=/tmp/alloy",
+		"--stability.level=experimental",
+		"--log.level=warn",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = runner.Options{
+		HTTPListenAddr: "127.0.0.1:8080",
+		StoragePath:    "/tmp/alloy",
+		MinStability:   component.StabilityExperimental,
+		LogLevel:       zapcore.WarnLevel,
+	}
+	if opts != expect {
+		t.Errorf("expected options %+v, got %+v", expect, opts)
+	}
+
+	for _, args := range [][]string{
+		{"--no-such-flag=1"},
+		{"--stability.level=beta"},
+		{"--log.level=fatal"},
+		{"--server.http.listen-addr=localhost"},
+		{"config.alloy"},
+	} {
+		if _, err := RunnerOptions(args); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+}
+
+func TestRunRemote(t *testing.T) {
+	var (
+		mut sync.Mutex
+		src = `prometheus.scrape "a" { targets = [] }`
+	)
+	set := func(s string) {
+		mut.Lock()
+		defer mut.Unlock()
+		src = s
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		_, _ = io.WriteString(w, src)
+	}))
+	defer ts.Close()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	opts := runner.Options{StoragePath: t.TempDir(), Logger: zap.New(core)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- runRemote(ctx, opts, &remoteFlags{url: ts.URL, pollFrequency: 10 * time.Millisecond})
+	}()
+
+	waitForLog(t, logs, "applied the remote config", 1)
+
+	// An invalid config leaves the current one running.
+	set(`prometheus.scrap "b" { }`)
+	waitForLog(t, logs, "failed to update the remote config, keeping the current config", 1)
+
+	set(`prometheus.scrape "b" { targets = [] }`)
+	waitForLog(t, logs, "applied the remote config", 2)
+	waitForLog(t, logs, "running component", 2)
+	if !running(logs, "prometheus.scrape.a") || !running(logs, "prometheus.scrape.b") {
+		t.Errorf("expected both configs to run, got %v", logs.All())
+	}
+
+	// An unchanged config doesn't restart the runner.
+	time.Sleep(50 * time.Millisecond)
+	if n := logs.FilterMessage("applied the remote config").Len(); n != 2 {
+		t.Errorf("expected the config to be applied twice, got %d", n)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected runRemote to return nil, got %v", err)
+	}
+	cached, err := os.ReadFile(filepath.Join(opts.StoragePath, remoteCacheDir, "remote.alloy"))
+	if err != nil || string(cached) != `prometheus.scrape "b" { targets = [] }` {
+		t.Errorf("expected the last config to be cached, got %q (%v)", cached, err)
+	}
+}
+
+func TestRun_ReloadsImports(t *testing.T) {
+	dir := t.TempDir()
+	module, config := filepath.Join(dir, "module.alloy"), filepath.Join(dir, "config.alloy")
+	writeFile(t, module, `prometheus.scrape "a" { targets = [] }`)
+	writeFile(t, config, fmt.Sprintf(`import.file "shared" {
+	filename       = %q
+	poll_frequency = "10ms"
+}
+`, module))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	opts := runner.Options{
+		ConfigFile: config,
+		// The HTTP server keeps the runner running after its components
+		// complete.
+		HTTPListenAddr: "127.0.0.1:0",
+		Logger:         zap.New(core),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, opts) }()
+
+	waitForLog(t, logs, "running component", 1)
+	writeFile(t, module, `prometheus.scrape "b" { targets = [] }`)
+	waitForLog(t, logs, "reloaded the imported modules", 1)
+	waitForLog(t, logs, "running component", 2)
+	if !running(logs, "import.file.shared/prometheus.scrape.a") || !running(logs, "import.file.shared/prometheus.scrape.b") {
+		t.Errorf("expected both versions of the module to run, got %v", logs.All())
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected run to return nil, got %v", err)
+	}
+}
+
+// waitForLog waits for msg to be logged n times.
+func waitForLog(t *testing.T, logs *observer.ObservedLogs, msg string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage(msg).Len() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q to be logged %d times, got %v", msg, n, logs.All())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// running reports whether the component id was run.
+func running(logs *observer.ObservedLogs, id string) bool {
+	return logs.FilterMessage("running component").FilterField(zap.String("component", id)).Len() > 0
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,37 @@
+package alloycli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/jharvey10/test-repo/internal/lsp"
+)
+
+func toolsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tools",
+		Short: "Tools for working with configuration files",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Usage()
+		},
+	}
+	cmd.AddCommand(
+		toolsLSPCommand(),
+	)
+
+	return cmd
+}
+
+func toolsLSPCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a language server for configuration files",
+		Long: `lsp runs a Language Server Protocol server over stdin and stdout, for use
+by editors. It reports diagnostics as files change, completes component
+names and attributes, shows component documentation on hover, jumps to
+the definition of referenced components and formats files.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return lsp.Serve(cmd.Context(), cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+}
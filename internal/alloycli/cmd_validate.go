@@ -0,0 +1,73 @@
+package alloycli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/syntax/diag"
+)
+
+func validateCommand() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "Check a configuration file for errors",
+		Long: `validate parses a configuration file and checks that every component it
+declares exists, is uniquely labeled and has valid arguments. Problems are
+printed in the order they appear, with the source they refer to, or as a JSON array with --format=json for editors
+and other tools. Files with a .json extension are read in the JSON
+representation of the configuration language.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filename := args[0]
+			src, err := readSource(cmd, filename)
+			if err != nil {
+				return err
+			}
+
+			ds := config.Validate(filename, src)
+
+			switch format {
+			case "text":
+				p := diag.NewPrinter(diag.PrinterConfig{
+					Color:              isTerminal(cmd.OutOrStdout()),
+					ContextLinesBefore: 1,
+					ContextLinesAfter:  1,
+				})
+				if err := p.Fprint(cmd.OutOrStdout(), map[string][]byte{filename: src}, ds); err != nil {
+					return err
+				}
+			case "json":
+				if err := diag.WriteJSON(cmd.OutOrStdout(), ds); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unsupported format %q: expected text or json", format)
+			}
+
+			if ds.HasErrors() {
+				return errors.New("configuration is invalid")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// isTerminal reports whether w is a terminal, so colored output can be
+// enabled.
+func isTerminal(w any) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
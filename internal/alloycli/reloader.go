@@ -0,0 +1,91 @@
+package alloycli
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/jharvey10/test-repo/internal/runner"
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+// reloader runs a config in a runner.Engine, and restarts the engine with
+// the latest content of the modules the config imports when they change.
+type reloader struct {
+	ctx    context.Context
+	opts   runner.Options
+	logger *zap.Logger
+
+	mut sync.Mutex
+	f   *ast.File
+	eng *runner.Engine
+	// stopWatching stops watching eng for changed modules.
+	stopWatching context.CancelFunc
+}
+
+// load replaces the running engine with one running f. The current engine
+// keeps running if f fails to load.
+func (rl *reloader) load(f *ast.File) error {
+	rl.mut.Lock()
+	defer rl.mut.Unlock()
+	return rl.loadLocked(f)
+}
+
+func (rl *reloader) loadLocked(f *ast.File) error {
+	next, err := runner.NewEngine(rl.opts, f)
+	if err != nil {
+		return err
+	}
+	if rl.eng != nil {
+		rl.stopWatching()
+		_ = rl.eng.Stop()
+	}
+
+	ctx, cancel := context.WithCancel(rl.ctx)
+	rl.f, rl.eng, rl.stopWatching = f, next, cancel
+	next.Start(rl.ctx)
+	go rl.watch(ctx, next)
+	return nil
+}
+
+// watch reloads the config when a module imported by eng changes, until
+// ctx is canceled.
+func (rl *reloader) watch(ctx context.Context, eng *runner.Engine) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-eng.Changed():
+		}
+
+		rl.mut.Lock()
+		// eng may have been replaced while waiting for the lock.
+		if ctx.Err() == nil {
+			if err := rl.loadLocked(rl.f); err != nil {
+				rl.logger.Warn("failed to reload the imported modules, keeping the current config", zap.Error(err))
+			} else {
+				rl.logger.Info("reloaded the imported modules")
+			}
+		}
+		rl.mut.Unlock()
+	}
+}
+
+// engine returns the running engine, or nil if no config has loaded.
+func (rl *reloader) engine() *runner.Engine {
+	rl.mut.Lock()
+	defer rl.mut.Unlock()
+	return rl.eng
+}
+
+// stop stops the running engine and returns the result of its Run method.
+func (rl *reloader) stop() error {
+	rl.mut.Lock()
+	defer rl.mut.Unlock()
+	if rl.eng == nil {
+		return nil
+	}
+	rl.stopWatching()
+	return rl.eng.Stop()
+}
@@ -0,0 +1,7 @@
+// Package all imports all known component packages so that they register
+// themselves with the component registry.
+package all
+
+import (
+	_ "github.com/jharvey10/test-repo/internal/component/prometheus" // Import prometheus.scrape
+)
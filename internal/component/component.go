@@ -1,5 +1,11 @@
 package component
 
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
 // Component is the interface that all Alloy components must implement.
 type Component interface {
 	// Run starts the component and blocks until the context is cancelled.
@@ -9,12 +15,105 @@ type Component interface {
 	Name() string
 }
 
+// DebugPublisher receives the data handled by a component for live
+// debugging.
+type DebugPublisher interface {
+	// Active reports whether anyone is listening. Components should check it
+	// before formatting data for Publish.
+	Active() bool
+
+	// Publish sends data to any listeners. Publish never blocks.
+	Publish(data string)
+}
+
+// LiveDebuggable is implemented by components which publish the data they
+// handle for live debugging.
+type LiveDebuggable interface {
+	// SetDebugPublisher is called by the runner before the component runs.
+	SetDebugPublisher(p DebugPublisher)
+}
+
+// Sample is a metric sample.
+type Sample struct {
+	// Labels identify the series, including its name as the __name__
+	// label.
+	Labels map[string]string
+	Value  float64
+}
+
+// Appender receives the metric samples produced by a component.
+type Appender interface {
+	Append(s Sample) error
+}
+
+// MetricsProducer is implemented by components which produce metric
+// samples.
+type MetricsProducer interface {
+	// SetAppender is called by the runner before the component runs, if
+	// the runner has somewhere to send the component's samples.
+	SetAppender(a Appender)
+}
+
+// LogEntry is a log line, along with the labels identifying its stream.
+type LogEntry struct {
+	Labels map[string]string
+	Line   string
+}
+
+// LogReceiver receives log entries.
+type LogReceiver interface {
+	Receive(e LogEntry) error
+}
+
+// LogsProducer is implemented by components which produce log entries.
+type LogsProducer interface {
+	// SetLogReceiver is called by the runner before the component runs, if
+	// the runner has somewhere to send the component's log entries.
+	SetLogReceiver(r LogReceiver)
+}
+
+// LogsConsumer is implemented by components which accept log entries.
+type LogsConsumer interface {
+	// LogReceiver returns where log entries are sent to the component.
+	LogReceiver() LogReceiver
+}
+
+// Options are the options a component is built with.
+type Options struct {
+	// ID uniquely identifies the component. It is the name of the
+	// component followed by the label of the block declaring it, such as
+	// prometheus.scrape.default.
+	ID string
+
+	// Logger logs on behalf of the component, tagged with its ID.
+	// Components must log through it rather than printing, so that their
+	// logs reach wherever the runner's logs go.
+	Logger *zap.Logger
+
+	// HTTPClient performs the component's outgoing HTTP requests. It is
+	// nil unless the runner overrides the default client.
+	HTTPClient *http.Client
+}
+
 // Registration holds metadata about a registered component.
 type Registration struct {
 	Name        string
 	Description string
-	Build       func() Component
 	Version     string
+
+	// Build builds a component from its arguments, which are a value of
+	// the same type as Args decoded from the block declaring the
+	// component, or nil if Args is nil.
+	Build func(opts Options, args any) (Component, error)
+
+	// Stability is the stability level of the component. The runner
+	// refuses to run components below its minimum stability level.
+	Stability Stability
+
+	// Args is the zero value of the component's arguments struct, which
+	// uses syntax struct tags to describe the attributes and blocks the
+	// component accepts. It is nil for components without arguments.
+	Args any
 }
 
 // registry holds all registered components.
@@ -23,7 +122,7 @@ var registry = make(map[string]Registration)
 var Wow = Registration{
 	Name:        "wow",
 	Description: "Wow",
-	Build:       func() Component { return nil },
+	Build:       func(Options, any) (Component, error) { return nil, nil },
 	Version:     "1.32.0", // x-release-please-version
 }
 
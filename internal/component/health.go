@@ -0,0 +1,88 @@
+package component
+
+import (
+	"fmt"
+	"time"
+)
+
+// HealthType is the overall health of a component.
+type HealthType uint8
+
+const (
+	// HealthTypeUnknown is the health of a component which has not run yet.
+	HealthTypeUnknown HealthType = iota
+	// HealthTypeHealthy is the health of a component which is running
+	// normally.
+	HealthTypeHealthy
+	// HealthTypeUnhealthy is the health of a component which failed or
+	// reported a problem.
+	HealthTypeUnhealthy
+	// HealthTypeExited is the health of a component which returned from Run
+	// without an error.
+	HealthTypeExited
+)
+
+// String returns the name of the health type.
+func (ht HealthType) String() string {
+	switch ht {
+	case HealthTypeUnknown:
+		return "unknown"
+	case HealthTypeHealthy:
+		return "healthy"
+	case HealthTypeUnhealthy:
+		return "unhealthy"
+	case HealthTypeExited:
+		return "exited"
+	default:
+		return fmt.Sprintf("HealthType(%d)", uint8(ht))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (ht HealthType) MarshalText() ([]byte, error) {
+	return []byte(ht.String()), nil
+}
+
+// Health is the health of a component at a point in time.
+type Health struct {
+	Health     HealthType `json:"state"`
+	Message    string     `json:"message,omitempty"`
+	UpdateTime time.Time  `json:"update_time"`
+}
+
+// HealthComponent is implemented by components which report their own
+// health while running. The runner combines it with the health it tracks
+// for the component's Run method.
+type HealthComponent interface {
+	Component
+
+	// CurrentHealth returns the current health of the component.
+	CurrentHealth() Health
+}
+
+// LeastHealthy returns the least healthy of the given healths. Unhealthy
+// is less healthy than unknown, which is less healthy than exited and
+// healthy. Ties are broken by the most recent update time.
+func LeastHealthy(h Health, hh ...Health) Health {
+	least := h
+	for _, next := range hh {
+		lr, nr := healthRank(least.Health), healthRank(next.Health)
+		if nr > lr || (nr == lr && next.UpdateTime.After(least.UpdateTime)) {
+			least = next
+		}
+	}
+	return least
+}
+
+func healthRank(ht HealthType) int {
+	switch ht {
+	case HealthTypeUnhealthy:
+		return 3
+	case HealthTypeUnknown:
+		return 2
+	case HealthTypeExited:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -1,39 +1,156 @@
 package prometheus
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/syntax/alloytypes"
 )
 
 func init() {
 	component.Register(component.Registration{
 		Name:        "prometheus.scrape",
 		Description: "Scrapes Prometheus metrics from targets",
-		Build:       func() component.Component { return New() },
+		Stability:   component.StabilityGenerallyAvailable,
+		Args:        Arguments{},
+		Build: func(opts component.Options, args any) (component.Component, error) {
+			return New(opts, args.(Arguments)), nil
+		},
 	})
 }
 
+// Arguments holds the configuration of a prometheus.scrape component.
+type Arguments struct {
+	Targets     []map[string]string `syntax:"targets,attr"`
+	JobName     string              `syntax:"job_name,attr,optional"`
+	BearerToken alloytypes.Secret   `syntax:"bearer_token,attr,optional"`
+}
+
+// scrapeTimeout bounds each scrape of a target.
+const scrapeTimeout = 10 * time.Second
+
 // Scraper implements a Prometheus metrics scraper component.
+//
+// Targets are only scraped when the runner sets an Appender to send their
+// samples to, as components can't be connected to each other yet.
 type Scraper struct {
-	targets []string
+	id          string
+	logger      *zap.Logger
+	client      *http.Client
+	targets     []string
+	jobName     string
+	bearerToken alloytypes.Secret
+	debug       component.DebugPublisher
+	appender    component.Appender
 }
 
-// New creates a new Prometheus scraper. Oh hi.
-func New() *Scraper {
-	return &Scraper{
-		targets: make([]string, 0),
+var (
+	_ component.LiveDebuggable  = (*Scraper)(nil)
+	_ component.MetricsProducer = (*Scraper)(nil)
+)
+
+// New creates a new Prometheus scraper for the targets in args. Oh hi.
+func New(opts component.Options, args Arguments) *Scraper {
+	s := &Scraper{
+		id:          opts.ID,
+		logger:      opts.Logger,
+		client:      opts.HTTPClient,
+		targets:     make([]string, 0, len(args.Targets)),
+		jobName:     args.JobName,
+		bearerToken: args.BearerToken,
+	}
+	if s.logger == nil {
+		s.logger = zap.NewNop()
+	}
+	if s.client == nil {
+		s.client = http.DefaultClient
 	}
+	if s.jobName == "" {
+		s.jobName = opts.ID
+	}
+	for _, target := range args.Targets {
+		s.AddTarget(target["__address__"])
+	}
+	return s
 }
 
-// Name returns the component name.
+// Name returns the component ID.
 func (s *Scraper) Name() string {
-	return "prometheus.scrape"
+	return s.id
 }
 
-// Run starts the scraper.
+// Run scrapes every target once. It returns the errors of all the targets
+// which failed.
 func (s *Scraper) Run() error {
-	fmt.Printf("[%s] Starting with %d targets\n", s.Name(), len(s.targets))
+	s.logger.Info("starting scraper", zap.Int("targets", len(s.targets)))
+
+	if s.debug != nil && s.debug.Active() {
+		for _, target := range s.targets {
+			s.debug.Publish(fmt.Sprintf("scrape target=%s", target))
+		}
+	}
+
+	if s.appender == nil {
+		return nil
+	}
+	// A failing target doesn't stop the others from being scraped.
+	var errs []error
+	for _, target := range s.targets {
+		if err := s.scrape(target); err != nil {
+			errs = append(errs, fmt.Errorf("scraping %s: %w", target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// scrape fetches the metrics of target and appends them, labeled with the
+// job and instance they were scraped from. Appended samples are published to
+// live debugging.
+func (s *Scraper) scrape(target string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+target+"/metrics", nil)
+	if err != nil {
+		return err
+	}
+	if s.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.bearerToken.Reveal())
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	samples, err := ParseText(string(body))
+	if err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		sample.Labels["job"] = s.jobName
+		sample.Labels["instance"] = target
+		if err := s.appender.Append(sample); err != nil {
+			return err
+		}
+		if s.debug != nil && s.debug.Active() {
+			s.debug.Publish(FormatSample(sample))
+		}
+	}
 	return nil
 }
 
@@ -41,3 +158,13 @@ func (s *Scraper) Run() error {
 func (s *Scraper) AddTarget(target string) {
 	s.targets = append(s.targets, target)
 }
+
+// SetAppender implements component.MetricsProducer.
+func (s *Scraper) SetAppender(a component.Appender) {
+	s.appender = a
+}
+
+// SetDebugPublisher implements component.LiveDebuggable.
+func (s *Scraper) SetDebugPublisher(p component.DebugPublisher) {
+	s.debug = p
+}
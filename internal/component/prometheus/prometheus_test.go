@@ -0,0 +1,82 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/internal/component"
+)
+
+func TestScraper_PublishesSamples(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "up 1")
+	}))
+	defer srv.Close()
+	target := strings.TrimPrefix(srv.URL, "http://")
+
+	s := New(component.Options{ID: "prometheus.scrape.default"}, Arguments{
+		Targets: []map[string]string{{"__address__": target}},
+	})
+	s.SetAppender(appenderFunc(func(component.Sample) error { return nil }))
+	debug := &publisher{}
+	s.SetDebugPublisher(debug)
+
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := fmt.Sprintf(`up{instance=%q,job="prometheus.scrape.default"} 1`, target)
+	found := false
+	for _, data := range debug.published {
+		found = found || data == expect
+	}
+	if !found {
+		t.Errorf("published %q, want it to include %q", debug.published, expect)
+	}
+}
+
+type appenderFunc func(component.Sample) error
+
+func (f appenderFunc) Append(s component.Sample) error { return f(s) }
+
+// publisher records the data published to it.
+type publisher struct {
+	published []string
+}
+
+func (p *publisher) Active() bool { return true }
+
+func (p *publisher) Publish(data string) { p.published = append(p.published, data) }
+
+func TestScraper_ScrapesPastFailures(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "up 1")
+	}))
+	defer working.Close()
+	failingTarget := strings.TrimPrefix(failing.URL, "http://")
+	workingTarget := strings.TrimPrefix(working.URL, "http://")
+
+	s := New(component.Options{ID: "prometheus.scrape.default"}, Arguments{
+		Targets: []map[string]string{{"__address__": failingTarget}, {"__address__": workingTarget}},
+	})
+	var appended []component.Sample
+	s.SetAppender(appenderFunc(func(s component.Sample) error {
+		appended = append(appended, s)
+		return nil
+	}))
+
+	err := s.Run()
+	if expect := fmt.Sprintf("scraping %s: unexpected status 500 Internal Server Error", failingTarget); err == nil || err.Error() != expect {
+		t.Errorf("expected error %q, got %v", expect, err)
+	}
+	if len(appended) != 1 || appended[0].Labels["instance"] != workingTarget {
+		t.Errorf("expected the sample of %s to be appended, got %v", workingTarget, appended)
+	}
+}
@@ -0,0 +1,148 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jharvey10/test-repo/internal/component"
+)
+
+// nameLabel is the label holding the name of a series.
+const nameLabel = "__name__"
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// ParseText parses samples in the Prometheus text exposition format, such
+// as:
+//
+//	# HELP http_requests_total Requests served.
+//	http_requests_total{code="200"} 1027
+//
+// Comments, blank lines and timestamps are ignored.
+func ParseText(text string) ([]component.Sample, error) {
+	var samples []component.Sample
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+// parseSample parses a single line of the text exposition format.
+func parseSample(line string) (component.Sample, error) {
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return component.Sample{}, fmt.Errorf("invalid sample %q", line)
+	}
+	s := component.Sample{Labels: map[string]string{nameLabel: line[:end]}}
+	rest := line[end:]
+
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		if rest, err = parseLabels(rest[1:], s.Labels); err != nil {
+			return component.Sample{}, err
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return component.Sample{}, fmt.Errorf("expected a value and an optional timestamp, got %q", rest)
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return component.Sample{}, fmt.Errorf("invalid value %q", fields[0])
+	}
+	s.Value = v
+	return s, nil
+}
+
+// parseLabels parses the labels following an opening brace into labels,
+// and returns the rest of the line after the closing brace.
+func parseLabels(rest string, labels map[string]string) (string, error) {
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(rest, "}") {
+			return rest[1:], nil
+		}
+
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return "", fmt.Errorf("expected a label name followed by =, got %q", rest)
+		}
+		name := strings.TrimSpace(rest[:eq])
+		rest = strings.TrimLeft(rest[eq+1:], " \t")
+		if !strings.HasPrefix(rest, `"`) {
+			return "", fmt.Errorf("expected a quoted value for label %q", name)
+		}
+
+		var (
+			value   strings.Builder
+			escaped bool
+			closed  = -1
+		)
+		for i := 1; i < len(rest) && closed < 0; i++ {
+			switch c := rest[i]; {
+			case escaped:
+				if c == 'n' {
+					c = '\n'
+				}
+				value.WriteByte(c)
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				closed = i
+			default:
+				value.WriteByte(c)
+			}
+		}
+		if closed < 0 {
+			return "", fmt.Errorf("unterminated value for label %q", name)
+		}
+		labels[name] = value.String()
+
+		rest = strings.TrimLeft(rest[closed+1:], " \t")
+		if strings.HasPrefix(rest, ",") {
+			rest = rest[1:]
+		} else if !strings.HasPrefix(rest, "}") {
+			return "", fmt.Errorf("expected , or } after label %q", name)
+		}
+	}
+}
+
+// FormatSample formats s as a line of the text exposition format, with
+// its labels sorted by name.
+func FormatSample(s component.Sample) string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		if name != nameLabel {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString(s.Labels[nameLabel])
+	if len(names) > 0 {
+		sb.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, `%s="%s"`, name, labelValueEscaper.Replace(s.Labels[name]))
+		}
+		sb.WriteByte('}')
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(strconv.FormatFloat(s.Value, 'g', -1, 64))
+	return sb.String()
+}
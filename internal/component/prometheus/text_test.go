@@ -0,0 +1,53 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	samples, err := ParseText(`
+# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{code="200",path="/a \"quoted\"\\path"} 1027 1395066363000
+http_requests_total{ code = "500" , } 3
+up 1
+temperature -Inf
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, s := range samples {
+		lines = append(lines, FormatSample(s))
+	}
+	expect := []string{
+		`http_requests_total{code="200",path="/a \"quoted\"\\path"} 1027`,
+		`http_requests_total{code="500"} 3`,
+		`up 1`,
+		`temperature -Inf`,
+	}
+	if got := strings.Join(lines, "\n"); got != strings.Join(expect, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expect, "\n"), got)
+	}
+}
+
+func TestParseText_Errors(t *testing.T) {
+	tt := []struct {
+		text   string
+		expect string
+	}{
+		{"up", `line 1: invalid sample "up"`},
+		{"\nup one", `line 2: invalid value "one"`},
+		{`up{job="a"`, `line 1: expected , or } after label "job"`},
+		{`up{job=a} 1`, `line 1: expected a quoted value for label "job"`},
+		{`up{job="a} 1`, `line 1: unterminated value for label "job"`},
+		{`up 1 2 3`, `line 1: expected a value and an optional timestamp, got " 1 2 3"`},
+	}
+	for _, tc := range tt {
+		if _, err := ParseText(tc.text); err == nil || err.Error() != tc.expect {
+			t.Errorf("%q: expected error %q, got %v", tc.text, tc.expect, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package component
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stability is the stability level of a component. Levels are ordered
+// from least to most stable.
+type Stability uint8
+
+const (
+	// StabilityUndefined is the stability of registrations which don't set
+	// one. They are treated as generally available.
+	StabilityUndefined Stability = iota
+	// StabilityExperimental components may change or be removed at any
+	// time.
+	StabilityExperimental
+	// StabilityPublicPreview components are ready to be tried out, but may
+	// still change.
+	StabilityPublicPreview
+	// StabilityGenerallyAvailable components are stable.
+	StabilityGenerallyAvailable
+)
+
+// stabilityNames are the names of the defined stability levels, from least
+// to most stable.
+var stabilityNames = []string{"experimental", "public-preview", "generally-available"}
+
+// ParseStability parses the name of a stability level, such as
+// "public-preview".
+func ParseStability(name string) (Stability, error) {
+	for i, n := range stabilityNames {
+		if n == name {
+			return Stability(i + 1), nil
+		}
+	}
+	return StabilityUndefined, fmt.Errorf("invalid stability level %q, must be one of %s", name, strings.Join(stabilityNames, ", "))
+}
+
+// String returns the name of the stability level.
+func (s Stability) String() string {
+	switch s {
+	case StabilityUndefined:
+		return "undefined"
+	case StabilityExperimental, StabilityPublicPreview, StabilityGenerallyAvailable:
+		return stabilityNames[s-1]
+	default:
+		return fmt.Sprintf("Stability(%d)", uint8(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Stability) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Permits reports whether a component with the stability level other may
+// be used when s is the minimum stability level.
+func (s Stability) Permits(other Stability) bool {
+	if other == StabilityUndefined {
+		other = StabilityGenerallyAvailable
+	}
+	return other >= s
+}
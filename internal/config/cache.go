@@ -0,0 +1,121 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+// Cache parses and decodes successive versions of a configuration
+// incrementally, so that reloading it only parses and decodes the blocks
+// which changed. It combines a parser.Cache for each file with a vm.Cache
+// for the arguments of the blocks. A Cache must not be used concurrently.
+type Cache struct {
+	parsers map[string]*parser.Cache // Keyed by filename.
+	vm      *vm.Cache
+	decoded map[*ast.BlockStmt]*decodedBlock
+	scope   *vm.Scope // The root scope of the latest version.
+}
+
+// decodedBlock holds the decoded arguments of a block.
+type decodedBlock struct {
+	body *ast.BlockStmt // The block without its label.
+	args reflect.Value  // Pointer to the arguments.
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{
+		parsers: make(map[string]*parser.Cache),
+		vm:      vm.NewCache(),
+		decoded: make(map[*ast.BlockStmt]*decodedBlock),
+		scope:   syntax.RootScope(),
+	}
+}
+
+// ParseFiles is like the ParseFiles function, but reuses the statements
+// which did not change since the previous call. Files with a .json
+// extension are always parsed in full.
+//
+// ParseFiles starts a new version of the configuration: blocks which are
+// not part of it are dropped from the cache, and the constants blocks are
+// decoded with are read again.
+func (c *Cache) ParseFiles(files map[string][]byte) (*ast.File, error) {
+	for name := range c.parsers {
+		if _, ok := files[name]; !ok {
+			delete(c.parsers, name)
+		}
+	}
+
+	f, err := parseFiles(files, func(filename string, src []byte) (*ast.File, error) {
+		if filepath.Ext(filename) == ".json" {
+			return parser.ParseJSON(filename, src)
+		}
+		pc := c.parsers[filename]
+		if pc == nil {
+			pc = parser.NewCache()
+			c.parsers[filename] = pc
+		}
+		return pc.ParseFile(filename, src)
+	})
+
+	decoded := make(map[*ast.BlockStmt]*decodedBlock, len(c.decoded))
+	for _, stmt := range f.Body {
+		if block, ok := stmt.(*ast.BlockStmt); ok && c.decoded[block] != nil {
+			decoded[block] = c.decoded[block]
+		}
+	}
+	c.decoded = decoded
+	c.scope = syntax.RootScope()
+	c.vm.Sweep()
+	return f, err
+}
+
+// ValidateFile is like the ValidateFile function, but decodes arguments
+// with DecodeArgs.
+func (c *Cache) ValidateFile(f *ast.File) diag.Diagnostics {
+	return validateFile(f, c.DecodeArgs)
+}
+
+// DecodeArgs is like the DecodeArgs function, but only decodes block again
+// if it or a value it references changed since it was last decoded. block
+// must be part of the file returned by the latest call to ParseFiles.
+//
+// The arguments of blocks which are not decoded again are shared with the
+// earlier results, so they must not be modified.
+func (c *Cache) DecodeArgs(zero any, block *ast.BlockStmt) (any, error) {
+	return c.EvaluateArgs(c.scope, zero, block)
+}
+
+// EvaluateArgs is like DecodeArgs, but resolves the identifiers in block
+// against scope. Blocks are decoded again when the values they reference
+// in scope change.
+func (c *Cache) EvaluateArgs(scope *vm.Scope, zero any, block *ast.BlockStmt) (any, error) {
+	ty := argsType(zero)
+	d := c.decoded[block]
+	if d == nil || d.args.Type().Elem() != ty {
+		d = &decodedBlock{body: unlabeled(block)}
+		c.decoded[block] = d
+	}
+
+	// Arguments are decoded into a new value, so that decoding doesn't
+	// modify the arguments returned earlier.
+	args := reflect.New(ty)
+	evaluated, err := c.vm.EvaluateID(scope, block.GetBlockName()+"."+block.Label, d.body, args.Interface())
+	if err != nil {
+		delete(c.decoded, block)
+		return nil, err
+	}
+	if evaluated {
+		d.args = args
+	}
+	if zero == nil {
+		return nil, nil
+	}
+	return d.args.Elem().Interface(), nil
+}
@@ -0,0 +1,73 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/internal/component/prometheus"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+func TestCache_DecodeArgs(t *testing.T) {
+	const src = `prometheus.scrape "a" {
+	targets = [{"__address__" = "a:9090"}]
+}
+
+prometheus.scrape "b" {
+	targets = [{"__address__" = "b:9090"}]
+}
+`
+	c := config.NewCache()
+
+	// decode returns the decoded arguments of each block in src, keyed by
+	// label.
+	decode := func(src string) map[string]prometheus.Arguments {
+		t.Helper()
+		f, err := c.ParseFiles(map[string][]byte{"config.alloy": []byte(src)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ds := c.ValidateFile(f); len(ds) > 0 {
+			t.Fatal(ds)
+		}
+
+		res := make(map[string]prometheus.Arguments)
+		for _, stmt := range f.Body {
+			block := stmt.(*ast.BlockStmt)
+			args, err := c.DecodeArgs(prometheus.Arguments{}, block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res[block.Label] = args.(prometheus.Arguments)
+		}
+		return res
+	}
+
+	first := decode(src)
+	second := decode(strings.Replace(src, "b:9090", "b:9091", 1))
+
+	if &first["a"].Targets[0] != &second["a"].Targets[0] {
+		t.Error("expected the arguments of the unchanged block to be reused")
+	}
+	if addr := second["b"].Targets[0]["__address__"]; addr != "b:9091" {
+		t.Errorf("expected the changed block to be decoded again, got address %q", addr)
+	}
+	if addr := first["b"].Targets[0]["__address__"]; addr != "b:9090" {
+		t.Errorf("expected earlier arguments to be left untouched, got address %q", addr)
+	}
+}
+
+func TestCache_DecodeArgsError(t *testing.T) {
+	c := config.NewCache()
+	for i := 0; i < 2; i++ {
+		f, err := c.ParseFiles(map[string][]byte{"config.alloy": []byte("prometheus.scrape \"a\" {\n\ttargets = []\n\tjob_name = 12\n}\n")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Blocks which fail to decode are decoded again every time.
+		if ds := c.ValidateFile(f); len(ds) != 1 || ds[0].Message != "expected string, got number" {
+			t.Errorf("load %d: unexpected diagnostics: %v", i, ds)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Package config checks configuration files against the component
+// registry.
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/importsource"
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+// Parse parses the configuration file src. Files with a .json extension
+// are read in the JSON representation described by parser.ParseJSON.
+func Parse(filename string, src []byte) (*ast.File, error) {
+	if filepath.Ext(filename) == ".json" {
+		return parser.ParseJSON(filename, src)
+	}
+	return parser.ParseFile(filename, src)
+}
+
+// ParseFiles parses several configuration files, keyed by filename, as a
+// single configuration. The statements of each file are appended in
+// filename order. Errors in every file are reported.
+func ParseFiles(files map[string][]byte) (*ast.File, error) {
+	return parseFiles(files, Parse)
+}
+
+func parseFiles(files map[string][]byte, parse func(filename string, src []byte) (*ast.File, error)) (*ast.File, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		merged = &ast.File{Name: strings.Join(names, ",")}
+		ds     diag.Diagnostics
+	)
+	for _, name := range names {
+		f, err := parse(name, files[name])
+		ds.Merge(diag.FromError(err))
+		merged.Body = append(merged.Body, f.Body...)
+		merged.Comments = append(merged.Comments, f.Comments...)
+	}
+	return merged, ds.Err()
+}
+
+// Validate parses the configuration file src and checks that it only
+// declares registered components and import blocks, each with a unique
+// label and with arguments which decode. Syntax errors are reported
+// alongside any problems found in the parts of the file which could be
+// parsed, sorted by position.
+func Validate(filename string, src []byte) diag.Diagnostics {
+	f, err := Parse(filename, src)
+	ds := diag.FromError(err)
+	ds.Merge(ValidateFile(f))
+	ds.Sort()
+	return ds
+}
+
+// ValidateFile checks a parsed configuration file. See Validate.
+func ValidateFile(f *ast.File) diag.Diagnostics {
+	return validateFile(f, DecodeArgs)
+}
+
+// validateFile implements ValidateFile, decoding arguments with decode.
+//
+// Blocks named after the label of an import block, such as shared.pipeline
+// for import.file "shared", are instances of components declared by the
+// imported module. Their arguments, and the arguments of blocks which
+// reference the exports of such instances, are only decoded once the
+// module is loaded.
+func validateFile(f *ast.File, decode func(zero any, block *ast.BlockStmt) (any, error)) diag.Diagnostics {
+	var (
+		ds       diag.Diagnostics
+		declared = make(map[string]*ast.BlockStmt)
+		imports  = importLabels(f)
+	)
+
+	for _, stmt := range f.Body {
+		switch stmt := stmt.(type) {
+		case *ast.AttributeStmt:
+			ds.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				StartPos: ast.StartPos(stmt.Name).Position(),
+				EndPos:   ast.EndPos(stmt.Name).Position(),
+				Message:  fmt.Sprintf("attribute %q is not allowed outside of a component block", stmt.Name.Name),
+			})
+
+		case *ast.BlockStmt:
+			name := stmt.GetBlockName()
+			importArgs, isImport := importsource.Blocks[name]
+			reg, ok := component.Get(name)
+			module := imports[stmt.Name[0]]
+			isInstance := !ok && !isImport && module != nil
+			if !ok && !isImport && !isInstance {
+				ds.Add(unrecognizedError(stmt))
+				continue
+			}
+			if isInstance && len(stmt.Name) != 2 {
+				ds.Add(diag.Diagnostic{
+					Severity: diag.SeverityLevelError,
+					StartPos: stmt.NamePos.Position(),
+					EndPos:   stmt.NamePos.Add(len(name) - 1).Position(),
+					Message:  fmt.Sprintf("invalid name %q for a component of the module imported by %s", name, module.GetBlockName()+"."+module.Label),
+					Hint:     fmt.Sprintf("components of the module are named %s.<declare label>", module.Label),
+				})
+				continue
+			}
+			if err := checkComponentLabel(stmt, declared); err != nil {
+				ds.Add(*err)
+				continue
+			}
+
+			switch {
+			case isImport:
+				if prev := imports[stmt.Label]; prev != stmt {
+					ds.Add(labelError(stmt, fmt.Sprintf("import label %q already used at %s", stmt.Label, prev.NamePos)))
+					continue
+				}
+				ds.Merge(validateImport(stmt, importArgs, decode))
+			case isInstance, referencesImports(stmt, imports):
+				// Checked when the module is loaded.
+			default:
+				if _, err := decode(reg.Args, stmt); err != nil {
+					ds.Merge(diag.FromError(err))
+				}
+			}
+		}
+	}
+
+	return ds
+}
+
+// importLabels returns the import blocks of f keyed by label. Only the
+// first block with each label is kept.
+func importLabels(f *ast.File) map[string]*ast.BlockStmt {
+	imports := make(map[string]*ast.BlockStmt)
+	for _, stmt := range f.Body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok || block.Label == "" || imports[block.Label] != nil {
+			continue
+		}
+		if _, ok := importsource.Blocks[block.GetBlockName()]; ok {
+			imports[block.Label] = block
+		}
+	}
+	return imports
+}
+
+// referencesImports reports whether block references the label of one of
+// imports.
+func referencesImports(block *ast.BlockStmt, imports map[string]*ast.BlockStmt) bool {
+	if len(imports) == 0 {
+		return false
+	}
+	v := &identifierFinder{names: imports}
+	ast.Walk(v, block)
+	return v.found
+}
+
+// identifierFinder is an ast.Visitor which looks for identifiers with one
+// of names.
+type identifierFinder struct {
+	names map[string]*ast.BlockStmt
+	found bool
+}
+
+func (v *identifierFinder) Visit(node ast.Node) ast.Visitor {
+	if ident, ok := node.(*ast.IdentifierExpr); ok && v.names[ident.Ident.Name] != nil {
+		v.found = true
+	}
+	if v.found {
+		return nil
+	}
+	return v
+}
+
+// unrecognizedError returns the diagnostic for a block which isn't a
+// registered component.
+func unrecognizedError(block *ast.BlockStmt) diag.Diagnostic {
+	name := block.GetBlockName()
+	return diag.Diagnostic{
+		Severity: diag.SeverityLevelError,
+		StartPos: block.NamePos.Position(),
+		EndPos:   block.NamePos.Add(len(name) - 1).Position(),
+		Message:  fmt.Sprintf("unrecognized component name %q", name),
+		Hint:     diag.Suggest(name, ComponentNames()),
+	}
+}
+
+// checkComponentLabel checks that the component declared by block has a
+// label, and that it is the only one with its name and label in declared,
+// which it is added to.
+func checkComponentLabel(block *ast.BlockStmt, declared map[string]*ast.BlockStmt) *diag.Diagnostic {
+	name := block.GetBlockName()
+	if block.Label == "" {
+		return &diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			StartPos: block.NamePos.Position(),
+			EndPos:   block.NamePos.Add(len(name) - 1).Position(),
+			Message:  fmt.Sprintf("component %q must have a label", name),
+			Hint:     fmt.Sprintf(`add a label, such as: %s "default" { ... }`, name),
+		}
+	}
+
+	id := name + "." + block.Label
+	if prev, exists := declared[id]; exists {
+		d := labelError(block, fmt.Sprintf("component %q already declared at %s", id, prev.NamePos))
+		return &d
+	}
+	declared[id] = block
+	return nil
+}
+
+// validateImport checks the arguments of an import block.
+func validateImport(block *ast.BlockStmt, zero importsource.BlockArguments, decode func(any, *ast.BlockStmt) (any, error)) diag.Diagnostics {
+	args, err := decode(zero, block)
+	if err != nil {
+		return diag.FromError(err)
+	}
+	if _, err := args.(importsource.BlockArguments).Poller(); err != nil {
+		name := block.GetBlockName()
+		return diag.Diagnostics{{
+			Severity: diag.SeverityLevelError,
+			StartPos: block.NamePos.Position(),
+			EndPos:   block.NamePos.Add(len(name) - 1).Position(),
+			Message:  err.Error(),
+		}}
+	}
+	return nil
+}
+
+// DecodeArgs decodes block into a new value of the type of zero. It
+// returns nil for blocks without arguments, which must be empty.
+func DecodeArgs(zero any, block *ast.BlockStmt) (any, error) {
+	return EvaluateArgs(syntax.RootScope(), zero, block)
+}
+
+// EvaluateArgs is like DecodeArgs, but resolves the identifiers in block
+// against scope.
+func EvaluateArgs(scope *vm.Scope, zero any, block *ast.BlockStmt) (any, error) {
+	args := reflect.New(argsType(zero))
+	if err := vm.New(unlabeled(block)).Evaluate(scope, args.Interface()); err != nil {
+		return nil, err
+	}
+	if zero == nil {
+		return nil, nil
+	}
+	return args.Elem().Interface(), nil
+}
+
+// argsType returns the type arguments are decoded into for zero.
+func argsType(zero any) reflect.Type {
+	if ty := reflect.TypeOf(zero); ty != nil {
+		return ty
+	}
+	return reflect.TypeOf(struct{}{})
+}
+
+// unlabeled returns a copy of block without its label. The label names the
+// block rather than being one of its arguments. Decoding a copy of the
+// block without it keeps errors pointing at the block.
+func unlabeled(block *ast.BlockStmt) *ast.BlockStmt {
+	body := *block
+	body.Label = ""
+	return &body
+}
+
+// ComponentNames returns the sorted names of all registered components.
+func ComponentNames() []string {
+	names := make([]string, 0, len(component.All()))
+	for name := range component.All() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,207 @@
+package config_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	_ "github.com/jharvey10/test-repo/internal/component/prometheus"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+)
+
+func TestValidate(t *testing.T) {
+	src := `prometheus.scrape "default" {
+	targets = []
+}
+import.file "shared" { filename = "shared.alloy" }
+import.file { filename = "shared.alloy" }
+prometheus.scrap "typo" { }
+prometheus.scrape { }
+prometheus.scrape "default" { }
+level = "debug"
+prometheus.scrape "job" {
+	targets  = []
+	job_name = 12
+}
+prometheus.scrape "unknown" {
+	targets = []
+	timeout = "10s"
+}
+prometheus.scrape "missing" { }
+import.file "frequency" {
+	filename       = "shared.alloy"
+	poll_frequency = "often"
+}
+broken = 
+`
+
+	type result struct {
+		line, col int
+		message   string
+		hint      string
+	}
+	expect := []result{
+		{5, 1, `component "import.file" must have a label`, `add a label, such as: import.file "default" { ... }`},
+		{6, 1, `unrecognized component name "prometheus.scrap"`, `did you mean "prometheus.scrape"?`},
+		{7, 1, `component "prometheus.scrape" must have a label`, `add a label, such as: prometheus.scrape "default" { ... }`},
+		{8, 1, `component "prometheus.scrape.default" already declared at config.alloy:1:1`, ""},
+		{9, 1, `attribute "level" is not allowed outside of a component block`, ""},
+		{12, 13, "expected string, got number", ""},
+		{16, 2, `unrecognized attribute name "timeout"`, ""},
+		{18, 1, `missing required attribute "targets"`, ""},
+		{19, 1, `invalid poll_frequency "often": must be a positive duration such as "1m"`, ""},
+		{23, 1, `attribute "broken" is not allowed outside of a component block`, ""},
+		{24, 1, "expected expression, got EOF", ""},
+	}
+
+	ds := config.Validate("config.alloy", []byte(src))
+	if len(ds) != len(expect) {
+		t.Fatalf("expected %d diagnostics, got %d:\n%s", len(expect), len(ds), ds.Error())
+	}
+	for i, d := range ds {
+		actual := result{d.StartPos.Line, d.StartPos.Column, d.Message, d.Hint}
+		if actual != expect[i] {
+			t.Errorf("diagnostic %d: expected %+v, got %+v", i, expect[i], actual)
+		}
+	}
+}
+
+func TestValidate_ModuleComponents(t *testing.T) {
+	src := `import.file "shared" { filename = "shared.alloy" }
+shared.pipeline "a" { value = 1 }
+shared.pipeline "a" { }
+shared.pipeline { }
+shared.pipeline.nested "a" { }
+prometheus.scrape "a" {
+	targets  = []
+	job_name = shared.pipeline.a.job
+}
+import.git "shared" { repository = "https://example.com/modules.git" }
+other.pipeline "a" { }
+`
+
+	expect := []string{
+		`3:1: component "shared.pipeline.a" already declared at config.alloy:2:1`,
+		`4:1: component "shared.pipeline" must have a label`,
+		`5:1: invalid name "shared.pipeline.nested" for a component of the module imported by import.file.shared`,
+		`10:1: import label "shared" already used at config.alloy:1:1`,
+		`11:1: unrecognized component name "other.pipeline"`,
+	}
+
+	ds := config.Validate("config.alloy", []byte(src))
+	var actual []string
+	for _, d := range ds {
+		actual = append(actual, fmt.Sprintf("%d:%d: %s", d.StartPos.Line, d.StartPos.Column, d.Message))
+	}
+	if strings.Join(actual, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expected diagnostics:\n%s\ngot:\n%s", strings.Join(expect, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestValidateModule(t *testing.T) {
+	src := `declare "pipeline" {
+	argument "address" { }
+	argument "job" { default = "pipeline" }
+	argument "job" { }
+	argument "timeout" { optional = "yes" }
+	level = "debug"
+
+	prometheus.scrape "default" {
+		targets  = [{"__address__" = argument.address.value}]
+		job_name = argument.job.value
+	}
+	prometheus.scrap "typo" { }
+
+	export "job" { value = argument.job.value }
+	export "empty" { }
+	export "extra" {
+		value = 1
+		other = 2
+	}
+}
+
+declare { }
+declare "pipeline" { }
+prometheus.scrape "default" { targets = [] }
+`
+
+	expect := []string{
+		`4:2: argument "job" already declared at config.alloy:3:2`,
+		`5:34: expected bool, got string`,
+		`6:2: attribute "level" is not allowed in a declare block`,
+		`12:2: unrecognized component name "prometheus.scrap"`,
+		`15:2: missing required attribute "value"`,
+		`18:3: export blocks may only set the value attribute`,
+		`22:1: declare block must have a label`,
+		`23:1: declare block "pipeline" already declared at config.alloy:1:1`,
+	}
+
+	f, err := config.Parse("config.alloy", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := config.ValidateModule(f)
+	ds.Sort()
+	var actual []string
+	for _, d := range ds {
+		actual = append(actual, fmt.Sprintf("%d:%d: %s", d.StartPos.Line, d.StartPos.Column, d.Message))
+	}
+	if strings.Join(actual, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("expected diagnostics:\n%s\ngot:\n%s", strings.Join(expect, "\n"), strings.Join(actual, "\n"))
+	}
+
+	d, _ := config.ParseDeclare(f.Body[0].(*ast.BlockStmt))
+	if len(d.Arguments) != 3 || d.Arguments[0].Optional || !d.Arguments[1].Optional || d.Arguments[1].Default != "pipeline" {
+		t.Errorf("unexpected arguments %+v", d.Arguments)
+	}
+	if len(d.Components) != 1 || len(d.Exports) != 3 {
+		t.Errorf("expected 1 component and 3 exports, got %d and %d", len(d.Components), len(d.Exports))
+	}
+}
+
+func TestValidate_JSON(t *testing.T) {
+	src := `[
+  {"block": "prometheus.scrape", "label": "default", "body": [
+    {"attribute": "targets", "value": {"type": "array", "value": []}}
+  ]},
+  {"block": "prometheus.scrap", "label": "typo"}
+]`
+
+	ds := config.Validate("config.json", []byte(src))
+	if len(ds) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d:\n%s", len(ds), ds.Error())
+	}
+	if d := ds[0]; d.StartPos.Line != 5 || d.StartPos.Column != 14 || d.Message != `unrecognized component name "prometheus.scrap"` {
+		t.Errorf("unexpected diagnostic %s", d.Error())
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	f, err := config.ParseFiles(map[string][]byte{
+		"b.alloy": []byte(`prometheus.scrape "default" { targets = [] }`),
+		"a.json":  []byte(`[{"block": "prometheus.scrape", "label": "other", "body": [{"attribute": "targets", "value": {"type": "array", "value": []}}]}]`),
+		"c.alloy": []byte(`prometheus.scrape "default" { targets = [] }`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Body) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(f.Body))
+	}
+
+	// Components are declared once across all files.
+	ds := config.ValidateFile(f)
+	if len(ds) != 1 || ds[0].Message != `component "prometheus.scrape.default" already declared at b.alloy:1:1` {
+		t.Errorf("unexpected diagnostics:\n%s", ds.Error())
+	}
+
+	_, err = config.ParseFiles(map[string][]byte{
+		"a.alloy": []byte("broken = \n"),
+		"b.alloy": []byte("prometheus.scrape \"x\" {\n"),
+	})
+	if ds := diag.FromError(err); len(ds) != 2 || ds[0].StartPos.Filename != "a.alloy" || ds[1].StartPos.Filename != "b.alloy" {
+		t.Errorf("expected an error from each file, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+)
+
+// Declare is a component defined by a declare block in a module. A config
+// importing the module with an import block labelled shared instantiates
+// it with a block named shared.<Name>, whose attributes are the
+// arguments of the instance:
+//
+//	declare "pipeline" {
+//		argument "address" { }
+//		argument "job" { default = "pipeline" }
+//
+//		prometheus.scrape "default" {
+//			targets  = [{"__address__" = argument.address.value}]
+//			job_name = argument.job.value
+//		}
+//
+//		export "job" { value = argument.job.value }
+//	}
+type Declare struct {
+	Name      string
+	Arguments []Argument
+	Exports   []Export
+
+	// Components are the blocks of the components an instance runs. Their
+	// arguments can only be decoded once the values of the instance's
+	// arguments are known.
+	Components []*ast.BlockStmt
+}
+
+// Argument is an argument of a Declare, set by an argument block. Inside
+// the declare block its value is referenced as argument.<Name>.value.
+type Argument struct {
+	Name string
+	// Optional arguments which are not set take the value of Default.
+	// Arguments with a default are always optional.
+	Optional bool
+	Default  any
+	Comment  string
+}
+
+// argumentBody is the body of an argument block.
+type argumentBody struct {
+	Optional bool   `syntax:"optional,attr,optional"`
+	Default  any    `syntax:"default,attr,optional"`
+	Comment  string `syntax:"comment,attr,optional"`
+}
+
+// Export is a value an instance of a Declare exports, set by an export
+// block. The importing config references it as
+// <import label>.<declare label>.<instance label>.<Name>.
+type Export struct {
+	Name  string
+	Value ast.Expr
+}
+
+// ValidateModule checks the parsed files of a module. In addition to what
+// ValidateFile accepts, modules can contain declare blocks, which are
+// checked with ParseDeclare.
+func ValidateModule(f *ast.File) diag.Diagnostics {
+	var (
+		ds       diag.Diagnostics
+		rest     = &ast.File{Name: f.Name, Comments: f.Comments}
+		declares = make(map[string]*ast.BlockStmt)
+	)
+	for _, stmt := range f.Body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok || block.GetBlockName() != "declare" {
+			rest.Body = append(rest.Body, stmt)
+			continue
+		}
+
+		_, declareDiags := ParseDeclare(block)
+		ds.Merge(declareDiags)
+		if prev, exists := declares[block.Label]; exists && block.Label != "" {
+			ds.Add(labelError(block, fmt.Sprintf("declare block %q already declared at %s", block.Label, prev.NamePos)))
+			continue
+		}
+		declares[block.Label] = block
+	}
+	ds.Merge(ValidateFile(rest))
+	return ds
+}
+
+// ParseDeclare reads the declare block block. The components it declares
+// must be registered and have unique labels, but their arguments are not
+// decoded. Diagnostics are returned for every problem found.
+func ParseDeclare(block *ast.BlockStmt) (*Declare, diag.Diagnostics) {
+	var (
+		d        = &Declare{Name: block.Label}
+		ds       diag.Diagnostics
+		declared = make(map[string]*ast.BlockStmt)
+	)
+	if err := checkLabel(block); err != nil {
+		ds.Add(*err)
+	}
+
+	for _, stmt := range block.Body {
+		switch stmt := stmt.(type) {
+		case *ast.AttributeStmt:
+			ds.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				StartPos: ast.StartPos(stmt.Name).Position(),
+				EndPos:   ast.EndPos(stmt.Name).Position(),
+				Message:  fmt.Sprintf("attribute %q is not allowed in a declare block", stmt.Name.Name),
+			})
+
+		case *ast.BlockStmt:
+			switch name := stmt.GetBlockName(); name {
+			case "argument", "export":
+				if err := checkLabel(stmt); err != nil {
+					ds.Add(*err)
+					continue
+				}
+				id := name + "." + stmt.Label
+				if prev, exists := declared[id]; exists {
+					ds.Add(labelError(stmt, fmt.Sprintf("%s %q already declared at %s", name, stmt.Label, prev.NamePos)))
+					continue
+				}
+				declared[id] = stmt
+
+				if name == "argument" {
+					arg, err := parseArgument(stmt)
+					ds.Merge(diag.FromError(err))
+					d.Arguments = append(d.Arguments, arg)
+					continue
+				}
+				export, exportDiags := parseExport(stmt)
+				ds.Merge(exportDiags)
+				d.Exports = append(d.Exports, export)
+
+			default:
+				if _, ok := component.Get(name); !ok {
+					ds.Add(unrecognizedError(stmt))
+					continue
+				}
+				if err := checkComponentLabel(stmt, declared); err != nil {
+					ds.Add(*err)
+					continue
+				}
+				d.Components = append(d.Components, stmt)
+			}
+		}
+	}
+	return d, ds
+}
+
+// parseArgument reads an argument block.
+func parseArgument(block *ast.BlockStmt) (Argument, error) {
+	arg := Argument{Name: block.Label}
+	body, err := DecodeArgs(argumentBody{}, block)
+	if err != nil {
+		return arg, err
+	}
+	b := body.(argumentBody)
+	arg.Optional = b.Optional || b.Default != nil
+	arg.Default = b.Default
+	arg.Comment = b.Comment
+	return arg, nil
+}
+
+// parseExport reads an export block, which only sets the value attribute.
+func parseExport(block *ast.BlockStmt) (Export, diag.Diagnostics) {
+	var (
+		export = Export{Name: block.Label}
+		ds     diag.Diagnostics
+	)
+	for _, stmt := range block.Body {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok || attr.Name.Name != "value" {
+			ds.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				StartPos: ast.StartPos(stmt).Position(),
+				EndPos:   ast.EndPos(stmt).Position(),
+				Message:  "export blocks may only set the value attribute",
+			})
+			continue
+		}
+		export.Value = attr.Value
+	}
+	if export.Value == nil && len(ds) == 0 {
+		ds.Add(labelError(block, `missing required attribute "value"`))
+	}
+	return export, ds
+}
+
+// checkLabel checks that block has a label. The parser already checks
+// that labels are valid identifiers.
+func checkLabel(block *ast.BlockStmt) *diag.Diagnostic {
+	if block.Label != "" {
+		return nil
+	}
+	name := block.GetBlockName()
+	return &diag.Diagnostic{
+		Severity: diag.SeverityLevelError,
+		StartPos: block.NamePos.Position(),
+		EndPos:   block.NamePos.Add(len(name) - 1).Position(),
+		Message:  fmt.Sprintf("%s block must have a label", name),
+		Hint:     fmt.Sprintf(`add a label, such as: %s "default" { ... }`, name),
+	}
+}
+
+// labelError returns an error diagnostic spanning the name and label of
+// block.
+func labelError(block *ast.BlockStmt, msg string) diag.Diagnostic {
+	return diag.Diagnostic{
+		Severity: diag.SeverityLevelError,
+		StartPos: block.NamePos.Position(),
+		EndPos:   block.LabelPos.Add(len(block.Label) + 1).Position(),
+		Message:  msg,
+	}
+}
@@ -0,0 +1,361 @@
+// Package convert converts configuration files written for other tools
+// into native configuration.
+//
+// Each source format is read from YAML and mapped onto the equivalent
+// components, which are written with the syntax encoder. Fields which
+// have no native equivalent are reported as warnings; malformed input and
+// components which are not registered are reported as errors.
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/scanner"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// Format is a source configuration format.
+type Format string
+
+// Supported Format values.
+const (
+	FormatPrometheus Format = "prometheus"
+	FormatOtelCol    Format = "otelcol"
+	FormatPromtail   Format = "promtail"
+)
+
+// Formats lists every supported source format.
+var Formats = []Format{FormatPrometheus, FormatOtelCol, FormatPromtail}
+
+// Convert converts the configuration file src, written in the given source
+// format, into native configuration. filename is used in diagnostics. The
+// converted configuration is nil if any errors were reported.
+func Convert(filename string, src []byte, format Format) ([]byte, diag.Diagnostics) {
+	c := &converter{filename: filename, labels: make(map[string]int)}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(src, &root); err != nil {
+		c.diags.Add(diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			StartPos: token.Position{Filename: filename},
+			Message:  err.Error(),
+		})
+		return nil, c.diags
+	}
+	doc := &root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+
+	var out any
+	switch format {
+	case FormatPrometheus:
+		out = c.convertPrometheus(c.object(doc, ""))
+	case FormatOtelCol:
+		out = c.convertOtelCol(c.object(doc, ""))
+	case FormatPromtail:
+		out = c.convertPromtail(c.object(doc, ""))
+	default:
+		c.diags.Add(diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			Message:  fmt.Sprintf("unsupported source format %q", format),
+		})
+		return nil, c.diags
+	}
+
+	c.reportUnused()
+	c.reportUnavailable()
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i].StartPos, c.diags[j].StartPos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	if c.diags.HasErrors() {
+		return nil, c.diags
+	}
+
+	res, err := syntax.Marshal(out)
+	if err != nil {
+		c.diags.Add(diag.Diagnostic{Severity: diag.SeverityLevelError, Message: err.Error()})
+		return nil, c.diags
+	}
+	return res, c.diags
+}
+
+// converter holds the state of a single conversion.
+type converter struct {
+	filename string
+	diags    diag.Diagnostics
+	objects  []*object
+	labels   map[string]int // Number of uses of each component label.
+
+	components []convertedComponent
+}
+
+// convertedComponent is a component written to the converted
+// configuration, along with the node it was converted from.
+type convertedComponent struct {
+	name, label string
+	node        *yaml.Node
+}
+
+func (c *converter) add(severity diag.Severity, n *yaml.Node, msg string) {
+	d := diag.Diagnostic{Severity: severity, Message: msg}
+	d.StartPos = token.Position{Filename: c.filename}
+	if n != nil {
+		d.StartPos.Line, d.StartPos.Column = n.Line, n.Column
+		d.EndPos = d.StartPos
+		if n.Kind == yaml.ScalarNode && n.Value != "" && !strings.Contains(n.Value, "\n") {
+			d.EndPos.Column += len(n.Value) - 1
+		}
+	}
+	c.diags.Add(d)
+}
+
+func (c *converter) errorf(n *yaml.Node, format string, args ...any) {
+	c.add(diag.SeverityLevelError, n, fmt.Sprintf(format, args...))
+}
+
+func (c *converter) warnf(n *yaml.Node, format string, args ...any) {
+	c.add(diag.SeverityLevelWarn, n, fmt.Sprintf(format, args...))
+}
+
+// reportUnused warns about every key which was not read by a converter.
+func (c *converter) reportUnused() {
+	for _, o := range c.objects {
+		for i := 0; i+1 < len(o.node.Content); i += 2 {
+			key := o.node.Content[i]
+			if !o.used[key.Value] {
+				c.warnf(key, "unsupported field %q", o.path+key.Value)
+			}
+		}
+	}
+}
+
+// reportUnavailable reports every converted component which is not
+// registered, as the converted configuration could not be loaded.
+func (c *converter) reportUnavailable() {
+	for _, comp := range c.components {
+		if _, ok := component.Get(comp.name); !ok {
+			c.errorf(comp.node, "cannot convert to %s %q: the component is not available", comp.name, comp.label)
+		}
+	}
+}
+
+// component returns a unique label for an instance of the component called
+// name, converted from the node n. See label.
+func (c *converter) component(n *yaml.Node, name, label string) string {
+	label = c.label(name, label)
+	c.components = append(c.components, convertedComponent{name: name, label: label, node: n})
+	return label
+}
+
+// label returns a unique label for an instance of the component called
+// name. Characters which are not valid in identifiers are replaced with
+// underscores, and repeated labels are numbered.
+func (c *converter) label(name, label string) string {
+	var sb strings.Builder
+	for _, r := range label {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	label = sb.String()
+	if label == "" || !scanner.IsValidIdentifier(label) {
+		label = "_" + label
+	}
+
+	key := name + "." + label
+	c.labels[key]++
+	if n := c.labels[key]; n > 1 {
+		return c.label(name, label+"_"+strconv.Itoa(n))
+	}
+	return label
+}
+
+// object is a YAML mapping. It records which keys were read, so keys with
+// no native equivalent can be reported.
+type object struct {
+	c    *converter
+	node *yaml.Node
+	path string // Prefix for the names of keys in diagnostics.
+	used map[string]bool
+}
+
+// object returns the mapping n. path is the name of n in diagnostics. A
+// missing or null node is treated as an empty mapping.
+func (c *converter) object(n *yaml.Node, path string) *object {
+	o := &object{c: c, node: &yaml.Node{Kind: yaml.MappingNode}, used: make(map[string]bool)}
+	if path != "" {
+		o.path = path + "."
+	}
+	switch {
+	case n == nil || n.Tag == "!!null":
+	case n.Kind != yaml.MappingNode:
+		c.errorf(n, "%s: expected a mapping", strings.TrimSuffix(path, "."))
+	default:
+		o.node = n
+	}
+	c.objects = append(c.objects, o)
+	return o
+}
+
+// keys returns the keys of the mapping in order.
+func (o *object) keys() []*yaml.Node {
+	var keys []*yaml.Node
+	for i := 0; i+1 < len(o.node.Content); i += 2 {
+		keys = append(keys, o.node.Content[i])
+	}
+	return keys
+}
+
+// get returns the value of key, or nil if it is not set.
+func (o *object) get(key string) *yaml.Node {
+	o.used[key] = true
+	for i := 0; i+1 < len(o.node.Content); i += 2 {
+		if o.node.Content[i].Value == key {
+			return o.node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// key returns the node of key itself, or nil if it is not set. Like get,
+// it marks key as read.
+func (o *object) key(key string) *yaml.Node {
+	o.used[key] = true
+	for i := 0; i+1 < len(o.node.Content); i += 2 {
+		if o.node.Content[i].Value == key {
+			return o.node.Content[i]
+		}
+	}
+	return nil
+}
+
+// empty reports whether the mapping has no keys.
+func (o *object) empty() bool { return len(o.node.Content) == 0 }
+
+// ignore marks key as read without converting it.
+func (o *object) ignore(key string) { o.used[key] = true }
+
+func (o *object) object(key string) *object {
+	return o.c.object(o.get(key), o.path+key)
+}
+
+// str returns the string value of key.
+func (o *object) str(key string) string {
+	n := o.get(key)
+	if n == nil || n.Tag == "!!null" {
+		return ""
+	}
+	if n.Kind != yaml.ScalarNode {
+		o.c.errorf(n, "%s%s: expected a string", o.path, key)
+		return ""
+	}
+	return n.Value
+}
+
+// int returns the integer value of key.
+func (o *object) int(key string) int {
+	n := o.get(key)
+	if n == nil || n.Tag == "!!null" {
+		return 0
+	}
+	var i int
+	if err := n.Decode(&i); err != nil {
+		o.c.errorf(n, "%s%s: expected an integer", o.path, key)
+	}
+	return i
+}
+
+// bool returns the boolean value of key.
+func (o *object) bool(key string) bool {
+	n := o.get(key)
+	if n == nil || n.Tag == "!!null" {
+		return false
+	}
+	var b bool
+	if err := n.Decode(&b); err != nil {
+		o.c.errorf(n, "%s%s: expected a boolean", o.path, key)
+	}
+	return b
+}
+
+// list returns the elements of the sequence key.
+func (o *object) list(key string) []*yaml.Node {
+	n := o.get(key)
+	if n == nil || n.Tag == "!!null" {
+		return nil
+	}
+	if n.Kind != yaml.SequenceNode {
+		o.c.errorf(n, "%s%s: expected a list", o.path, key)
+		return nil
+	}
+	return n.Content
+}
+
+// objects returns the mappings in the sequence key.
+func (o *object) objects(key string) []*object {
+	var res []*object
+	for i, n := range o.list(key) {
+		res = append(res, o.c.object(n, fmt.Sprintf("%s%s[%d]", o.path, key, i)))
+	}
+	return res
+}
+
+// strings returns the strings in the sequence key.
+func (o *object) strings(key string) []string {
+	var res []string
+	for _, n := range o.list(key) {
+		if n.Kind != yaml.ScalarNode {
+			o.c.errorf(n, "%s%s: expected a list of strings", o.path, key)
+			continue
+		}
+		res = append(res, n.Value)
+	}
+	return res
+}
+
+// stringMap returns the mapping key as a map of strings.
+func (o *object) stringMap(key string) map[string]string {
+	n := o.get(key)
+	if n == nil || n.Tag == "!!null" {
+		return nil
+	}
+	var res map[string]string
+	if err := n.Decode(&res); err != nil {
+		o.c.errorf(n, "%s%s: expected a mapping of strings", o.path, key)
+	}
+	return res
+}
+
+// ref is a reference to the exports of a component.
+type ref string
+
+// MarshalSyntaxExpr implements syntax.ExprMarshaler.
+func (r ref) MarshalSyntaxExpr() ([]byte, error) { return []byte(r), nil }
+
+// basicAuthBlock is a basic_auth block.
+type basicAuthBlock struct {
+	Username string `syntax:"username,attr,optional"`
+	Password string `syntax:"password,attr,optional"`
+}
+
+func convertBasicAuth(o *object) *basicAuthBlock {
+	if o.empty() {
+		return nil
+	}
+	return &basicAuthBlock{Username: o.str("username"), Password: o.str("password")}
+}
@@ -0,0 +1,99 @@
+package convert_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/jharvey10/test-repo/internal/component/all"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/internal/convert"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/printer"
+)
+
+// TestConvert converts each testdata/<format>/*.yaml file and compares the
+// result against the matching .alloy file and the diagnostics against the
+// matching .diags file. A missing .alloy file expects the conversion to
+// fail, and a missing .diags file expects no diagnostics. Every output must
+// be a valid configuration for the registered components.
+func TestConvert(t *testing.T) {
+	for _, format := range convert.Formats {
+		inputs, err := filepath.Glob(filepath.Join("testdata", string(format), "*.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inputs) == 0 {
+			t.Errorf("no test inputs for format %q", format)
+		}
+
+		for _, input := range inputs {
+			base := strings.TrimSuffix(input, ".yaml")
+			t.Run(string(format)+"/"+filepath.Base(base), func(t *testing.T) {
+				src, err := os.ReadFile(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				out, ds := convert.Convert(filepath.Base(input), src, format)
+
+				if expect := readGolden(t, base+".diags"); formatDiags(ds) != expect {
+					t.Errorf("unexpected diagnostics:\n%s\nexpected:\n%s", formatDiags(ds), expect)
+				}
+
+				expect := readGolden(t, base+".alloy")
+				if string(out) != expect {
+					t.Fatalf("unexpected output:\n%s\nexpected:\n%s", out, expect)
+				}
+				if out == nil {
+					return
+				}
+
+				// The output must be valid and already in canonical form.
+				formatted, err := printer.Format(base+".alloy", out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(formatted) != string(out) {
+					t.Errorf("output is not formatted:\n%s", formatted)
+				}
+				if ds := config.Validate(base+".alloy", out); len(ds) > 0 {
+					t.Errorf("output is not a valid configuration:\n%s", formatDiags(ds))
+				}
+			})
+		}
+	}
+}
+
+func TestConvert_UnknownFormat(t *testing.T) {
+	out, ds := convert.Convert("config.yaml", []byte("{}"), "nginx")
+	if out != nil || !ds.HasErrors() {
+		t.Fatalf("expected an error for an unknown format, got %q, %v", out, ds)
+	}
+}
+
+// readGolden returns the contents of path, or an empty string if it does
+// not exist.
+func readGolden(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ""
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func formatDiags(ds diag.Diagnostics) string {
+	var sb strings.Builder
+	for _, d := range ds {
+		sb.WriteString(d.Severity.String())
+		sb.WriteString(": ")
+		sb.WriteString(d.Error())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,401 @@
+package convert
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// otelcolConfig is the native equivalent of an OpenTelemetry Collector
+// configuration file. It covers the components bundled with the collector
+// distribution in collector/builder-config.yml.
+type otelcolConfig struct {
+	BasicAuths        []*basicAuthExtension `syntax:"otelcol.auth.basic,block,optional"`
+	OTLPReceivers     []*otlpReceiverBlock  `syntax:"otelcol.receiver.otlp,block,optional"`
+	MemoryLimiters    []*memoryLimiterBlock `syntax:"otelcol.processor.memory_limiter,block,optional"`
+	Batches           []*batchBlock         `syntax:"otelcol.processor.batch,block,optional"`
+	CountConnectors   []*countBlock         `syntax:"otelcol.connector.count,block,optional"`
+	SpanMetrics       []*spanMetricsBlock   `syntax:"otelcol.connector.spanmetrics,block,optional"`
+	OTLPHTTPExporters []*otlphttpBlock      `syntax:"otelcol.exporter.otlphttp,block,optional"`
+}
+
+// otelOutput lists the components which receive the telemetry a component
+// emits, by signal.
+type otelOutput struct {
+	Metrics []ref `syntax:"metrics,attr,optional"`
+	Logs    []ref `syntax:"logs,attr,optional"`
+	Traces  []ref `syntax:"traces,attr,optional"`
+}
+
+type basicAuthExtension struct {
+	Label    string `syntax:",label"`
+	Username string `syntax:"username,attr"`
+	Password string `syntax:"password,attr"`
+}
+
+type otlpReceiverBlock struct {
+	Label  string      `syntax:",label"`
+	GRPC   *otlpServer `syntax:"grpc,block,optional"`
+	HTTP   *otlpServer `syntax:"http,block,optional"`
+	Output *otelOutput `syntax:"output,block"`
+}
+
+type otlpServer struct {
+	Endpoint string `syntax:"endpoint,attr,optional"`
+}
+
+type memoryLimiterBlock struct {
+	Label                string      `syntax:",label"`
+	CheckInterval        string      `syntax:"check_interval,attr,optional"`
+	Limit                string      `syntax:"limit,attr,optional"`
+	SpikeLimit           string      `syntax:"spike_limit,attr,optional"`
+	LimitPercentage      int         `syntax:"limit_percentage,attr,optional"`
+	SpikeLimitPercentage int         `syntax:"spike_limit_percentage,attr,optional"`
+	Output               *otelOutput `syntax:"output,block"`
+}
+
+type batchBlock struct {
+	Label            string      `syntax:",label"`
+	Timeout          string      `syntax:"timeout,attr,optional"`
+	SendBatchSize    int         `syntax:"send_batch_size,attr,optional"`
+	SendBatchMaxSize int         `syntax:"send_batch_max_size,attr,optional"`
+	Output           *otelOutput `syntax:"output,block"`
+}
+
+type countBlock struct {
+	Label  string      `syntax:",label"`
+	Output *otelOutput `syntax:"output,block"`
+}
+
+type spanMetricsBlock struct {
+	Label                string                 `syntax:",label"`
+	Namespace            string                 `syntax:"namespace,attr,optional"`
+	MetricsFlushInterval string                 `syntax:"metrics_flush_interval,attr,optional"`
+	Dimensions           []spanMetricsDimension `syntax:"dimension,block,optional"`
+	Output               *otelOutput            `syntax:"output,block"`
+}
+
+type spanMetricsDimension struct {
+	Name    string `syntax:"name,attr"`
+	Default string `syntax:"default,attr,optional"`
+}
+
+type otlphttpBlock struct {
+	Label  string         `syntax:",label"`
+	Client otlphttpClient `syntax:"client,block"`
+}
+
+type otlphttpClient struct {
+	Endpoint    string            `syntax:"endpoint,attr"`
+	Compression string            `syntax:"compression,attr,optional"`
+	Timeout     string            `syntax:"timeout,attr,optional"`
+	Headers     map[string]string `syntax:"headers,attr,optional"`
+	Auth        ref               `syntax:"auth,attr,optional"`
+}
+
+// otelComponent is a converted collector component, used to wire the
+// pipelines together.
+type otelComponent struct {
+	input  ref         // Where telemetry is sent to this component.
+	output *otelOutput // Nil for exporters.
+
+	// forward connectors have no native equivalent. Telemetry sent to them
+	// is sent straight to their outputs instead.
+	forward bool
+
+	next map[string][]*otelComponent // Components receiving each signal.
+}
+
+// convertOtelCol converts an OpenTelemetry Collector configuration file.
+// Every receiver, exporter and connector becomes a component, every
+// processor becomes a component for each pipeline using it, and the
+// pipelines in the service section are expressed through the
+// output block of each component.
+func (c *converter) convertOtelCol(root *object) any {
+	var out otelcolConfig
+
+	// Components are keyed by section and ID, such as "exporters/otlphttp".
+	// Defined components which could not be converted are nil.
+	components := make(map[string]*otelComponent)
+	authenticators := make(map[string]ref)
+
+	// forEach calls fn for every component defined in section, with the
+	// component's type, its name and its configuration.
+	forEach := func(section string, fn func(key *yaml.Node, typ, name string, o *object) *otelComponent) {
+		s := root.object(section)
+		for _, key := range s.keys() {
+			typ, name, _ := strings.Cut(key.Value, "/")
+			if name == "" {
+				name = "default"
+			}
+			components[section+"/"+key.Value] = fn(key, typ, name, s.object(key.Value))
+		}
+	}
+	// unsupported reports a component type with no native equivalent. Its
+	// fields are not reported individually.
+	unsupported := func(kind string, key *yaml.Node, typ string, o *object) {
+		c.warnf(key, "unsupported %s %q", kind, typ)
+		for _, k := range o.keys() {
+			o.ignore(k.Value)
+		}
+	}
+	component := func(input string, output *otelOutput) *otelComponent {
+		return &otelComponent{input: ref(input), output: output, next: make(map[string][]*otelComponent)}
+	}
+
+	forEach("extensions", func(key *yaml.Node, typ, name string, o *object) *otelComponent {
+		switch typ {
+		case "basicauth":
+			auth := o.object("client_auth")
+			if auth.empty() {
+				c.warnf(key, "extension %q: only client_auth is supported", key.Value)
+				return nil
+			}
+			b := &basicAuthExtension{
+				Label:    c.component(key, "otelcol.auth.basic", name),
+				Username: auth.str("username"),
+				Password: auth.str("password"),
+			}
+			out.BasicAuths = append(out.BasicAuths, b)
+			authenticators[key.Value] = ref("otelcol.auth.basic." + b.Label + ".handler")
+		default:
+			unsupported("extension", key, typ, o)
+		}
+		return nil
+	})
+
+	forEach("receivers", func(key *yaml.Node, typ, name string, o *object) *otelComponent {
+		switch typ {
+		case "otlp":
+			protocols := o.object("protocols")
+			r := &otlpReceiverBlock{Label: c.component(key, "otelcol.receiver.otlp", name), Output: &otelOutput{}}
+			if n := protocols.get("grpc"); n != nil {
+				r.GRPC = &otlpServer{Endpoint: protocols.object("grpc").str("endpoint")}
+			}
+			if n := protocols.get("http"); n != nil {
+				r.HTTP = &otlpServer{Endpoint: protocols.object("http").str("endpoint")}
+			}
+			out.OTLPReceivers = append(out.OTLPReceivers, r)
+			return component("", r.Output)
+		default:
+			unsupported("receiver", key, typ, o)
+			return nil
+		}
+	})
+
+	// Processors are instantiated once for every pipeline which uses them,
+	// as they are in the collector, so pipelines don't share their state.
+	// Each defined processor maps to a function creating an instance for a
+	// pipeline from its entry n in the pipeline, which is nil if the
+	// processor could not be converted.
+	processors := make(map[string]func(pipeline string, n *yaml.Node) *otelComponent)
+	procs := root.object("processors")
+	for _, key := range procs.keys() {
+		typ, name, _ := strings.Cut(key.Value, "/")
+		if name == "" {
+			name = "default"
+		}
+		o := procs.object(key.Value)
+
+		switch typ {
+		case "batch":
+			tmpl := batchBlock{
+				Timeout:          o.str("timeout"),
+				SendBatchSize:    o.int("send_batch_size"),
+				SendBatchMaxSize: o.int("send_batch_max_size"),
+			}
+			processors[key.Value] = func(pipeline string, n *yaml.Node) *otelComponent {
+				b := tmpl
+				b.Label = c.component(n, "otelcol.processor.batch", name+"_"+pipeline)
+				b.Output = &otelOutput{}
+				out.Batches = append(out.Batches, &b)
+				return component("otelcol.processor.batch."+b.Label+".input", b.Output)
+			}
+		case "memory_limiter":
+			tmpl := memoryLimiterBlock{
+				CheckInterval:        o.str("check_interval"),
+				LimitPercentage:      o.int("limit_percentage"),
+				SpikeLimitPercentage: o.int("spike_limit_percentage"),
+			}
+			if mib := o.int("limit_mib"); mib > 0 {
+				tmpl.Limit = fmt.Sprintf("%dMiB", mib)
+			}
+			if mib := o.int("spike_limit_mib"); mib > 0 {
+				tmpl.SpikeLimit = fmt.Sprintf("%dMiB", mib)
+			}
+			processors[key.Value] = func(pipeline string, n *yaml.Node) *otelComponent {
+				m := tmpl
+				m.Label = c.component(n, "otelcol.processor.memory_limiter", name+"_"+pipeline)
+				m.Output = &otelOutput{}
+				out.MemoryLimiters = append(out.MemoryLimiters, &m)
+				return component("otelcol.processor.memory_limiter."+m.Label+".input", m.Output)
+			}
+		default:
+			unsupported("processor", key, typ, o)
+			processors[key.Value] = nil
+		}
+	}
+
+	forEach("connectors", func(key *yaml.Node, typ, name string, o *object) *otelComponent {
+		switch typ {
+		case "forward":
+			comp := component("", nil)
+			comp.forward = true
+			return comp
+		case "count":
+			b := &countBlock{Label: c.component(key, "otelcol.connector.count", name), Output: &otelOutput{}}
+			out.CountConnectors = append(out.CountConnectors, b)
+			return component("otelcol.connector.count."+b.Label+".input", b.Output)
+		case "spanmetrics":
+			b := &spanMetricsBlock{
+				Label:                c.component(key, "otelcol.connector.spanmetrics", name),
+				Namespace:            o.str("namespace"),
+				MetricsFlushInterval: o.str("metrics_flush_interval"),
+				Output:               &otelOutput{},
+			}
+			for _, d := range o.objects("dimensions") {
+				b.Dimensions = append(b.Dimensions, spanMetricsDimension{Name: d.str("name"), Default: d.str("default")})
+			}
+			out.SpanMetrics = append(out.SpanMetrics, b)
+			return component("otelcol.connector.spanmetrics."+b.Label+".input", b.Output)
+		default:
+			unsupported("connector", key, typ, o)
+			return nil
+		}
+	})
+
+	forEach("exporters", func(key *yaml.Node, typ, name string, o *object) *otelComponent {
+		switch typ {
+		case "otlphttp":
+			e := &otlphttpBlock{
+				Label: c.component(key, "otelcol.exporter.otlphttp", name),
+				Client: otlphttpClient{
+					Endpoint:    o.str("endpoint"),
+					Compression: o.str("compression"),
+					Timeout:     o.str("timeout"),
+					Headers:     o.stringMap("headers"),
+				},
+			}
+			auth := o.object("auth")
+			if id := auth.str("authenticator"); id != "" {
+				handler, ok := authenticators[id]
+				if !ok {
+					c.errorf(auth.get("authenticator"), "authenticator %q is not a basicauth extension with client_auth", id)
+				}
+				e.Client.Auth = handler
+			}
+			out.OTLPHTTPExporters = append(out.OTLPHTTPExporters, e)
+			return component("otelcol.exporter.otlphttp."+e.Label+".input", nil)
+		default:
+			unsupported("exporter", key, typ, o)
+			return nil
+		}
+	})
+
+	var instances []*otelComponent // Processor instances of every pipeline.
+	service := root.object("service")
+	service.strings("extensions") // Extensions need no wiring.
+	pipelines := service.object("pipelines")
+	for _, key := range pipelines.keys() {
+		p := pipelines.object(key.Value)
+		signal, _, _ := strings.Cut(key.Value, "/")
+		if signal != "metrics" && signal != "logs" && signal != "traces" {
+			c.errorf(key, "pipeline %q: unknown signal %q", key.Value, signal)
+			continue
+		}
+
+		lookup := func(field string, sections ...string) []*otelComponent {
+			var res []*otelComponent
+			for _, n := range p.list(field) {
+				found := false
+				for _, section := range sections {
+					comp, ok := components[section+"/"+n.Value]
+					if !ok {
+						continue
+					}
+					found = true
+					if comp != nil {
+						res = append(res, comp)
+					}
+					break
+				}
+				if !found {
+					c.errorf(n, "pipeline %q: %s %q is not defined", key.Value, strings.TrimSuffix(field, "s"), n.Value)
+				}
+			}
+			return res
+		}
+		sources := lookup("receivers", "receivers", "connectors")
+		exporters := lookup("exporters", "exporters", "connectors")
+
+		var chain []*otelComponent
+		for _, n := range p.list("processors") {
+			newProcessor, ok := processors[n.Value]
+			if !ok {
+				c.errorf(n, "pipeline %q: processor %q is not defined", key.Value, n.Value)
+				continue
+			}
+			if newProcessor != nil {
+				proc := newProcessor(key.Value, n)
+				chain = append(chain, proc)
+				instances = append(instances, proc)
+			}
+		}
+
+		// Receivers send to the first processor, each processor to the next
+		// one and the last processor to every exporter.
+		link := func(to []*otelComponent) {
+			for _, from := range sources {
+				for _, comp := range to {
+					if !slices.Contains(from.next[signal], comp) {
+						from.next[signal] = append(from.next[signal], comp)
+					}
+				}
+			}
+		}
+		for _, proc := range chain {
+			link([]*otelComponent{proc})
+			sources = []*otelComponent{proc}
+		}
+		link(exporters)
+	}
+
+	for _, comp := range slices.Concat(slices.Collect(maps.Values(components)), instances) {
+		if comp == nil || comp.output == nil {
+			continue
+		}
+		comp.output.Metrics = resolveOutputs(comp.next["metrics"], "metrics", nil)
+		comp.output.Logs = resolveOutputs(comp.next["logs"], "logs", nil)
+		comp.output.Traces = resolveOutputs(comp.next["traces"], "traces", nil)
+	}
+
+	return out
+}
+
+// resolveOutputs returns the inputs of the components in next, replacing
+// forward connectors with the components they send the signal to.
+func resolveOutputs(next []*otelComponent, signal string, seen map[*otelComponent]bool) []ref {
+	var res []ref
+	for _, comp := range next {
+		if !comp.forward {
+			res = append(res, comp.input)
+			continue
+		}
+		if seen[comp] {
+			continue
+		}
+		if seen == nil {
+			seen = make(map[*otelComponent]bool)
+		}
+		seen[comp] = true
+		for _, r := range resolveOutputs(comp.next[signal], signal, seen) {
+			if !slices.Contains(res, r) {
+				res = append(res, r)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package convert
+
+// prometheusConfig is the native equivalent of a Prometheus configuration
+// file.
+type prometheusConfig struct {
+	Scrapes []scrapeBlock `syntax:"prometheus.scrape,block,optional"`
+}
+
+// scrapeBlock holds the arguments of prometheus.scrape. Scrape settings it
+// has no argument for, such as intervals and remote_write, are reported
+// as unsupported fields.
+type scrapeBlock struct {
+	Label       string              `syntax:",label"`
+	Targets     []map[string]string `syntax:"targets,attr"`
+	JobName     string              `syntax:"job_name,attr,optional"`
+	BearerToken string              `syntax:"bearer_token,attr,optional"`
+}
+
+// convertPrometheus converts a Prometheus configuration file. Every scrape
+// job becomes a prometheus.scrape component with its static targets.
+func (c *converter) convertPrometheus(root *object) any {
+	var out prometheusConfig
+
+	for _, o := range root.objects("scrape_configs") {
+		jobName := o.str("job_name")
+		scrape := scrapeBlock{
+			Label:       c.component(o.node, "prometheus.scrape", jobName),
+			Targets:     []map[string]string{},
+			JobName:     jobName,
+			BearerToken: o.str("bearer_token"),
+		}
+
+		for _, static := range o.objects("static_configs") {
+			labels := static.stringMap("labels")
+			for _, target := range static.strings("targets") {
+				t := map[string]string{"__address__": target}
+				for k, v := range labels {
+					t[k] = v
+				}
+				scrape.Targets = append(scrape.Targets, t)
+			}
+		}
+
+		out.Scrapes = append(out.Scrapes, scrape)
+	}
+
+	return out
+}
@@ -0,0 +1,97 @@
+package convert
+
+import "strconv"
+
+// promtailConfig is the native equivalent of a Promtail configuration
+// file.
+type promtailConfig struct {
+	FileMatches []fileMatchBlock  `syntax:"local.file_match,block,optional"`
+	Sources     []sourceFileBlock `syntax:"loki.source.file,block,optional"`
+	Writes      []lokiWriteBlock  `syntax:"loki.write,block,optional"`
+}
+
+type fileMatchBlock struct {
+	Label       string              `syntax:",label"`
+	PathTargets []map[string]string `syntax:"path_targets,attr"`
+}
+
+type sourceFileBlock struct {
+	Label     string `syntax:",label"`
+	Targets   ref    `syntax:"targets,attr"`
+	ForwardTo []ref  `syntax:"forward_to,attr"`
+}
+
+type lokiWriteBlock struct {
+	Label          string            `syntax:",label"`
+	ExternalLabels map[string]string `syntax:"external_labels,attr,optional"`
+	Endpoint       lokiEndpoint      `syntax:"endpoint,block"`
+}
+
+type lokiEndpoint struct {
+	URL         string            `syntax:"url,attr"`
+	TenantID    string            `syntax:"tenant_id,attr,optional"`
+	BearerToken string            `syntax:"bearer_token,attr,optional"`
+	Headers     map[string]string `syntax:"headers,attr,optional"`
+	BasicAuth   *basicAuthBlock   `syntax:"basic_auth,block,optional"`
+}
+
+// convertPromtail converts a Promtail configuration file. Every client
+// becomes a loki.write component, and every scrape job becomes a
+// local.file_match component finding the files to tail and a
+// loki.source.file component reading them.
+func (c *converter) convertPromtail(root *object) any {
+	var out promtailConfig
+
+	if positions := root.key("positions"); positions != nil {
+		c.warnf(positions, "positions are kept in the data directory; the positions file is not used")
+	}
+
+	forwardTo := []ref{}
+	for i, o := range root.objects("clients") {
+		label := "default"
+		if i > 0 {
+			label += "_" + strconv.Itoa(i+1)
+		}
+		w := lokiWriteBlock{
+			Label:          c.component(o.node, "loki.write", label),
+			ExternalLabels: o.stringMap("external_labels"),
+			Endpoint: lokiEndpoint{
+				URL:         o.str("url"),
+				TenantID:    o.str("tenant_id"),
+				BearerToken: o.str("bearer_token"),
+				Headers:     o.stringMap("headers"),
+				BasicAuth:   convertBasicAuth(o.object("basic_auth")),
+			},
+		}
+		out.Writes = append(out.Writes, w)
+		forwardTo = append(forwardTo, ref("loki.write."+w.Label+".receiver"))
+	}
+
+	for _, o := range root.objects("scrape_configs") {
+		label := c.component(o.node, "local.file_match", o.str("job_name"))
+		match := fileMatchBlock{Label: label, PathTargets: []map[string]string{}}
+
+		for _, static := range o.objects("static_configs") {
+			labels := static.stringMap("labels")
+			if _, ok := labels["__path__"]; !ok {
+				c.warnf(static.node, "static_configs without a __path__ label do not match any files")
+			}
+			for _, target := range static.strings("targets") {
+				t := map[string]string{"__address__": target}
+				for k, v := range labels {
+					t[k] = v
+				}
+				match.PathTargets = append(match.PathTargets, t)
+			}
+		}
+
+		out.FileMatches = append(out.FileMatches, match)
+		out.Sources = append(out.Sources, sourceFileBlock{
+			Label:     c.component(o.node, "loki.source.file", label),
+			Targets:   ref("local.file_match." + label + ".targets"),
+			ForwardTo: forwardTo,
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,71 @@
+package importsource
+
+import (
+	"fmt"
+	"time"
+)
+
+// Blocks maps the name of each import block to the zero value of its
+// arguments, which are decoded from the block's body.
+var Blocks = map[string]BlockArguments{
+	"import.file": FileArguments{},
+	"import.git":  GitArguments{},
+	"import.http": HTTPArguments{},
+}
+
+// BlockArguments are the decoded arguments of an import block.
+type BlockArguments interface {
+	// Poller returns a Poller for the Source the arguments configure.
+	// OnChange and OnError are left for the caller to set.
+	Poller() (*Poller, error)
+}
+
+// FileArguments are the arguments of an import.file block.
+type FileArguments struct {
+	Filename      string `syntax:"filename,attr"`
+	PollFrequency string `syntax:"poll_frequency,attr,optional"`
+}
+
+// Poller implements BlockArguments.
+func (args FileArguments) Poller() (*Poller, error) {
+	return newPoller(&File{Path: args.Filename}, args.PollFrequency)
+}
+
+// GitArguments are the arguments of an import.git block.
+type GitArguments struct {
+	Repository    string `syntax:"repository,attr"`
+	Revision      string `syntax:"revision,attr,optional"`
+	Path          string `syntax:"path,attr,optional"`
+	PollFrequency string `syntax:"poll_frequency,attr,optional"`
+}
+
+// Poller implements BlockArguments.
+func (args GitArguments) Poller() (*Poller, error) {
+	return newPoller(&Git{Repository: args.Repository, Revision: args.Revision, Path: args.Path}, args.PollFrequency)
+}
+
+// HTTPArguments are the arguments of an import.http block.
+type HTTPArguments struct {
+	URL           string `syntax:"url,attr"`
+	PollFrequency string `syntax:"poll_frequency,attr,optional"`
+}
+
+// Poller implements BlockArguments.
+func (args HTTPArguments) Poller() (*Poller, error) {
+	return newPoller(&HTTP{URL: args.URL}, args.PollFrequency)
+}
+
+// newPoller creates a Poller for src. An empty frequency selects
+// DefaultPollFrequency.
+func newPoller(src Source, frequency string) (*Poller, error) {
+	p := &Poller{Source: src}
+	if frequency == "" {
+		return p, nil
+	}
+	d, err := time.ParseDuration(frequency)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid poll_frequency %q: must be a positive duration such as \"1m\"", frequency)
+	}
+	p.Frequency = d
+	return p, nil
+}
@@ -0,0 +1,66 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File is a Source which loads a module from the local filesystem. Path may
+// point at a single file or at a directory, in which case every file ending
+// in FileExtension directly inside it is loaded.
+type File struct {
+	Path string
+}
+
+var _ Source = (*File)(nil)
+
+// Name implements Source.
+func (f *File) Name() string { return "import.file" }
+
+// Fetch implements Source.
+func (f *File) Fetch(_ context.Context) (Files, error) {
+	if f.Path == "" {
+		return nil, fmt.Errorf("%s: path must not be empty", f.Name())
+	}
+	return readFiles(f.Path)
+}
+
+// readFiles reads path, which may be a file or a directory of fragments.
+func readFiles(path string) (Files, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		bb, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return Files{filepath.Base(path): bb}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(Files)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), FileExtension) {
+			continue
+		}
+		bb, err := os.ReadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[e.Name()] = bb
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("directory %s contains no %s files", path, FileExtension)
+	}
+	return files, nil
+}
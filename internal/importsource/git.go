@@ -0,0 +1,100 @@
+package importsource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Git is a Source which loads a module from a path inside a git repository.
+// The repository is fetched with the git binary into a local working copy,
+// which is updated in place on every Fetch.
+type Git struct {
+	// Repository is the URL or local path of the repository to fetch.
+	Repository string
+	// Revision is the branch, tag or commit to check out. Defaults to HEAD.
+	Revision string
+	// Path is the file or directory inside the repository to load. Defaults
+	// to the root of the repository.
+	Path string
+	// Dir is where the working copy is kept. A temporary directory is
+	// created when Dir is empty.
+	Dir string
+
+	mut sync.Mutex
+}
+
+var _ Source = (*Git)(nil)
+
+// Name implements Source.
+func (g *Git) Name() string { return "import.git" }
+
+// Fetch implements Source.
+func (g *Git) Fetch(ctx context.Context) (Files, error) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.Repository == "" {
+		return nil, fmt.Errorf("%s: repository must not be empty", g.Name())
+	}
+	if err := g.sync(ctx); err != nil {
+		return nil, fmt.Errorf("%s: syncing %s: %w", g.Name(), g.Repository, err)
+	}
+
+	path := filepath.Join(g.Dir, filepath.Clean("/"+g.Path))
+	return readFiles(path)
+}
+
+// sync brings the working copy in line with Revision of Repository.
+func (g *Git) sync(ctx context.Context) error {
+	if g.Dir == "" {
+		dir, err := os.MkdirTemp("", "import-git-")
+		if err != nil {
+			return err
+		}
+		g.Dir = dir
+	}
+
+	_, err := os.Stat(filepath.Join(g.Dir, ".git"))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := g.git(ctx, "init", "--quiet"); err != nil {
+			return err
+		}
+		if err := g.git(ctx, "remote", "add", "origin", g.Repository); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	revision := g.Revision
+	if revision == "" {
+		revision = "HEAD"
+	}
+	if err := g.git(ctx, "fetch", "--quiet", "--force", "origin", revision); err != nil {
+		return err
+	}
+	return g.git(ctx, "checkout", "--quiet", "--force", "--detach", "FETCH_HEAD")
+}
+
+// git runs a git subcommand inside the working copy.
+func (g *Git) git(ctx context.Context, args ...string) error {
+	if err := os.MkdirAll(g.Dir, 0o755); err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", g.Dir}, args...)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package importsource
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGit_Fetch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	ctx := context.Background()
+	bare, work := filepath.Join(t.TempDir(), "modules.git"), t.TempDir()
+	runGit(t, "", "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	runGit(t, "", "clone", "--quiet", bare, work)
+
+	commitFile(t, work, "pipelines/scrape.alloy", "v1")
+	commitFile(t, work, "README.md", "not a module")
+	runGit(t, work, "push", "--quiet", "origin", "HEAD:main")
+
+	src := &Git{
+		Repository: bare,
+		Revision:   "main",
+		Path:       "pipelines",
+		Dir:        filepath.Join(t.TempDir(), "checkout"),
+	}
+
+	files, err := src.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := string(files["scrape.alloy"]); got != "v1" {
+		t.Fatalf("scrape.alloy = %q, want %q", got, "v1")
+	}
+
+	commitFile(t, work, "pipelines/scrape.alloy", "v2")
+	runGit(t, work, "push", "--quiet", "origin", "HEAD:main")
+
+	files, err = src.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch after push: %v", err)
+	}
+	if got := string(files["scrape.alloy"]); got != "v2" {
+		t.Errorf("scrape.alloy after push = %q, want %q", got, "v2")
+	}
+}
+
+func TestGit_FetchUnknownRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	bare := filepath.Join(t.TempDir(), "modules.git")
+	runGit(t, "", "init", "--quiet", "--bare", bare)
+
+	src := &Git{Repository: bare, Revision: "does-not-exist", Dir: t.TempDir()}
+	if _, err := src.Fetch(context.Background()); err == nil {
+		t.Fatal("want error for unknown revision, got nil")
+	}
+}
+
+func commitFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "update "+name)
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
@@ -0,0 +1,78 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// DefaultHTTPTimeout is used when HTTP has no Timeout set.
+const DefaultHTTPTimeout = 30 * time.Second
+
+// HTTP is a Source which loads a single-file module from a URL with a GET
+// request. The file is named after the last element of the URL's path, so
+// a URL ending in .json is read as JSON configuration.
+type HTTP struct {
+	URL string
+	// Client performs the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Timeout bounds each Fetch, including reading the response, so that
+	// an endpoint which hangs doesn't block its caller. Defaults to
+	// DefaultHTTPTimeout.
+	Timeout time.Duration
+}
+
+var _ Source = (*HTTP)(nil)
+
+// Name implements Source.
+func (h *HTTP) Name() string { return "import.http" }
+
+// Fetch implements Source.
+func (h *HTTP) Fetch(ctx context.Context) (Files, error) {
+	if h.URL == "" {
+		return nil, fmt.Errorf("%s: url must not be empty", h.Name())
+	}
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", h.Name(), err)
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", h.Name(), err)
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: fetching %s: %w", h.Name(), h.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: fetching %s: unexpected status %s", h.Name(), h.URL, resp.Status)
+	}
+	bb, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading %s: %w", h.Name(), h.URL, err)
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		name = "module" + FileExtension
+	}
+	return Files{name: bb}, nil
+}
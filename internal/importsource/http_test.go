@@ -0,0 +1,61 @@
+package importsource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTP_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.alloy" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("content of " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	tt := []struct {
+		path   string
+		expect string // Name of the fetched file.
+	}{
+		{"/configs/main.alloy", "main.alloy"},
+		{"/configs/main.json", "main.json"},
+		{"/", "module.alloy"},
+		{"", "module.alloy"},
+	}
+	for _, tc := range tt {
+		files, err := (&HTTP{URL: srv.URL + tc.path}).Fetch(context.Background())
+		if err != nil {
+			t.Errorf("%q: %v", tc.path, err)
+			continue
+		}
+		if len(files) != 1 || files[tc.expect] == nil {
+			t.Errorf("%q: expected a single file named %s, got %q", tc.path, tc.expect, files)
+		}
+	}
+
+	if _, err := (&HTTP{URL: srv.URL + "/missing.alloy"}).Fetch(context.Background()); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestHTTP_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	_, err := (&HTTP{URL: srv.URL + "/main.alloy", Timeout: 50 * time.Millisecond}).Fetch(context.Background())
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected Fetch to time out quickly, took %s", elapsed)
+	}
+}
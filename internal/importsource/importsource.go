@@ -0,0 +1,63 @@
+// Package importsource loads reusable configuration fragments (modules) for
+// the import.file, import.git and import.http blocks.
+//
+// The runner runs the components a module declares alongside the ones in
+// the config importing it, with their IDs prefixed by the ID of the import
+// block, such as import.git.shared/prometheus.scrape.default. Modules can't
+// import other modules.
+//
+// Modules can also define components with declare blocks, which take
+// arguments and export values. The importing config instantiates them by
+// the label of the import block and the declare block, such as
+// shared.pipeline "default" { ... }, and references their exports as
+// shared.pipeline.default.<export>. See config.Declare.
+//
+// A Source only knows how to fetch the raw fragment files. A Poller wraps a
+// Source, re-fetches it periodically and notifies the runner whenever the
+// fetched content changes so that the declared components can be reloaded.
+package importsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"sort"
+)
+
+// FileExtension is the extension of configuration fragments which are
+// loaded when a Source points at a directory.
+const FileExtension = ".alloy"
+
+// Source fetches the files which make up a module.
+type Source interface {
+	// Name returns the name of the import block, for example "import.file".
+	Name() string
+
+	// Fetch returns the content of every fragment in the module, keyed by
+	// its path relative to the root of the source.
+	Fetch(ctx context.Context) (Files, error)
+}
+
+// Files holds the content of fetched fragments keyed by relative path.
+type Files map[string][]byte
+
+// Hash returns a digest of the files which changes whenever a file is
+// added, removed or modified.
+func (f Files) Hash() [sha256.Size]byte {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write(f[name])
+		h.Write([]byte{0})
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,76 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultPollFrequency is used when a Poller has no Frequency set.
+const DefaultPollFrequency = time.Minute
+
+// Poller periodically fetches a Source and reports content changes so that
+// the module can be hot-reloaded.
+type Poller struct {
+	Source    Source
+	Frequency time.Duration
+
+	// OnChange is called with the fetched files on the first successful
+	// fetch and every time the content changes afterwards. If OnChange
+	// returns an error, the update is retried on the next poll.
+	OnChange func(Files) error
+
+	// OnError, if set, is called for fetch and reload errors that happen
+	// after the initial load. The last good module stays loaded.
+	OnError func(error)
+}
+
+// Run loads the module once and then polls it until ctx is canceled. The
+// initial load must succeed; later failures are passed to OnError.
+func (p *Poller) Run(ctx context.Context) error {
+	last, err := p.update(ctx, [32]byte{})
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", p.Source.Name(), err)
+	}
+
+	freq := p.Frequency
+	if freq <= 0 {
+		freq = DefaultPollFrequency
+	}
+	t := time.NewTicker(freq)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			next, err := p.update(ctx, last)
+			if err != nil {
+				if p.OnError != nil {
+					p.OnError(fmt.Errorf("reloading %s: %w", p.Source.Name(), err))
+				}
+				continue
+			}
+			last = next
+		}
+	}
+}
+
+// update fetches the source and calls OnChange if its hash differs from
+// last. It returns the hash of the content which is now loaded.
+func (p *Poller) update(ctx context.Context, last [32]byte) ([32]byte, error) {
+	files, err := p.Source.Fetch(ctx)
+	if err != nil {
+		return last, err
+	}
+
+	hash := files.Hash()
+	if hash == last {
+		return last, nil
+	}
+	if err := p.OnChange(files); err != nil {
+		return last, err
+	}
+	return hash, nil
+}
@@ -0,0 +1,82 @@
+package importsource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPoller_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.alloy"), "v1")
+	writeFile(t, filepath.Join(dir, "ignored.txt"), "not a module")
+
+	updates := make(chan Files, 10)
+	p := &Poller{
+		Source:    &File{Path: dir},
+		Frequency: 10 * time.Millisecond,
+		OnChange: func(f Files) error {
+			updates <- f
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+
+	first := receive(t, updates)
+	if len(first) != 1 || string(first["a.alloy"]) != "v1" {
+		t.Fatalf("initial load = %q, want only a.alloy=v1", first)
+	}
+
+	writeFile(t, filepath.Join(dir, "a.alloy"), "v2")
+	if second := receive(t, updates); string(second["a.alloy"]) != "v2" {
+		t.Fatalf("reload = %q, want a.alloy=v2", second)
+	}
+
+	// Unchanged content must not trigger another reload.
+	select {
+	case f := <-updates:
+		t.Fatalf("unexpected reload with unchanged content: %q", f)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestPoller_InitialLoadFails(t *testing.T) {
+	p := &Poller{
+		Source:   &File{Path: filepath.Join(t.TempDir(), "missing.alloy")},
+		OnChange: func(Files) error { return nil },
+	}
+	if err := p.Run(context.Background()); err == nil {
+		t.Fatal("want error for missing module, got nil")
+	}
+}
+
+func receive(t *testing.T, ch <-chan Files) Files {
+	t.Helper()
+
+	select {
+	case f := <-ch:
+		return f
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for module update")
+		return nil
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
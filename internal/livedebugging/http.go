@@ -0,0 +1,137 @@
+package livedebugging
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HandlerPattern is the route the Hub is served on by the runner's HTTP
+// server.
+const HandlerPattern = "GET /api/v0/debug/tail/{component}"
+
+// ServeHTTP streams the data published by the component named in the
+// request path as server-sent events, until the client disconnects.
+//
+// The optional sample and buffer query parameters map to the fields of
+// SubscribeOptions.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("component")
+	if name == "" {
+		http.Error(w, "component name is required", http.StatusBadRequest)
+		return
+	}
+
+	var opts SubscribeOptions
+	if v := r.URL.Query().Get("sample"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			http.Error(w, "sample must be a number between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		opts.SampleRate = rate
+	}
+	if v := r.URL.Query().Get("buffer"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 || size > MaxBufferSize {
+			http.Error(w, fmt.Sprintf("buffer must be an integer between 1 and %d", MaxBufferSize), http.StatusBadRequest)
+			return
+		}
+		opts.BufferSize = size
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch, cancel := h.Subscribe(name, opts)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data := <-ch:
+			for _, line := range strings.Split(data, "\n") {
+				if _, err := fmt.Fprintf(w, "data: %s\n", line); err != nil {
+					return
+				}
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Tail connects to the runner's HTTP server at addr and writes the data
+// published by the named component to out, one entry per line, until ctx is
+// canceled or the server closes the stream.
+func Tail(ctx context.Context, addr, name string, opts SubscribeOptions, out io.Writer) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("invalid server address %q: %w", addr, err)
+	}
+	u = u.JoinPath("/api/v0/debug/tail", name)
+
+	q := u.Query()
+	if opts.SampleRate > 0 {
+		q.Set("sample", strconv.FormatFloat(opts.SampleRate, 'f', -1, 64))
+	}
+	if opts.BufferSize > 0 {
+		q.Set("buffer", strconv.Itoa(opts.BufferSize))
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var event []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "":
+			if len(event) > 0 {
+				if _, err := fmt.Fprintln(out, strings.Join(event, "\n")); err != nil {
+					return err
+				}
+				event = event[:0]
+			}
+		case strings.HasPrefix(line, "data: "):
+			event = append(event, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Package livedebugging streams the data flowing through components to
+// subscribers in real time.
+//
+// Components publish the data they handle to a Hub. Each subscriber gets a
+// bounded, optionally sampled stream: when a subscriber falls behind, new
+// data is dropped for that subscriber instead of slowing the component down.
+package livedebugging
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"github.com/jharvey10/test-repo/internal/component"
+)
+
+// DefaultBufferSize is the number of entries buffered per subscriber when
+// SubscribeOptions.BufferSize is not set.
+const DefaultBufferSize = 1000
+
+// MaxBufferSize is the largest SubscribeOptions.BufferSize accepted; larger
+// sizes are lowered to it.
+const MaxBufferSize = 100000
+
+// SubscribeOptions configures a subscription.
+type SubscribeOptions struct {
+	// BufferSize is the maximum number of undelivered entries, up to
+	// MaxBufferSize. Entries published while the buffer is full are
+	// dropped.
+	BufferSize int
+	// SampleRate is the fraction of entries to deliver, between 0 and 1.
+	// Zero means every entry is delivered.
+	SampleRate float64
+}
+
+// Hub fans out published data to the subscribers of each component.
+type Hub struct {
+	mut  sync.RWMutex
+	subs map[string]map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch         chan string
+	sampleRate float64
+}
+
+// NewHub creates a new Hub.
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[*subscriber]struct{})}
+}
+
+// Subscribe starts streaming the data published by the component with the
+// given name. The returned function ends the subscription and closes the
+// channel.
+func (h *Hub) Subscribe(name string, opts SubscribeOptions) (<-chan string, func()) {
+	switch {
+	case opts.BufferSize <= 0:
+		opts.BufferSize = DefaultBufferSize
+	case opts.BufferSize > MaxBufferSize:
+		opts.BufferSize = MaxBufferSize
+	}
+	s := &subscriber{
+		ch:         make(chan string, opts.BufferSize),
+		sampleRate: opts.SampleRate,
+	}
+
+	h.mut.Lock()
+	if h.subs[name] == nil {
+		h.subs[name] = make(map[*subscriber]struct{})
+	}
+	h.subs[name][s] = struct{}{}
+	h.mut.Unlock()
+
+	var once sync.Once
+	return s.ch, func() {
+		once.Do(func() {
+			h.mut.Lock()
+			defer h.mut.Unlock()
+
+			delete(h.subs[name], s)
+			if len(h.subs[name]) == 0 {
+				delete(h.subs, name)
+			}
+			close(s.ch)
+		})
+	}
+}
+
+// Active reports whether the component with the given name has any
+// subscribers.
+func (h *Hub) Active(name string) bool {
+	h.mut.RLock()
+	defer h.mut.RUnlock()
+	return len(h.subs[name]) > 0
+}
+
+// Publish sends data to the subscribers of the component with the given
+// name. Publish never blocks.
+func (h *Hub) Publish(name string, data string) {
+	h.mut.RLock()
+	defer h.mut.RUnlock()
+
+	for s := range h.subs[name] {
+		if s.sampleRate > 0 && s.sampleRate < 1 && rand.Float64() >= s.sampleRate {
+			continue
+		}
+		select {
+		case s.ch <- data:
+		default:
+		}
+	}
+}
+
+// Publisher returns a component.DebugPublisher which publishes to the
+// component with the given name.
+func (h *Hub) Publisher(name string) component.DebugPublisher {
+	return &publisher{hub: h, name: name}
+}
+
+type publisher struct {
+	hub  *Hub
+	name string
+}
+
+func (p *publisher) Active() bool        { return p.hub.Active(p.name) }
+func (p *publisher) Publish(data string) { p.hub.Publish(p.name, data) }
@@ -0,0 +1,153 @@
+package livedebugging
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHub_DropsWhenBufferFull(t *testing.T) {
+	h := NewHub()
+	ch, cancel := h.Subscribe("prometheus.scrape", SubscribeOptions{BufferSize: 2})
+	defer cancel()
+
+	for _, data := range []string{"a", "b", "c"} {
+		h.Publish("prometheus.scrape", data)
+	}
+
+	if got := <-ch; got != "a" {
+		t.Errorf("first entry = %q, want a", got)
+	}
+	if got := <-ch; got != "b" {
+		t.Errorf("second entry = %q, want b", got)
+	}
+	select {
+	case got := <-ch:
+		t.Errorf("entry %q should have been dropped", got)
+	default:
+	}
+}
+
+func TestHub_Active(t *testing.T) {
+	h := NewHub()
+	p := h.Publisher("prometheus.scrape")
+	if p.Active() {
+		t.Fatal("publisher active without subscribers")
+	}
+
+	_, cancel := h.Subscribe("prometheus.scrape", SubscribeOptions{})
+	if !p.Active() {
+		t.Fatal("publisher inactive with a subscriber")
+	}
+
+	cancel()
+	cancel() // Canceling twice is safe.
+	if p.Active() {
+		t.Fatal("publisher active after unsubscribing")
+	}
+}
+
+func TestHub_Sampling(t *testing.T) {
+	h := NewHub()
+	ch, cancel := h.Subscribe("c", SubscribeOptions{BufferSize: 10000, SampleRate: 0.1})
+	defer cancel()
+
+	for range 10000 {
+		h.Publish("c", "x")
+	}
+	// With a 10% sample rate the expected count is 1000; allow generous
+	// slack so the test isn't flaky.
+	if n := len(ch); n < 500 || n > 1500 {
+		t.Errorf("received %d of 10000 entries with sample rate 0.1", n)
+	}
+}
+
+func TestTail(t *testing.T) {
+	h := NewHub()
+	mux := http.NewServeMux()
+	mux.Handle(HandlerPattern, h)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mut sync.Mutex
+		out bytes.Buffer
+	)
+	done := make(chan error)
+	go func() {
+		done <- Tail(ctx, srv.URL, "prometheus.scrape", SubscribeOptions{}, writerFunc(func(p []byte) (int, error) {
+			mut.Lock()
+			defer mut.Unlock()
+			return out.Write(p)
+		}))
+	}()
+
+	// Wait until the client has subscribed.
+	deadline := time.Now().Add(5 * time.Second)
+	for !h.Active("prometheus.scrape") {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for subscriber")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	h.Publish("prometheus.scrape", "scrape target=a")
+	h.Publish("prometheus.scrape", "multi\nline")
+	h.Publish("other", "not subscribed")
+
+	want := "scrape target=a\nmulti\nline\n"
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		mut.Lock()
+		got := out.String()
+		mut.Unlock()
+		if got == want {
+			break
+		}
+		if time.Now().After(deadline) || !strings.HasPrefix(want, got) {
+			t.Fatalf("output = %q, want %q", got, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+}
+
+func TestServeHTTP_InvalidSample(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(HandlerPattern, NewHub())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v0/debug/tail/c?sample=2", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServeHTTP_InvalidBuffer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(HandlerPattern, NewHub())
+
+	for _, buffer := range []string{"0", "100001", "1e10"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v0/debug/tail/c?buffer="+buffer, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("buffer=%s: status = %d, want %d", buffer, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
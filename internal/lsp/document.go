@@ -0,0 +1,236 @@
+package lsp
+
+import (
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// document is an open text document.
+type document struct {
+	uri        string
+	text       string
+	lineStarts []int // Byte offset of the start of each line.
+
+	// file is the parsed document. Documents with syntax errors keep the
+	// statements which could be parsed.
+	file *ast.File
+}
+
+func newDocument(uri, text string) *document {
+	doc := &document{uri: uri, text: text, lineStarts: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			doc.lineStarts = append(doc.lineStarts, i+1)
+		}
+	}
+	doc.file, _ = parser.ParseFile(doc.filename(), []byte(text))
+	return doc
+}
+
+// filename returns the path of a file:// URI, or the URI itself for other
+// schemes.
+func (doc *document) filename() string {
+	u, err := url.Parse(doc.uri)
+	if err != nil || u.Scheme != "file" {
+		return doc.uri
+	}
+	return u.Path
+}
+
+// offset converts pos into a byte offset in the document. Positions past
+// the end of a line are clamped to the end of the line.
+func (doc *document) offset(pos position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(doc.lineStarts) {
+		return len(doc.text)
+	}
+
+	off := doc.lineStarts[pos.Line]
+	for units := 0; units < pos.Character && off < len(doc.text) && doc.text[off] != '\n'; {
+		r, size := utf8.DecodeRuneInString(doc.text[off:])
+		off += size
+		units += utf16Len(r)
+	}
+	return off
+}
+
+// position converts a byte offset in the document into a position.
+func (doc *document) position(off int) position {
+	off = max(0, min(off, len(doc.text)))
+	line := sort.Search(len(doc.lineStarts), func(i int) bool { return doc.lineStarts[i] > off }) - 1
+
+	character := 0
+	for _, r := range doc.text[doc.lineStarts[line]:off] {
+		character += utf16Len(r)
+	}
+	return position{Line: line, Character: character}
+}
+
+// textRange returns the range between the byte offsets start and end.
+func (doc *document) textRange(start, end int) textRange {
+	return textRange{Start: doc.position(start), End: doc.position(end)}
+}
+
+// nodeRange returns the range covered by n.
+func (doc *document) nodeRange(n ast.Node) textRange {
+	return doc.textRange(ast.StartPos(n).Offset(), doc.endOffset(ast.EndPos(n)))
+}
+
+// endOffset returns the offset just past the character at pos. AST end
+// positions point at the last character of a node rather than past it.
+func (doc *document) endOffset(pos token.Pos) int {
+	off := pos.Offset()
+	if off >= len(doc.text) {
+		return len(doc.text)
+	}
+	_, size := utf8.DecodeRuneInString(doc.text[off:])
+	return off + size
+}
+
+// contains reports whether the byte offset off is inside n or directly
+// after it, so a cursor at the end of an identifier still refers to it.
+func (doc *document) contains(n ast.Node, off int) bool {
+	start, end := ast.StartPos(n), ast.EndPos(n)
+	return start.Valid() && end.Valid() && start.Offset() <= off && off <= doc.endOffset(end)
+}
+
+// blocksAt returns the blocks whose bodies contain the byte offset off,
+// from the outermost to the innermost. Blocks which are missing their
+// closing brace extend to the end of the document.
+func (doc *document) blocksAt(off int) []*ast.BlockStmt {
+	var path []*ast.BlockStmt
+
+	body := doc.file.Body
+	for {
+		i := slices.IndexFunc(body, func(stmt ast.Stmt) bool {
+			block, ok := stmt.(*ast.BlockStmt)
+			if !ok || !block.LCurlyPos.Valid() || off <= block.LCurlyPos.Offset() {
+				return false
+			}
+			return !block.RCurlyPos.Valid() || off <= block.RCurlyPos.Offset()
+		})
+		if i < 0 {
+			return path
+		}
+		block := body[i].(*ast.BlockStmt)
+		path = append(path, block)
+		body = block.Body
+	}
+}
+
+// inValue reports whether the byte offset off is inside the value of an
+// attribute, including attributes which are still being typed and have
+// no value yet.
+func (doc *document) inValue(off int) bool {
+	body := doc.file.Body
+	if path := doc.blocksAt(off); len(path) > 0 {
+		body = path[len(path)-1].Body
+	}
+	for _, stmt := range body {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if ok && attr.Value != nil && doc.endOffset(ast.EndPos(attr.Name)) < off && doc.contains(attr.Value, off) {
+			return true
+		}
+	}
+
+	lineStart := strings.LastIndexByte(doc.text[:off], '\n') + 1
+	return strings.Contains(doc.text[lineStart:off], "=")
+}
+
+// components returns the labeled blocks of registered components declared
+// in the document.
+func (doc *document) components() []*ast.BlockStmt {
+	var blocks []*ast.BlockStmt
+	for _, stmt := range doc.file.Body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok || block.Label == "" {
+			continue
+		}
+		if _, ok := component.Get(block.GetBlockName()); ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// referenceAt returns the outermost chain of identifiers and field
+// accesses, such as prometheus.scrape.default.targets, which contains the
+// byte offset off, along with the names in the chain. It returns a nil
+// expression if there is no such chain.
+func (doc *document) referenceAt(off int) (ast.Expr, []string) {
+	v := &referenceFinder{doc: doc, off: off}
+	ast.Walk(v, doc.file)
+	return v.expr, v.names
+}
+
+type referenceFinder struct {
+	doc   *document
+	off   int
+	expr  ast.Expr
+	names []string
+}
+
+func (v *referenceFinder) Visit(n ast.Node) ast.Visitor {
+	if n == nil || v.expr != nil {
+		return nil
+	}
+	switch n.(type) {
+	case *ast.File, ast.Body:
+		// Files and bodies have no position of their own.
+		return v
+	}
+	if !v.doc.contains(n, v.off) {
+		return nil
+	}
+	if expr, ok := n.(ast.Expr); ok {
+		if names, ok := referenceNames(expr); ok {
+			v.expr, v.names = expr, names
+			return nil
+		}
+	}
+	return v
+}
+
+// referenceNames flattens a chain of field accesses on an identifier into
+// its names.
+func referenceNames(expr ast.Expr) ([]string, bool) {
+	switch expr := expr.(type) {
+	case *ast.IdentifierExpr:
+		return []string{expr.Ident.Name}, true
+	case *ast.AccessExpr:
+		names, ok := referenceNames(expr.Value)
+		return append(names, expr.Name.Name), ok
+	default:
+		return nil, false
+	}
+}
+
+// resolve returns the component block referred to by names, which must
+// start with the component name and label. It returns nil if no declared
+// component matches.
+func (doc *document) resolve(names []string) *ast.BlockStmt {
+	for _, block := range doc.components() {
+		id := append(slices.Clone(block.Name), block.Label)
+		if len(names) >= len(id) && slices.Equal(names[:len(id)], id) {
+			return block
+		}
+	}
+	return nil
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
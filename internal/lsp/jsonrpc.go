@@ -0,0 +1,140 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError     = -32700
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+// message is a JSON-RPC 2.0 request, notification or response. Requests
+// have an ID and a Method, notifications only a Method and responses only
+// an ID.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error returned in a response.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// conn reads and writes JSON-RPC messages framed with Content-Length
+// headers, as used by the Language Server Protocol.
+type conn struct {
+	r *bufio.Reader
+
+	mut sync.Mutex
+	w   io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{r: bufio.NewReader(r), w: w}
+}
+
+// read reads the next message. It returns io.EOF once the input is closed
+// between messages, and an *rpcError with codeParseError if the body of a
+// message isn't valid JSON, after which reading may continue. Any other
+// error leaves the input in an unknown state.
+func (c *conn) read() (*message, error) {
+	header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, &rpcError{Code: codeParseError, Message: err.Error()}
+	}
+	return &msg, nil
+}
+
+// write writes msg. It is safe to call write from multiple goroutines.
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+// nullID is the ID of responses to requests whose ID couldn't be read.
+var nullID = json.RawMessage("null")
+
+// isParseError reports whether err is the error returned by read for a
+// message which isn't valid JSON.
+func isParseError(err error) bool {
+	var rerr *rpcError
+	return errors.As(err, &rerr) && rerr.Code == codeParseError
+}
+
+// reply writes the response to the request with the given ID. If err is
+// non-nil, it is sent instead of result.
+func (c *conn) reply(id *json.RawMessage, result any, err error) error {
+	msg := &message{ID: id}
+	if err != nil {
+		rerr, ok := err.(*rpcError)
+		if !ok {
+			rerr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		msg.Error = rerr
+		return c.write(msg)
+	}
+
+	res, merr := json.Marshal(result)
+	if merr != nil {
+		return merr
+	}
+	msg.Result = res
+	return c.write(msg)
+}
+
+// notify sends a notification to the client.
+func (c *conn) notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return c.write(&message{Method: method, Params: raw})
+}
@@ -0,0 +1,135 @@
+package lsp
+
+// The types below are the subset of the Language Server Protocol used by
+// the server. Field names follow the specification at
+// https://microsoft.github.io/language-server-protocol/.
+
+// position is a zero-based line and character offset in a document.
+// Character offsets count UTF-16 code units.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// textRange is a half-open range of a document.
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// location is a range inside a document.
+type location struct {
+	URI   string    `json:"uri"`
+	Range textRange `json:"range"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+type serverInfo struct {
+	Name string `json:"name"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync           int                `json:"textDocumentSync"`
+	CompletionProvider         *completionOptions `json:"completionProvider,omitempty"`
+	HoverProvider              bool               `json:"hoverProvider"`
+	DefinitionProvider         bool               `json:"definitionProvider"`
+	DocumentFormattingProvider bool               `json:"documentFormattingProvider"`
+}
+
+type completionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+// textDocumentSyncFull asks clients to send the full text of a document on
+// every change.
+const textDocumentSyncFull = 1
+
+type textDocumentItem struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChange        `json:"contentChanges"`
+}
+
+// contentChange holds the new text of a document. Only full document
+// changes are supported, so Range is always absent.
+type contentChange struct {
+	Text string `json:"text"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type documentFormattingParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+// Diagnostic severities.
+const (
+	severityError   = 1
+	severityWarning = 2
+)
+
+type diagnostic struct {
+	Range    textRange `json:"range"`
+	Severity int       `json:"severity"`
+	Source   string    `json:"source"`
+	Message  string    `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// Completion item kinds.
+const (
+	completionKindField     = 5
+	completionKindModule    = 9
+	completionKindReference = 18
+	completionKindStruct    = 22
+)
+
+type completionItem struct {
+	Label      string `json:"label"`
+	Kind       int    `json:"kind"`
+	Detail     string `json:"detail,omitempty"`
+	InsertText string `json:"insertText,omitempty"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *textRange    `json:"range,omitempty"`
+}
+
+type textEdit struct {
+	Range   textRange `json:"range"`
+	NewText string    `json:"newText"`
+}
@@ -0,0 +1,400 @@
+// Package lsp implements a Language Server Protocol server for
+// configuration files.
+//
+// The server reports diagnostics from config.Validate whenever a document
+// changes, completes component names and the attributes and blocks of
+// components, shows component descriptions on hover, resolves references
+// to components to their declaring block and formats documents with the
+// canonical printer. Documents are synchronized in full on every change.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/printer"
+)
+
+// Serve runs a language server which reads requests from r and writes
+// responses to w. It returns when the client sends the exit notification,
+// when r is closed or when ctx is canceled.
+func Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s := &server{
+		conn: newConn(r, w),
+		docs: make(map[string]*document),
+	}
+
+	type result struct {
+		msg *message
+		err error
+	}
+	msgs := make(chan result)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			msg, err := s.conn.read()
+			select {
+			case msgs <- result{msg, err}:
+			case <-done:
+				return
+			}
+			if err != nil && !isParseError(err) {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case res := <-msgs:
+			switch {
+			case errors.Is(res.err, io.EOF):
+				return nil
+			case isParseError(res.err):
+				// The ID of the request can't be known, so the error is
+				// reported with a null ID as JSON-RPC requires.
+				if err := s.conn.reply(&nullID, nil, res.err); err != nil {
+					return err
+				}
+				continue
+			case res.err != nil:
+				return res.err
+			}
+			if exit, err := s.handle(res.msg); err != nil || exit {
+				return err
+			}
+		}
+	}
+}
+
+type server struct {
+	conn     *conn
+	docs     map[string]*document
+	shutdown bool
+}
+
+// handle handles a single message from the client. It reports whether the
+// server should exit.
+func (s *server) handle(msg *message) (exit bool, err error) {
+	if msg.ID == nil {
+		return s.handleNotification(msg)
+	}
+
+	var result any
+	switch msg.Method {
+	case "initialize":
+		result = initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:           textDocumentSyncFull,
+				CompletionProvider:         &completionOptions{TriggerCharacters: []string{"."}},
+				HoverProvider:              true,
+				DefinitionProvider:         true,
+				DocumentFormattingProvider: true,
+			},
+			ServerInfo: serverInfo{Name: "alloy"},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/completion":
+		result, err = withParams(msg, s.completion)
+	case "textDocument/hover":
+		result, err = withParams(msg, s.hover)
+	case "textDocument/definition":
+		result, err = withParams(msg, s.definition)
+	case "textDocument/formatting":
+		result, err = withParams(msg, s.formatting)
+	default:
+		err = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+	}
+	return false, s.conn.reply(msg.ID, result, err)
+}
+
+func (s *server) handleNotification(msg *message) (exit bool, err error) {
+	switch msg.Method {
+	case "exit":
+		return true, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return false, nil
+		}
+		return false, s.update(params.TextDocument.URI, params.TextDocument.Text)
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return false, nil
+		}
+		last := params.ContentChanges[len(params.ContentChanges)-1]
+		return false, s.update(params.TextDocument.URI, last.Text)
+
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return false, nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		// Clear the diagnostics of the closed document.
+		return false, s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+	}
+
+	// Other notifications, such as initialized and $/cancelRequest, are
+	// ignored.
+	return false, nil
+}
+
+// withParams decodes the parameters of msg and passes them to fn.
+func withParams[P any](msg *message, fn func(P) (any, error)) (any, error) {
+	var params P
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return fn(params)
+}
+
+// update stores the new text of a document and publishes its diagnostics.
+func (s *server) update(uri, text string) error {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+
+	ds := config.Validate(doc.filename(), []byte(text))
+	out := make([]diagnostic, 0, len(ds))
+	for _, d := range ds {
+		out = append(out, convertDiagnostic(doc, d))
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: out,
+	})
+}
+
+func convertDiagnostic(doc *document, d diag.Diagnostic) diagnostic {
+	start, end := d.StartPos.Offset, d.StartPos.Offset+1
+	if d.EndPos.Valid() && d.EndPos.Offset >= start {
+		end = d.EndPos.Offset + 1
+	}
+
+	severity := severityError
+	if d.Severity == diag.SeverityLevelWarn {
+		severity = severityWarning
+	}
+
+	message := d.Message
+	if d.Hint != "" {
+		message += "\nhint: " + d.Hint
+	}
+
+	return diagnostic{
+		Range:    doc.textRange(start, end),
+		Severity: severity,
+		Source:   "alloy",
+		Message:  message,
+	}
+}
+
+// document returns the open document with the given URI.
+func (s *server) document(uri string) (*document, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("document %q is not open", uri)}
+	}
+	return doc, nil
+}
+
+func (s *server) completion(params textDocumentPositionParams) (any, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	off := doc.offset(params.Position)
+	items := []completionItem{}
+
+	// References to other components are completed inside attribute
+	// values.
+	if doc.inValue(off) {
+		for _, block := range doc.components() {
+			reg, _ := component.Get(block.GetBlockName())
+			items = append(items, completionItem{
+				Label:  block.GetBlockName() + "." + block.Label,
+				Kind:   completionKindReference,
+				Detail: reg.Description,
+			})
+		}
+		return items, nil
+	}
+
+	path := doc.blocksAt(off)
+	if len(path) == 0 {
+		for _, name := range config.ComponentNames() {
+			reg, _ := component.Get(name)
+			items = append(items, completionItem{
+				Label:  name,
+				Kind:   completionKindModule,
+				Detail: reg.Description,
+			})
+		}
+		return items, nil
+	}
+
+	reg, ok := component.Get(path[0].GetBlockName())
+	if !ok || reg.Args == nil {
+		return items, nil
+	}
+	fields := syntax.Fields(reflect.TypeOf(reg.Args))
+	for _, block := range path[1:] {
+		i := slices.IndexFunc(fields, func(f syntax.Field) bool { return f.Block && f.Name == block.GetBlockName() })
+		if i < 0 {
+			return items, nil
+		}
+		fields = syntax.Fields(fields[i].Type)
+	}
+
+	set := make(map[string]bool)
+	for _, stmt := range path[len(path)-1].Body {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok {
+			set[attr.Name.Name] = true
+		}
+	}
+
+	for _, f := range fields {
+		detail := "required"
+		if f.Optional {
+			detail = "optional"
+		}
+
+		switch {
+		case f.Block:
+			items = append(items, completionItem{
+				Label:      f.Name,
+				Kind:       completionKindStruct,
+				Detail:     detail + " block",
+				InsertText: f.Name + " { }",
+			})
+		case !set[f.Name]:
+			items = append(items, completionItem{
+				Label:      f.Name,
+				Kind:       completionKindField,
+				Detail:     detail + " attribute",
+				InsertText: f.Name + " = ",
+			})
+		}
+	}
+	return items, nil
+}
+
+func (s *server) hover(params textDocumentPositionParams) (any, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	off := doc.offset(params.Position)
+
+	// Hovering over the name of a component block.
+	for _, stmt := range doc.file.Body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+		start := block.NamePos.Offset()
+		end := start + len(block.GetBlockName())
+		if block.NamePos.Valid() && start <= off && off <= end {
+			rng := doc.textRange(start, end)
+			return componentHover(block.GetBlockName(), &rng), nil
+		}
+	}
+
+	// Hovering over a reference to a component.
+	if expr, names := doc.referenceAt(off); expr != nil {
+		if block := doc.resolve(names); block != nil {
+			rng := doc.nodeRange(expr)
+			return componentHover(block.GetBlockName(), &rng), nil
+		}
+	}
+	return nil, nil
+}
+
+// componentHover documents the component called name. It returns nil for
+// unknown components.
+func componentHover(name string, rng *textRange) *hover {
+	reg, ok := component.Get(name)
+	if !ok {
+		return nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s**", name)
+	if reg.Description != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(reg.Description)
+	}
+	if reg.Version != "" {
+		fmt.Fprintf(&sb, "\n\nVersion: %s", reg.Version)
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: sb.String()},
+		Range:    rng,
+	}
+}
+
+func (s *server) definition(params textDocumentPositionParams) (any, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, names := doc.referenceAt(doc.offset(params.Position))
+	if expr == nil {
+		return nil, nil
+	}
+	block := doc.resolve(names)
+	if block == nil {
+		return nil, nil
+	}
+
+	// Point at the block header, from its name to the end of its label.
+	end := block.LabelPos.Offset() + len(block.Label) + len(`""`)
+	return []location{{
+		URI:   doc.uri,
+		Range: doc.textRange(block.NamePos.Offset(), end),
+	}}, nil
+}
+
+func (s *server) formatting(params documentFormattingParams) (any, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := printer.Format(doc.filename(), []byte(doc.text))
+	if err != nil {
+		// Documents with syntax errors are left alone; the errors are
+		// already reported as diagnostics.
+		return []textEdit{}, nil
+	}
+	if string(out) == doc.text {
+		return []textEdit{}, nil
+	}
+	return []textEdit{{
+		Range:   doc.textRange(0, len(doc.text)),
+		NewText: string(out),
+	}}, nil
+}
@@ -0,0 +1,275 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	_ "github.com/jharvey10/test-repo/internal/component/prometheus"
+)
+
+const testURI = "file:///config.alloy"
+
+// testClient talks to a server running in the background.
+type testClient struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	done   chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	c := &testClient{t: t, conn: newConn(clientR, clientW), done: make(chan error, 1)}
+	go func() {
+		c.done <- Serve(context.Background(), serverR, serverW)
+		serverW.Close()
+	}()
+	t.Cleanup(func() {
+		clientW.Close()
+		if err := <-c.done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	c.call("initialize", map[string]any{}, nil)
+	c.notify("initialized", map[string]any{})
+	return c
+}
+
+func (c *testClient) notify(method string, params any) {
+	c.t.Helper()
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and decodes its result into result.
+func (c *testClient) call(method string, params any, result any) {
+	c.t.Helper()
+
+	c.nextID++
+	id := json.RawMessage(mustJSON(c.t, c.nextID))
+	if err := c.conn.write(&message{ID: &id, Method: method, Params: json.RawMessage(mustJSON(c.t, params))}); err != nil {
+		c.t.Fatal(err)
+	}
+
+	msg := c.read()
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %s", method, msg.Error.Message)
+	}
+	if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+func (c *testClient) read() *message {
+	c.t.Helper()
+	msg, err := c.conn.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// open opens a document and returns the diagnostics published for it.
+func (c *testClient) open(text string) []diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", didOpenParams{
+		TextDocument: textDocumentItem{URI: testURI, Version: 1, Text: text},
+	})
+	return c.readDiagnostics()
+}
+
+func (c *testClient) readDiagnostics() []diagnostic {
+	c.t.Helper()
+	msg := c.read()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got %q", msg.Method)
+	}
+	var params publishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	return params.Diagnostics
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func at(line, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: testURI},
+		Position:     position{Line: line, Character: character},
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newTestClient(t)
+
+	ds := c.open("prometheus.scrap \"default\" { }\n")
+	if len(ds) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", ds)
+	}
+	expect := diagnostic{
+		Range:    textRange{Start: position{0, 0}, End: position{0, 16}},
+		Severity: severityError,
+		Source:   "alloy",
+		Message:  "unrecognized component name \"prometheus.scrap\"\nhint: did you mean \"prometheus.scrape\"?",
+	}
+	if ds[0] != expect {
+		t.Errorf("expected %+v, got %+v", expect, ds[0])
+	}
+
+	// Arguments are checked against the component's schema.
+	c.notify("textDocument/didChange", didChangeParams{
+		TextDocument:   textDocumentIdentifier{URI: testURI},
+		ContentChanges: []contentChange{{Text: "prometheus.scrape \"default\" {\n\ttargets = []\n\tjob_name = 12\n}\n"}},
+	})
+	ds = c.readDiagnostics()
+	expect = diagnostic{
+		Range:    textRange{Start: position{2, 12}, End: position{2, 14}},
+		Severity: severityError,
+		Source:   "alloy",
+		Message:  "expected string, got number",
+	}
+	if len(ds) != 1 || ds[0] != expect {
+		t.Errorf("expected %+v, got %+v", expect, ds)
+	}
+
+	// Fixing the document clears its diagnostics.
+	c.notify("textDocument/didChange", didChangeParams{
+		TextDocument:   textDocumentIdentifier{URI: testURI},
+		ContentChanges: []contentChange{{Text: "prometheus.scrape \"default\" {\n\ttargets = []\n}\n"}},
+	})
+	if ds := c.readDiagnostics(); len(ds) != 0 {
+		t.Errorf("expected no diagnostics, got %v", ds)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newTestClient(t)
+	c.open("\nprometheus.scrape \"a\" {\n\ttargets = []\n\t\n}\n\nprometheus.scrape \"b\" {\n\ttargets = \n}\n")
+
+	labels := func(items []completionItem) []string {
+		var res []string
+		for _, item := range items {
+			res = append(res, item.Label)
+		}
+		return res
+	}
+
+	tt := []struct {
+		name   string
+		pos    textDocumentPositionParams
+		expect []string
+	}{
+		{"top level", at(0, 0), []string{"prometheus.scrape"}},
+		{"attributes not yet set", at(3, 1), []string{"job_name", "bearer_token"}},
+		{"references in values", at(7, 11), []string{"prometheus.scrape.a", "prometheus.scrape.b"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var items []completionItem
+			c.call("textDocument/completion", tc.pos, &items)
+			if got := strings.Join(labels(items), ","); got != strings.Join(tc.expect, ",") {
+				t.Errorf("expected %v, got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	c := newTestClient(t)
+	c.open("prometheus.scrape \"a\" {\n\ttargets = []\n}\n\nprometheus.scrape \"b\" {\n\ttargets = prometheus.scrape.a.targets\n}\n")
+
+	var h hover
+	c.call("textDocument/hover", at(0, 3), &h)
+	if !strings.Contains(h.Contents.Value, "Scrapes Prometheus metrics from targets") {
+		t.Errorf("unexpected hover contents %q", h.Contents.Value)
+	}
+
+	c.call("textDocument/hover", at(5, 30), &h)
+	if expect := (textRange{Start: position{5, 11}, End: position{5, 38}}); h.Range == nil || *h.Range != expect {
+		t.Errorf("expected hover over the reference %v, got %v", expect, h.Range)
+	}
+
+	var locs []location
+	c.call("textDocument/definition", at(5, 20), &locs)
+	expect := location{URI: testURI, Range: textRange{Start: position{0, 0}, End: position{0, 21}}}
+	if len(locs) != 1 || locs[0] != expect {
+		t.Errorf("expected %v, got %v", expect, locs)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newTestClient(t)
+	c.open("prometheus.scrape \"a\" {\ntargets=[]\n}")
+
+	var edits []textEdit
+	c.call("textDocument/formatting", documentFormattingParams{TextDocument: textDocumentIdentifier{URI: testURI}}, &edits)
+	if len(edits) != 1 {
+		t.Fatalf("expected 1 edit, got %v", edits)
+	}
+	if expect := "prometheus.scrape \"a\" {\n\ttargets = []\n}\n"; edits[0].NewText != expect {
+		t.Errorf("expected %q, got %q", expect, edits[0].NewText)
+	}
+	if expect := (textRange{End: position{2, 1}}); edits[0].Range != expect {
+		t.Errorf("expected edit to replace %v, got %v", expect, edits[0].Range)
+	}
+}
+
+func TestDocumentPositions(t *testing.T) {
+	// 😀 is two UTF-16 code units and four bytes.
+	doc := newDocument(testURI, "a = \"😀\"\nb = 1\n")
+
+	tt := []struct {
+		pos position
+		off int
+	}{
+		{position{0, 0}, 0},
+		{position{0, 5}, 5},
+		{position{0, 7}, 9},
+		{position{1, 0}, 11},
+		{position{1, 4}, 15},
+	}
+	for _, tc := range tt {
+		if off := doc.offset(tc.pos); off != tc.off {
+			t.Errorf("offset(%v): expected %d, got %d", tc.pos, tc.off, off)
+		}
+		if pos := doc.position(tc.off); pos != tc.pos {
+			t.Errorf("position(%d): expected %v, got %v", tc.off, tc.pos, pos)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	c := newTestClient(t)
+
+	body := "{bad json"
+	if _, err := fmt.Fprintf(c.conn.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		t.Fatal(err)
+	}
+	msg := c.read()
+	if msg.Error == nil || msg.Error.Code != codeParseError || msg.ID != nil {
+		t.Fatalf("expected a parse error with a null ID, got %+v", msg)
+	}
+
+	// The server keeps serving after a malformed message.
+	c.call("shutdown", nil, nil)
+}
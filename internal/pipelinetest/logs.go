@@ -0,0 +1,143 @@
+package pipelinetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jharvey10/test-repo/internal/component"
+)
+
+// parseLogLines returns an entry without labels for every line of src.
+// Empty lines are skipped.
+func parseLogLines(src string) []component.LogEntry {
+	var entries []component.LogEntry
+	for _, line := range strings.Split(src, "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			entries = append(entries, component.LogEntry{Line: line})
+		}
+	}
+	return entries
+}
+
+// otlpLogs is the part of the OTLP JSON encoding of logs which is read.
+type otlpLogs struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []struct {
+				Body       otlpValue       `json:"body"`
+				Attributes []otlpAttribute `json:"attributes"`
+			} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is an OTLP AnyValue. Only scalar values are supported.
+type otlpValue struct {
+	StringValue *string         `json:"stringValue"`
+	BoolValue   *bool           `json:"boolValue"`
+	IntValue    json.RawMessage `json:"intValue"` // A string or a number.
+	DoubleValue *float64        `json:"doubleValue"`
+}
+
+func (v otlpValue) String() (string, error) {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue, nil
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue), nil
+	case v.IntValue != nil:
+		s := strings.Trim(string(v.IntValue), `"`)
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			return "", fmt.Errorf("invalid intValue %s", v.IntValue)
+		}
+		return s, nil
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64), nil
+	}
+	return "", nil
+}
+
+// parseOTLPLogs returns an entry for every log record of the OTLP JSON logs
+// in src. The entries are labeled with the attributes of their resource
+// and their own attributes, which take precedence.
+func parseOTLPLogs(src string) ([]component.LogEntry, error) {
+	var logs otlpLogs
+	if err := json.Unmarshal([]byte(src), &logs); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON logs: %w", err)
+	}
+
+	var entries []component.LogEntry
+	for _, rl := range logs.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, record := range sl.LogRecords {
+				e := component.LogEntry{Labels: make(map[string]string)}
+				for _, attrs := range [][]otlpAttribute{rl.Resource.Attributes, record.Attributes} {
+					for _, attr := range attrs {
+						v, err := attr.Value.String()
+						if err != nil {
+							return nil, fmt.Errorf("attribute %q: %w", attr.Key, err)
+						}
+						e.Labels[attr.Key] = v
+					}
+				}
+				line, err := record.Body.String()
+				if err != nil {
+					return nil, fmt.Errorf("log record body: %w", err)
+				}
+				e.Line = line
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// logLines returns the sorted lines of entries, so that they can be
+// compared regardless of order and labels.
+func logLines(entries []component.LogEntry) []string {
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, e.Line)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// formatEntries formats entries as sorted lines of their labels followed
+// by their quoted line, such as {level="info"} "started", so that they can
+// be compared regardless of order.
+func formatEntries(entries []component.LogEntry) []string {
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names := make([]string, 0, len(e.Labels))
+		for name := range e.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var sb strings.Builder
+		sb.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, "%s=%q", name, e.Labels[name])
+		}
+		sb.WriteString("} ")
+		sb.WriteString(strconv.Quote(e.Line))
+		lines = append(lines, sb.String())
+	}
+	sort.Strings(lines)
+	return lines
+}
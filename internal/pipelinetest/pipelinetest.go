@@ -0,0 +1,360 @@
+// Package pipelinetest runs a configuration against fixtures and checks the
+// data its components produce.
+//
+// A test file declares the metrics which scrape targets serve, in the
+// Prometheus text exposition format, the log entries sent to components,
+// and the data expected from components:
+//
+//	target {
+//		address = "localhost:9090"
+//		metrics = `up 1`
+//	}
+//
+//	input {
+//		component = "loki.process.default"
+//		logs      = "level=info msg=started"
+//	}
+//
+//	expect {
+//		component = "prometheus.scrape.default"
+//		metrics   = `up{instance="localhost:9090",job="prometheus.scrape.default"} 1`
+//	}
+//
+// Log entries are written either as log lines, one entry per line without
+// labels, or as OTLP JSON logs, whose resource and record attributes
+// become the labels of each entry. Expected log lines are compared with the
+// lines of the entries a component produces, ignoring their labels, and
+// expected OTLP JSON logs with both their lines and labels.
+//
+// The components run with in-memory sinks in place of their destinations,
+// and with targets served from memory.
+package pipelinetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/component/prometheus"
+	"github.com/jharvey10/test-repo/internal/runner"
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+// Test holds the fixtures and expectations of a test file.
+type Test struct {
+	Targets []Target `syntax:"target,block,optional"`
+	Inputs  []Input  `syntax:"input,block,optional"`
+	Expects []Expect `syntax:"expect,block"`
+}
+
+// Target is a scrape target served from memory.
+type Target struct {
+	Address string `syntax:"address,attr"`
+	Metrics string `syntax:"metrics,attr"`
+}
+
+// Input holds log entries sent to a component which accepts them.
+type Input struct {
+	Component string `syntax:"component,attr"`
+	Logs      string `syntax:"logs,attr,optional"`
+	OTLP      string `syntax:"otlp,attr,optional"`
+}
+
+// Expect holds the data expected from a component. Only the kinds of data
+// which are set are checked.
+type Expect struct {
+	Component string  `syntax:"component,attr"`
+	Metrics   *string `syntax:"metrics,attr,optional"`
+	Logs      *string `syntax:"logs,attr,optional"`
+	OTLP      *string `syntax:"otlp,attr,optional"`
+}
+
+// kinds describes the kinds of data e checks, for errors.
+func (e Expect) kinds() string {
+	var kinds []string
+	if e.Metrics != nil {
+		kinds = append(kinds, "metrics")
+	}
+	if e.Logs != nil || e.OTLP != nil {
+		kinds = append(kinds, "logs")
+	}
+	if len(kinds) == 0 {
+		return "data"
+	}
+	return strings.Join(kinds, " and ")
+}
+
+// ParseTest parses the test file src.
+func ParseTest(filename string, src []byte) (*Test, error) {
+	f, err := parser.ParseFile(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	var t Test
+	if err := vm.New(f.Body).Evaluate(syntax.RootScope(), &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Failure is an expectation which wasn't met.
+type Failure struct {
+	// Component is the ID of the component the expectation is for.
+	Component string
+	// Diff lists the expected samples and log entries which weren't
+	// produced, prefixed with -, and the produced ones which weren't
+	// expected, prefixed with +.
+	Diff string
+}
+
+// Run runs the components declared in f with the fixtures of t until they
+// complete, and compares the data they produce with the expectations of t.
+// Input log entries are sent before the components run. It returns a
+// Failure for each expectation which isn't met, and an error if the
+// components can't be run.
+func Run(ctx context.Context, opts runner.Options, f *ast.File, t *Test) ([]Failure, error) {
+	type expectation struct {
+		metrics, logs, otlp []string
+	}
+	expected := make([]expectation, len(t.Expects))
+	for i, e := range t.Expects {
+		if e.Metrics != nil {
+			samples, err := prometheus.ParseText(*e.Metrics)
+			if err != nil {
+				return nil, fmt.Errorf("expected metrics of %s: %w", e.Component, err)
+			}
+			expected[i].metrics = formatSamples(samples)
+		}
+		if e.Logs != nil {
+			expected[i].logs = logLines(parseLogLines(*e.Logs))
+		}
+		if e.OTLP != nil {
+			entries, err := parseOTLPLogs(*e.OTLP)
+			if err != nil {
+				return nil, fmt.Errorf("expected logs of %s: %w", e.Component, err)
+			}
+			expected[i].otlp = formatEntries(entries)
+		}
+	}
+
+	targets := make(map[string]string, len(t.Targets))
+	for _, target := range t.Targets {
+		targets[target.Address] = target.Metrics
+	}
+
+	sinks := &sinks{appenders: make(map[string]*appender), receivers: make(map[string]*logReceiver)}
+	opts.Sinks = sinks
+	opts.HTTPClient = &http.Client{Transport: fixtureTransport(targets)}
+	// The runner must exit once the components complete.
+	opts.HTTPListenAddr = ""
+
+	r := runner.New(opts)
+	if err := r.Load(f); err != nil {
+		return nil, err
+	}
+	for _, e := range t.Expects {
+		if _, ok := r.Component(e.Component); !ok {
+			return nil, fmt.Errorf("expected %s of %s: no such component", e.kinds(), e.Component)
+		}
+	}
+	for _, in := range t.Inputs {
+		if err := sendInput(r, in); err != nil {
+			return nil, fmt.Errorf("input of %s: %w", in.Component, err)
+		}
+	}
+	if err := r.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	var failures []Failure
+	for i, e := range t.Expects {
+		var diffs []string
+		if e.Metrics != nil {
+			diffs = append(diffs, diffLines(expected[i].metrics, sinks.samples(e.Component)))
+		}
+		if e.Logs != nil {
+			diffs = append(diffs, diffLines(expected[i].logs, logLines(sinks.entries(e.Component))))
+		}
+		if e.OTLP != nil {
+			diffs = append(diffs, diffLines(expected[i].otlp, formatEntries(sinks.entries(e.Component))))
+		}
+		if diff := strings.Join(slices.DeleteFunc(diffs, func(d string) bool { return d == "" }), "\n"); diff != "" {
+			failures = append(failures, Failure{Component: e.Component, Diff: diff})
+		}
+	}
+	return failures, nil
+}
+
+// sendInput sends the log entries of in to its component.
+func sendInput(r *runner.Runner, in Input) error {
+	c, ok := r.Component(in.Component)
+	if !ok {
+		return errors.New("no such component")
+	}
+	lc, ok := c.(component.LogsConsumer)
+	if !ok {
+		return errors.New("the component does not accept logs")
+	}
+
+	entries := parseLogLines(in.Logs)
+	if in.OTLP != "" {
+		otlp, err := parseOTLPLogs(in.OTLP)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, otlp...)
+	}
+
+	receiver := lc.LogReceiver()
+	for _, e := range entries {
+		if err := receiver.Receive(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sinks implements runner.Sinks by recording samples and log entries in
+// memory.
+type sinks struct {
+	mut       sync.Mutex
+	appenders map[string]*appender
+	receivers map[string]*logReceiver
+}
+
+// Appender implements runner.Sinks.
+func (s *sinks) Appender(id string) component.Appender {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.appenders[id] == nil {
+		s.appenders[id] = &appender{}
+	}
+	return s.appenders[id]
+}
+
+// LogReceiver implements runner.Sinks.
+func (s *sinks) LogReceiver(id string) component.LogReceiver {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.receivers[id] == nil {
+		s.receivers[id] = &logReceiver{}
+	}
+	return s.receivers[id]
+}
+
+// samples returns the formatted samples the component id produced.
+func (s *sinks) samples(id string) []string {
+	s.mut.Lock()
+	a := s.appenders[id]
+	s.mut.Unlock()
+	if a == nil {
+		return nil
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return formatSamples(a.samples)
+}
+
+// entries returns the log entries the component id produced.
+func (s *sinks) entries(id string) []component.LogEntry {
+	s.mut.Lock()
+	lr := s.receivers[id]
+	s.mut.Unlock()
+	if lr == nil {
+		return nil
+	}
+
+	lr.mut.Lock()
+	defer lr.mut.Unlock()
+	return slices.Clone(lr.entries)
+}
+
+// appender records the samples appended to it.
+type appender struct {
+	mut     sync.Mutex
+	samples []component.Sample
+}
+
+// Append implements component.Appender.
+func (a *appender) Append(s component.Sample) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.samples = append(a.samples, s)
+	return nil
+}
+
+// logReceiver records the log entries it receives.
+type logReceiver struct {
+	mut     sync.Mutex
+	entries []component.LogEntry
+}
+
+// Receive implements component.LogReceiver.
+func (lr *logReceiver) Receive(e component.LogEntry) error {
+	lr.mut.Lock()
+	defer lr.mut.Unlock()
+	lr.entries = append(lr.entries, e)
+	return nil
+}
+
+// fixtureTransport serves the metrics of each target address from memory.
+type fixtureTransport map[string]string
+
+// RoundTrip implements http.RoundTripper.
+func (ft fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	metrics, ok := ft[req.URL.Host]
+	if !ok {
+		return nil, fmt.Errorf("no fixture for target %s", req.URL.Host)
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       io.NopCloser(strings.NewReader(metrics)),
+		Request:    req,
+	}, nil
+}
+
+// formatSamples formats samples as sorted lines of the text exposition
+// format, so that they can be compared regardless of order.
+func formatSamples(samples []component.Sample) []string {
+	lines := make([]string, 0, len(samples))
+	for _, s := range samples {
+		lines = append(lines, prometheus.FormatSample(s))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// diffLines returns the lines of expected missing from actual, prefixed
+// with -, followed by the lines of actual missing from expected, prefixed
+// with +. Both must be sorted. It returns an empty string if they match.
+func diffLines(expected, actual []string) string {
+	var missing, extra []string
+	i, j := 0, 0
+	for i < len(expected) || j < len(actual) {
+		switch {
+		case j == len(actual) || (i < len(expected) && expected[i] < actual[j]):
+			missing = append(missing, "- "+expected[i])
+			i++
+		case i == len(expected) || actual[j] < expected[i]:
+			extra = append(extra, "+ "+actual[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return strings.Join(append(missing, extra...), "\n")
+}
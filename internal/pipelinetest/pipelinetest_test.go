@@ -0,0 +1,232 @@
+package pipelinetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	_ "github.com/jharvey10/test-repo/internal/component/prometheus"
+	"github.com/jharvey10/test-repo/internal/runner"
+	"github.com/jharvey10/test-repo/syntax/parser"
+)
+
+const testConfig = `prometheus.scrape "a" {
+	targets  = [{"__address__" = "a:9090"}, {"__address__" = "b:9090"}]
+	job_name = "api"
+}
+
+prometheus.scrape "b" {
+	targets = [{"__address__" = "b:9090"}]
+}
+`
+
+// logsComponent sends the log entries it receives on to its receiver,
+// adding its label to them.
+type logsComponent struct {
+	id, label string
+	receiver  component.LogReceiver
+}
+
+func (c *logsComponent) Run() error                             { return nil }
+func (c *logsComponent) Name() string                           { return c.id }
+func (c *logsComponent) SetLogReceiver(r component.LogReceiver) { c.receiver = r }
+func (c *logsComponent) LogReceiver() component.LogReceiver     { return c }
+
+func (c *logsComponent) Receive(e component.LogEntry) error {
+	labels := map[string]string{"component": c.label}
+	for k, v := range e.Labels {
+		labels[k] = v
+	}
+	return c.receiver.Receive(component.LogEntry{Labels: labels, Line: e.Line})
+}
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "test.logs",
+		Stability: component.StabilityGenerallyAvailable,
+		Build: func(opts component.Options, _ any) (component.Component, error) {
+			_, label, _ := strings.Cut(strings.TrimPrefix(opts.ID, "test."), ".")
+			return &logsComponent{id: opts.ID, label: label}, nil
+		},
+	})
+}
+
+func run(t *testing.T, config, test string) ([]Failure, error) {
+	t.Helper()
+
+	f, err := parser.ParseFile("config.alloy", []byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf, err := ParseTest("test.alloy", []byte(test))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Run(context.Background(), runner.Options{Logger: zap.NewNop()}, f, tf)
+}
+
+func TestRun(t *testing.T) {
+	failures, err := run(t, testConfig, `
+target {
+	address = "a:9090"
+	metrics = "# TYPE up gauge\nup 1\nrequests_total{code=\"200\"} 10\n"
+}
+
+target {
+	address = "b:9090"
+	metrics = "up 0"
+}
+
+expect {
+	component = "prometheus.scrape.a"
+	metrics   = "up{instance=\"b:9090\",job=\"api\"} 0\nup{instance=\"a:9090\",job=\"api\"} 1\nrequests_total{code=\"200\",instance=\"a:9090\",job=\"api\"} 10"
+}
+
+expect {
+	component = "prometheus.scrape.b"
+	metrics   = "up{instance=\"b:9090\",job=\"prometheus.scrape.b\"} 0"
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("expected no failures, got %+v", failures)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	failures, err := run(t, testConfig, `
+target {
+	address = "a:9090"
+	metrics = "up 1"
+}
+
+target {
+	address = "b:9090"
+	metrics = "up 0"
+}
+
+expect {
+	component = "prometheus.scrape.a"
+	metrics   = "up{instance=\"a:9090\",job=\"api\"} 1\nup{instance=\"b:9090\",job=\"api\"} 1"
+}
+
+expect {
+	component = "prometheus.scrape.b"
+	metrics   = "up{instance=\"b:9090\",job=\"prometheus.scrape.b\"} 0"
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Failure{{
+		Component: "prometheus.scrape.a",
+		Diff:      "- up{instance=\"b:9090\",job=\"api\"} 1\n+ up{instance=\"b:9090\",job=\"api\"} 0",
+	}}
+	if len(failures) != len(expect) || failures[0] != expect[0] {
+		t.Errorf("expected failures %+v, got %+v", expect, failures)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tt := []struct {
+		test   string
+		expect string // Substring of the expected error.
+	}{
+		{`
+target {
+	address = "a:9090"
+	metrics = "up 1"
+}
+
+expect {
+	component = "prometheus.scrape.a"
+	metrics   = "up 1"
+}
+`, `scraping b:9090: Get "http://b:9090/metrics": no fixture for target b:9090`},
+		{`
+expect {
+	component = "prometheus.scrape.c"
+	metrics   = "up 1"
+}
+`, "expected metrics of prometheus.scrape.c: no such component"},
+		{`
+expect {
+	component = "prometheus.scrape.a"
+	metrics   = "up"
+}
+`, `expected metrics of prometheus.scrape.a: line 1: invalid sample "up"`},
+		{`
+input {
+	component = "prometheus.scrape.a"
+	logs      = "started"
+}
+
+expect {
+	component = "prometheus.scrape.a"
+}
+`, "input of prometheus.scrape.a: the component does not accept logs"},
+		{`
+expect {
+	component = "prometheus.scrape.a"
+	otlp      = "{"
+}
+`, "expected logs of prometheus.scrape.a: invalid OTLP JSON logs: unexpected end of JSON input"},
+	}
+	for _, tc := range tt {
+		if _, err := run(t, testConfig, tc.test); err == nil || !strings.Contains(err.Error(), tc.expect) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.test, tc.expect, err)
+		}
+	}
+}
+
+func TestRun_Logs(t *testing.T) {
+	const otlp = `{"resourceLogs": [{
+	"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "api"}}]},
+	"scopeLogs": [{"logRecords": [
+		{"body": {"stringValue": "request served"}, "attributes": [{"key": "status", "value": {"intValue": "200"}}]},
+		{"body": {"stringValue": "shutting down"}}
+	]}]
+}]}`
+
+	failures, err := run(t, `test.logs "a" { }
+test.logs "b" { }
+`, `
+input {
+	component = "test.logs.a"
+	logs      = "level=info msg=started\nlevel=warn msg=slow\n"
+}
+
+input {
+	component = "test.logs.b"
+	otlp      = `+"`"+otlp+"`"+`
+}
+
+expect {
+	component = "test.logs.a"
+	logs      = "level=warn msg=slow\nlevel=info msg=started"
+}
+
+expect {
+	component = "test.logs.b"
+	otlp      = `+"`"+strings.Replace(otlp, `"shutting down"`, `"stopped"`, 1)+"`"+`
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Failure{{
+		Component: "test.logs.b",
+		Diff: `- {service.name="api",status="200"} "request served"
+- {service.name="api"} "stopped"
++ {component="b",service.name="api",status="200"} "request served"
++ {component="b",service.name="api"} "shutting down"`,
+	}}
+	if len(failures) != len(expect) || failures[0] != expect[0] {
+		t.Errorf("expected failures %+v, got %+v", expect, failures)
+	}
+}
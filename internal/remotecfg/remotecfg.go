@@ -0,0 +1,245 @@
+// Package remotecfg periodically fetches the runner's configuration from an
+// HTTP endpoint, as used by fleet management.
+//
+// Fetched configuration is validated before it is applied, which
+// `alloy run --remotecfg.url` does by restarting the runner, and the last
+// configuration which applied successfully is cached on disk so that the
+// runner can start while the endpoint is down.
+package remotecfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultPollFrequency is used when Options.PollFrequency is not set.
+const DefaultPollFrequency = time.Minute
+
+// DefaultTimeout is used when Options.Timeout is not set.
+const DefaultTimeout = 30 * time.Second
+
+const (
+	cacheConfigFile = "remote.alloy"
+	cacheETagFile   = "remote.etag"
+)
+
+// Options configures a Service.
+type Options struct {
+	// URL of the endpoint serving the configuration.
+	URL string
+	// PollFrequency is how often URL is polled for changes.
+	PollFrequency time.Duration
+	// CacheDir is the directory the last-good configuration is stored in.
+	CacheDir string
+	// Client performs the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Timeout bounds each request, including reading the response, so
+	// that an endpoint which hangs doesn't stop polling. Defaults to
+	// DefaultTimeout.
+	Timeout time.Duration
+
+	// Validate checks fetched configuration before it is applied.
+	Validate func(config []byte) error
+	// Apply loads configuration into the runner.
+	Apply func(config []byte) error
+	// OnError, if set, receives errors which happen after startup. The
+	// last-good configuration stays applied.
+	OnError func(error)
+}
+
+// Service polls a remote configuration endpoint.
+type Service struct {
+	opts Options
+	etag string
+}
+
+// New creates a new Service.
+func New(opts Options) (*Service, error) {
+	if opts.URL == "" {
+		return nil, errors.New("remotecfg: url must not be empty")
+	}
+	if opts.CacheDir == "" {
+		return nil, errors.New("remotecfg: cache directory must not be empty")
+	}
+	if opts.Validate == nil || opts.Apply == nil {
+		return nil, errors.New("remotecfg: Validate and Apply must be set")
+	}
+	if opts.PollFrequency <= 0 {
+		opts.PollFrequency = DefaultPollFrequency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &Service{opts: opts}, nil
+}
+
+// Run applies the initial configuration and then polls for changes until ctx
+// is canceled.
+//
+// The initial configuration comes from the endpoint when it is reachable and
+// from the on-disk cache otherwise. Run returns an error only if neither
+// yields a valid configuration.
+func (s *Service) Run(ctx context.Context) error {
+	if err := s.start(ctx); err != nil {
+		return err
+	}
+
+	t := time.NewTicker(s.opts.PollFrequency)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			if err := s.poll(ctx); err != nil && s.opts.OnError != nil {
+				s.opts.OnError(err)
+			}
+		}
+	}
+}
+
+// start applies the initial configuration. The cached configuration is
+// applied when the endpoint is unreachable or its configuration fails to
+// validate or apply.
+func (s *Service) start(ctx context.Context) error {
+	// The ETag is only sent when the cache was read, so that the endpoint
+	// can't report a missing configuration as current.
+	cached, etag, cacheErr := s.readCache()
+	if cacheErr == nil {
+		s.etag = etag
+	}
+
+	config, err := s.fetch(ctx)
+	switch {
+	case err == nil && config == nil && cacheErr == nil:
+		// The endpoint reported that the cached configuration is current.
+		return s.opts.Apply(cached)
+	case err == nil && config == nil:
+		err = errors.New("remotecfg: fetching config: unexpected status 304 Not Modified")
+	case err == nil:
+		if err = s.apply(config); err == nil {
+			return nil
+		}
+	}
+
+	if cacheErr != nil {
+		return fmt.Errorf("remotecfg: no cached config available: %w", err)
+	}
+	if s.opts.OnError != nil {
+		s.opts.OnError(fmt.Errorf("remotecfg: falling back to cached config: %w", err))
+	}
+	return s.opts.Apply(cached)
+}
+
+// poll fetches the endpoint and applies any changed configuration.
+func (s *Service) poll(ctx context.Context) error {
+	config, err := s.fetch(ctx)
+	if err != nil || config == nil {
+		return err
+	}
+	return s.apply(config)
+}
+
+// fetch requests the configuration from the endpoint. It returns nil
+// configuration without an error if the endpoint reports it unchanged.
+func (s *Service) fetch(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.opts.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remotecfg: fetching config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("remotecfg: fetching config: unexpected status %s", resp.Status)
+	}
+
+	config, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("remotecfg: reading config: %w", err)
+	}
+	s.etag = resp.Header.Get("ETag")
+	return config, nil
+}
+
+// apply validates and applies config, and caches it once it has applied
+// successfully.
+func (s *Service) apply(config []byte) error {
+	if err := s.opts.Validate(config); err != nil {
+		s.etag = ""
+		return fmt.Errorf("remotecfg: invalid config: %w", err)
+	}
+	if err := s.opts.Apply(config); err != nil {
+		s.etag = ""
+		return fmt.Errorf("remotecfg: applying config: %w", err)
+	}
+	if err := s.writeCache(config, s.etag); err != nil {
+		return fmt.Errorf("remotecfg: caching config: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) readCache() (config []byte, etag string, err error) {
+	config, err = os.ReadFile(filepath.Join(s.opts.CacheDir, cacheConfigFile))
+	if err != nil {
+		return nil, "", err
+	}
+	bb, err := os.ReadFile(filepath.Join(s.opts.CacheDir, cacheETagFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, "", err
+	}
+	return config, string(bb), nil
+}
+
+func (s *Service) writeCache(config []byte, etag string) error {
+	if err := os.MkdirAll(s.opts.CacheDir, 0o750); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(s.opts.CacheDir, cacheConfigFile), config); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.opts.CacheDir, cacheETagFile), []byte(etag))
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path,
+// so a crash never leaves a partially written cache behind.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
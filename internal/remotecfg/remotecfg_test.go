@@ -0,0 +1,288 @@
+package remotecfg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// server serves a configuration with an ETag derived from its content.
+type server struct {
+	mut         sync.Mutex
+	config      string
+	notModified int
+}
+
+func (s *server) set(config string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.config = config
+}
+
+func (s *server) notModifiedCount() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.notModified
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	etag := `"` + s.config + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write([]byte(s.config))
+}
+
+// recorder collects applied configurations.
+type recorder struct {
+	applied chan string
+}
+
+func newRecorder() *recorder { return &recorder{applied: make(chan string, 10)} }
+
+func (r *recorder) apply(config []byte) error {
+	r.applied <- string(config)
+	return nil
+}
+
+func (r *recorder) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case c := <-r.applied:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for config to be applied")
+		return ""
+	}
+}
+
+func validate(config []byte) error {
+	if strings.Contains(string(config), "invalid") {
+		return errors.New("config is invalid")
+	}
+	return nil
+}
+
+func TestService_PollsAndApplies(t *testing.T) {
+	srv := &server{config: "v1"}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	rec := newRecorder()
+	errs := make(chan error, 10)
+	svc, err := New(Options{
+		URL:           ts.URL,
+		PollFrequency: 10 * time.Millisecond,
+		CacheDir:      t.TempDir(),
+		Validate:      validate,
+		Apply:         rec.apply,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = svc.Run(ctx) }()
+
+	if got := rec.next(t); got != "v1" {
+		t.Fatalf("initial config = %q, want v1", got)
+	}
+
+	// Unchanged config is answered with 304 Not Modified.
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.notModifiedCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a conditional request")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// An invalid config must be reported and not applied.
+	srv.set("invalid")
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "invalid config") {
+			t.Fatalf("error = %v, want invalid config error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for validation error")
+	}
+
+	srv.set("v2")
+	for {
+		got := rec.next(t)
+		if got == "v2" {
+			break
+		}
+		if got == "invalid" {
+			t.Fatal("invalid config was applied")
+		}
+	}
+}
+
+func TestService_StartsFromCache(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	srv := &server{config: "cached"}
+	ts := httptest.NewServer(srv)
+
+	// Populate the cache from a reachable endpoint.
+	rec := newRecorder()
+	svc, err := New(Options{URL: ts.URL, CacheDir: cacheDir, Validate: validate, Apply: rec.apply})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	rec.next(t)
+
+	// Restart while the endpoint is down.
+	ts.Close()
+
+	rec = newRecorder()
+	svc, err = New(Options{URL: ts.URL, CacheDir: cacheDir, Validate: validate, Apply: rec.apply})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.start(context.Background()); err != nil {
+		t.Fatalf("start with endpoint down: %v", err)
+	}
+	if got := rec.next(t); got != "cached" {
+		t.Errorf("config = %q, want cached", got)
+	}
+}
+
+func TestService_StartsFromCacheWhenInvalid(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	srv := &server{config: "cached"}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	rec := newRecorder()
+	svc, err := New(Options{URL: ts.URL, CacheDir: cacheDir, Validate: validate, Apply: rec.apply})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	rec.next(t)
+
+	// Restart while the endpoint serves configuration which doesn't
+	// validate.
+	srv.set("invalid")
+
+	var reported error
+	rec = newRecorder()
+	svc, err = New(Options{
+		URL:      ts.URL,
+		CacheDir: cacheDir,
+		Validate: validate,
+		Apply:    rec.apply,
+		OnError:  func(err error) { reported = err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.start(context.Background()); err != nil {
+		t.Fatalf("start with invalid config: %v", err)
+	}
+	if got := rec.next(t); got != "cached" {
+		t.Errorf("config = %q, want cached", got)
+	}
+	if reported == nil || !strings.Contains(reported.Error(), "config is invalid") {
+		t.Errorf("reported error = %v, want the validation error", reported)
+	}
+}
+
+func TestService_NotModifiedWithoutCache(t *testing.T) {
+	var ifNoneMatch []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer ts.Close()
+
+	svc, err := New(Options{
+		URL:      ts.URL,
+		CacheDir: t.TempDir(),
+		Validate: validate,
+		Apply: func([]byte) error {
+			t.Error("Apply called without a cached config")
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.start(context.Background()); err == nil {
+		t.Fatal("want error without cache, got nil")
+	}
+	if len(ifNoneMatch) != 1 || ifNoneMatch[0] != "" {
+		t.Errorf("If-None-Match = %q, want it unset", ifNoneMatch)
+	}
+}
+
+func TestService_NoEndpointNoCache(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	svc, err := New(Options{
+		URL:      ts.URL,
+		CacheDir: t.TempDir(),
+		Validate: validate,
+		Apply:    func([]byte) error { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Run(context.Background()); err == nil {
+		t.Fatal("want error without endpoint or cache, got nil")
+	}
+}
+
+func TestService_Timeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	svc, err := New(Options{
+		URL:      ts.URL,
+		CacheDir: t.TempDir(),
+		Timeout:  50 * time.Millisecond,
+		Validate: validate,
+		Apply:    func([]byte) error { return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := svc.Run(context.Background()); err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the request to time out quickly, took %s", elapsed)
+	}
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+// Engine is a Runner which runs in the background once started, so that it
+// can be stopped and replaced by one running a new configuration. It also
+// watches the modules its configuration imports, so that it can be
+// replaced when they change.
+type Engine struct {
+	*Runner
+
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+	watched chan struct{}
+	changed chan struct{}
+}
+
+// NewEngine creates an Engine which runs the components declared in f.
+func NewEngine(opts Options, f *ast.File) (*Engine, error) {
+	r := New(opts)
+	if err := r.Load(f); err != nil {
+		return nil, err
+	}
+	return &Engine{
+		Runner:  r,
+		done:    make(chan struct{}),
+		watched: make(chan struct{}),
+		changed: make(chan struct{}, 1),
+	}, nil
+}
+
+// Start runs the engine and watches its imported modules until ctx is
+// canceled or Stop is called. Imported modules are still watched after the
+// engine's components complete.
+func (e *Engine) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(e.watched)
+		e.watchImports(ctx, func() {
+			select {
+			case e.changed <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	go func() {
+		defer close(e.done)
+		e.err = e.Run(ctx)
+	}()
+}
+
+// Done returns a channel which is closed once the engine has exited.
+func (e *Engine) Done() <-chan struct{} { return e.done }
+
+// Err returns the result of the engine's Run method once Done is closed.
+func (e *Engine) Err() error {
+	<-e.done
+	return e.err
+}
+
+// Changed returns a channel which receives a value when a module imported
+// by the engine's configuration changes. The engine keeps running the
+// content it was started with until it is replaced.
+func (e *Engine) Changed() <-chan struct{} { return e.changed }
+
+// Stop stops the engine and waits for it to exit. It returns the result of
+// the engine's Run method.
+func (e *Engine) Stop() error {
+	e.cancel()
+	<-e.watched
+	return e.Err()
+}
@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jharvey10/test-repo/internal/livedebugging"
+	"github.com/jharvey10/test-repo/internal/supportbundle"
+	"github.com/jharvey10/test-repo/syntax"
+	"go.uber.org/zap"
+)
+
+// shutdownTimeout bounds how long in-flight requests are given to complete
+// when the HTTP server stops.
+const shutdownTimeout = 5 * time.Second
+
+// httpServer is the runner's HTTP server.
+type httpServer struct {
+	srv *http.Server
+	// cancel cancels the context of every request, which ends streaming
+	// responses such as live debugging.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startHTTP starts serving the runner's HTTP endpoints on addr.
+func (r *Runner) startHTTP(addr string) (*httpServer, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(livedebugging.HandlerPattern, r.debug)
+	mux.HandleFunc(supportbundle.HandlerPattern, r.handleSupportBundle)
+
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s := &httpServer{
+		srv: &http.Server{
+			Handler:     mux,
+			BaseContext: func(net.Listener) context.Context { return baseCtx },
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		if err := s.srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.logger.Error("HTTP server failed", zap.Error(err))
+		}
+	}()
+
+	r.logger.Info("HTTP server listening", zap.Stringer("addr", lis.Addr()))
+	return s, nil
+}
+
+// Shutdown stops the server, waiting up to shutdownTimeout for in-flight
+// requests to complete.
+func (s *httpServer) Shutdown(ctx context.Context) error {
+	s.cancel()
+
+	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+
+	err := s.srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = s.srv.Close()
+	}
+	<-s.done
+	return err
+}
+
+// handleSupportBundle serves a support bundle as a zip archive. The duration
+// query parameter sets how long the CPU is profiled for.
+func (r *Runner) handleSupportBundle(w http.ResponseWriter, req *http.Request) {
+	duration, err := supportbundle.ParseDuration(req.URL.Query().Get("duration"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	config, err := r.redactedConfig()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("rendering config: %v", err), http.StatusInternalServerError)
+		return
+	}
+	opts, err := json.MarshalIndent(r.opts, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Buffer the bundle so that a failure part way through can still be
+	// reported with an error status.
+	var buf bytes.Buffer
+	err = supportbundle.Write(req.Context(), &buf, supportbundle.Sources{
+		Config:             config,
+		Options:            opts,
+		Components:         r.Components(),
+		Logs:               r.logs.Bytes(),
+		Version:            syntax.Build().Version,
+		StartTime:          r.startTime,
+		CPUProfileDuration: duration,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("generating support bundle: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+supportbundle.DefaultFilename+`"`)
+	_, _ = buf.WriteTo(w)
+}
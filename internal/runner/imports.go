@@ -0,0 +1,219 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/internal/importsource"
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+// importedModule is a module imported by an import block.
+type importedModule struct {
+	id, name, label string
+	args            importsource.BlockArguments
+	poller          *importsource.Poller
+
+	// decls are the components declared in the module, and hash the hash
+	// of the files they were loaded from.
+	decls []declared
+	hash  [sha256.Size]byte
+
+	// declares are the components defined by the module's declare blocks,
+	// keyed by label, and instances their instances in the importing
+	// config.
+	declares  map[string]*config.Declare
+	instances []*moduleInstance
+
+	// exports holds the exports of the instances, keyed by declare label
+	// and then by instance label. It is the value of the import block's
+	// label in the scope of the importing config.
+	exports map[string]any
+}
+
+// moduleInstance is an instance of a component declared by a module.
+type moduleInstance struct {
+	id, name, label string
+	args            map[string]any // Arguments set by the instance
+	decls           []declared
+}
+
+// importModule fetches the module imported by block, whose decoded
+// arguments have the type of zero, and declares the components in it.
+// Identifiers in block are resolved against scope.
+func (r *Runner) importModule(block *ast.BlockStmt, zero importsource.BlockArguments, scope *vm.Scope) (*importedModule, error) {
+	m := &importedModule{
+		id:       block.GetBlockName() + "." + block.Label,
+		name:     block.GetBlockName(),
+		label:    block.Label,
+		declares: make(map[string]*config.Declare),
+		exports:  make(map[string]any),
+	}
+	args, err := r.decodeArgs(zero, block, "", scope)
+	if err != nil {
+		return nil, fmt.Errorf("decoding arguments of %s: %w", m.id, err)
+	}
+	m.args = args.(importsource.BlockArguments)
+	if m.poller, err = m.args.Poller(); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", block.NamePos, m.id, err)
+	}
+	// Keep git working copies with the runner's data rather than in a new
+	// temporary directory every time the config is loaded.
+	if git, ok := m.poller.Source.(*importsource.Git); ok && r.opts.StoragePath != "" {
+		git.Dir = filepath.Join(r.opts.StoragePath, m.id)
+	}
+
+	files, err := m.poller.Source.Fetch(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("importing %s: %w", m.id, err)
+	}
+	f, err := config.ParseFiles(files)
+	ds := diag.FromError(err)
+	ds.Merge(config.ValidateModule(f))
+	if ds.HasErrors() {
+		return nil, fmt.Errorf("importing %s: %w", m.id, ds)
+	}
+
+	moduleScope := syntax.RootScope()
+	for _, stmt := range f.Body {
+		block := stmt.(*ast.BlockStmt)
+		if _, ok := importsource.Blocks[block.GetBlockName()]; ok {
+			return nil, fmt.Errorf("importing %s: %s: modules can't import other modules", m.id, block.NamePos)
+		}
+		if block.GetBlockName() == "declare" {
+			// ValidateModule already reported any problems.
+			m.declares[block.Label], _ = config.ParseDeclare(block)
+			continue
+		}
+		d, err := r.declare(block, m.id, moduleScope)
+		if err != nil {
+			return nil, fmt.Errorf("importing %s: %w", m.id, err)
+		}
+		m.decls = append(m.decls, d)
+	}
+	m.hash = files.Hash()
+	return m, nil
+}
+
+// instantiate creates the instance of a component declared by m which
+// block declares. The arguments set by block are evaluated in scope, and
+// the instance's exports are added to m.exports.
+func (r *Runner) instantiate(block *ast.BlockStmt, m *importedModule, scope *vm.Scope) (*moduleInstance, error) {
+	inst := &moduleInstance{
+		id:    block.GetBlockName() + "." + block.Label,
+		name:  block.GetBlockName(),
+		label: block.Label,
+		args:  make(map[string]any),
+	}
+	d := m.declares[block.Name[1]]
+	if d == nil {
+		return nil, fmt.Errorf("%s: the module imported by %s doesn't declare %q", block.NamePos, m.id, block.Name[1])
+	}
+
+	arguments := make(map[string]config.Argument, len(d.Arguments))
+	for _, arg := range d.Arguments {
+		arguments[arg.Name] = arg
+	}
+	for _, stmt := range block.Body {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s only takes attributes", ast.StartPos(stmt), inst.id)
+		}
+		name := attr.Name.Name
+		if _, ok := arguments[name]; !ok {
+			return nil, fmt.Errorf("%s: unrecognized argument %q of %s", ast.StartPos(attr.Name), name, inst.id)
+		}
+		if _, set := inst.args[name]; set {
+			return nil, fmt.Errorf("%s: argument %q of %s set more than once", ast.StartPos(attr.Name), name, inst.id)
+		}
+		var val any
+		if err := vm.New(attr.Value).Evaluate(scope, &val); err != nil {
+			return nil, err
+		}
+		inst.args[name] = val
+	}
+
+	// Arguments are referenced as argument.<name>.value in the declare
+	// block.
+	vars := make(map[string]any, len(d.Arguments))
+	for _, arg := range d.Arguments {
+		val, set := inst.args[arg.Name]
+		if !set {
+			if !arg.Optional {
+				return nil, fmt.Errorf("%s: missing required argument %q of %s", block.NamePos, arg.Name, inst.id)
+			}
+			val = arg.Default
+		}
+		vars[arg.Name] = map[string]any{"value": val}
+	}
+	body := vm.NewScope(syntax.RootScope(), map[string]any{"argument": vars})
+
+	for _, c := range d.Components {
+		decl, err := r.declare(c, inst.id, body)
+		if err != nil {
+			return nil, fmt.Errorf("instantiating %s: %w", inst.id, err)
+		}
+		inst.decls = append(inst.decls, decl)
+	}
+
+	exports := make(map[string]any, len(d.Exports))
+	for _, e := range d.Exports {
+		var val any
+		if err := vm.New(e.Value).Evaluate(body, &val); err != nil {
+			return nil, fmt.Errorf("instantiating %s: evaluating export %q: %w", inst.id, e.Name, err)
+		}
+		exports[e.Name] = val
+	}
+	instances, _ := m.exports[d.Name].(map[string]any)
+	if instances == nil {
+		instances = make(map[string]any)
+		m.exports[d.Name] = instances
+	}
+	instances[block.Label] = exports
+
+	m.instances = append(m.instances, inst)
+	return inst, nil
+}
+
+// watchImports polls the imported modules until ctx is canceled, and calls
+// changed whenever one of them no longer matches the content which was
+// loaded.
+func (r *Runner) watchImports(ctx context.Context, changed func()) {
+	r.mut.RLock()
+	imports := r.imports
+	r.mut.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, m := range imports {
+		logger := r.logger.With(zap.String("module", m.id))
+		p := *m.poller
+		p.OnChange = func(files importsource.Files) error {
+			if files.Hash() != m.hash {
+				logger.Info("imported module changed")
+				changed()
+			}
+			return nil
+		}
+		p.OnError = func(err error) {
+			logger.Warn("failed to poll imported module", zap.Error(err))
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Run(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("failed to poll imported module, changes won't be reloaded", zap.Error(err))
+			}
+		}()
+	}
+	wg.Wait()
+}
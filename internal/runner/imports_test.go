@@ -0,0 +1,333 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jharvey10/test-repo/syntax/parser"
+)
+
+func TestLoad_ImportFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.alloy"), `test.args "a" {
+	value = "x"
+	token = "hunter2"
+}
+`)
+	writeFile(t, filepath.Join(dir, "b.alloy"), `test.undefined "b" { }`)
+
+	r := loadConfig(t, Options{}, fmt.Sprintf(`import.file "shared" { filename = %q }
+test.args "a" { value = "y" }
+`, dir))
+
+	var names []string
+	for _, c := range r.Components() {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	expect := []string{"import.file.shared/test.args.a", "import.file.shared/test.undefined.b", "test.args.a"}
+	if strings.Join(names, ",") != strings.Join(expect, ",") {
+		t.Errorf("expected components %v, got %v", expect, names)
+	}
+
+	config, err := r.redactedConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectConfig := fmt.Sprintf(`test.args "a" {
+	value = "y"
+}
+
+import.file "shared" {
+	filename = %q
+}
+
+// Components of the module imported by import.file.shared.
+test.args "a" {
+	value = "x"
+	token = "(secret)"
+}
+
+test.undefined "b" { }
+`, dir)
+	if string(config) != expectConfig {
+		t.Errorf("expected config:\n%s\ngot:\n%s", expectConfig, config)
+	}
+}
+
+func TestLoad_ImportDeclare(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "module.alloy")
+	writeFile(t, module, `declare "pipeline" {
+	argument "value" { }
+	argument "suffix" { default = "-default" }
+
+	test.args "a" {
+		value = argument.value.value + argument.suffix.value
+		token = "hunter2"
+	}
+
+	export "value" { value = argument.value.value + argument.suffix.value }
+}
+`)
+
+	r := loadConfig(t, Options{}, fmt.Sprintf(`import.file "shared" { filename = %q }
+
+shared.pipeline "x" { value = "x" }
+
+shared.pipeline "y" {
+	value  = "y"
+	suffix = "-" + shared.pipeline.x.value
+}
+
+test.args "a" { value = shared.pipeline.y.value }
+`, module))
+
+	values := make(map[string]string)
+	for _, c := range r.Components() {
+		comp, _ := r.Component(c.Name)
+		values[c.Name] = comp.(*testArgsComponent).args.Value
+	}
+	expect := map[string]string{
+		"shared.pipeline.x/test.args.a": "x-default",
+		"shared.pipeline.y/test.args.a": "y-x-default",
+		"test.args.a":                   "y-x-default",
+	}
+	if fmt.Sprint(values) != fmt.Sprint(expect) {
+		t.Errorf("expected component values %v, got %v", expect, values)
+	}
+
+	config, err := r.redactedConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectConfig := fmt.Sprintf(`test.args "a" {
+	value = "y-x-default"
+}
+
+import.file "shared" {
+	filename = %q
+}
+
+shared.pipeline "x" {
+	value = "x"
+}
+
+// Components of shared.pipeline.x.
+test.args "a" {
+	value = "x-default"
+	token = "(secret)"
+}
+
+shared.pipeline "y" {
+	suffix = "-x-default"
+	value  = "y"
+}
+
+// Components of shared.pipeline.y.
+test.args "a" {
+	value = "y-x-default"
+	token = "(secret)"
+}
+`, module)
+	if string(config) != expectConfig {
+		t.Errorf("expected config:\n%s\ngot:\n%s", expectConfig, config)
+	}
+}
+
+func TestLoad_ImportDeclareErrors(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "module.alloy")
+	writeFile(t, module, `declare "pipeline" {
+	argument "value" { }
+	argument "extra" { optional = true }
+
+	test.args "a" { value = argument.value.value }
+	test.args "b" { value = argument.extra.value }
+}
+`)
+	invalid := filepath.Join(t.TempDir(), "invalid.alloy")
+	writeFile(t, invalid, `declare "pipeline" {
+	argument "value" { }
+	export "value" { }
+}
+`)
+
+	tt := []struct {
+		module, config string
+		expect         string // Substring of the expected error.
+	}{
+		{module, `shared.other "a" { }`, `config.alloy:2:1: the module imported by import.file.shared doesn't declare "other"`},
+		{module, `shared.pipeline "a" { }`, `config.alloy:2:1: missing required argument "value" of shared.pipeline.a`},
+		{module, `shared.pipeline "a" { valeu = "x" }`, `config.alloy:2:23: unrecognized argument "valeu" of shared.pipeline.a`},
+		{module, `shared.pipeline "a" { value = 1 }`, `instantiating shared.pipeline.a: decoding arguments of shared.pipeline.a/test.args.a: module.alloy:5:26: expected string, got number`},
+		{module, `shared.pipeline "a" {
+	value = "x"
+	extra = "y"
+}
+test.args "b" { value = shared.pipeline.a.value }`, `decoding arguments of test.args.b: config.alloy:6:43: field "value" does not exist`},
+		{module, `shared.pipeline "a" {
+	value = "x"
+	nested { }
+}`, `config.alloy:4:2: shared.pipeline.a only takes attributes`},
+		{invalid, `shared.pipeline "a" { value = "x" }`, `importing import.file.shared: invalid.alloy:3:2: missing required attribute "value"`},
+	}
+	for _, tc := range tt {
+		f, err := parser.ParseFile("config.alloy", []byte(fmt.Sprintf("import.file \"shared\" { filename = %q }\n", tc.module)+tc.config))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := New(Options{})
+		err = r.Load(f)
+		switch {
+		case err == nil || !strings.Contains(err.Error(), tc.expect):
+			t.Errorf("%s: expected error containing %q, got %v", tc.config, tc.expect, err)
+		case len(r.Components()) != 0:
+			t.Errorf("%s: expected no components to be added, got %d", tc.config, len(r.Components()))
+		}
+	}
+}
+
+func TestLoad_ImportErrors(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "nested.alloy")
+	writeFile(t, nested, fmt.Sprintf(`import.file "again" { filename = %q }`, nested))
+	invalid := filepath.Join(dir, "invalid.alloy")
+	writeFile(t, invalid, `test.args "a" { value = 1 }`)
+
+	tt := []struct {
+		config string
+		expect string // Substring of the expected error.
+	}{
+		{fmt.Sprintf(`import.file "a" { filename = %q }`, nested), "modules can't import other modules"},
+		{fmt.Sprintf(`import.file "a" { filename = %q }`, invalid), "importing import.file.a: invalid.alloy:1:25: expected string, got number"},
+		{fmt.Sprintf(`import.file "a" { filename = %q }`, filepath.Join(dir, "missing.alloy")), "importing import.file.a: stat"},
+		{`import.file "a" { }`, `config.alloy:1:1: missing required attribute "filename"`},
+		{fmt.Sprintf(`import.file "a" {
+	filename       = %q
+	poll_frequency = "often"
+}`, invalid), `config.alloy:1:1: invalid poll_frequency "often"`},
+	}
+	for _, tc := range tt {
+		f, err := parser.ParseFile("config.alloy", []byte(tc.config))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := New(Options{})
+		err = r.Load(f)
+		switch {
+		case err == nil || !strings.Contains(err.Error(), tc.expect):
+			t.Errorf("%s: expected error containing %q, got %v", tc.config, tc.expect, err)
+		case len(r.Components()) != 0:
+			t.Errorf("%s: expected no components to be added, got %d", tc.config, len(r.Components()))
+		}
+	}
+}
+
+func TestLoad_ImportGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	bare, work := filepath.Join(t.TempDir(), "modules.git"), t.TempDir()
+	runGit(t, "", "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	runGit(t, "", "clone", "--quiet", bare, work)
+	writeFile(t, filepath.Join(work, "pipelines", "a.alloy"), `test.args "a" { value = "x" }`)
+	runGit(t, work, "add", ".")
+	runGit(t, work, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "add module")
+	runGit(t, work, "push", "--quiet", "origin", "HEAD:main")
+
+	storage := t.TempDir()
+	r := loadConfig(t, Options{StoragePath: storage}, fmt.Sprintf(`import.git "shared" {
+	repository = %q
+	revision   = "main"
+	path       = "pipelines"
+}
+`, bare))
+
+	if comps := r.Components(); len(comps) != 1 || comps[0].Name != "import.git.shared/test.args.a" {
+		t.Errorf("expected the module's component to be loaded, got %v", comps)
+	}
+	if _, err := os.Stat(filepath.Join(storage, "import.git.shared", "pipelines", "a.alloy")); err != nil {
+		t.Errorf("expected the working copy in the storage path: %v", err)
+	}
+}
+
+func TestEngine_ImportChanged(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "module.alloy")
+	writeFile(t, module, `test.args "a" { value = "x" }`)
+
+	f, err := parser.ParseFile("config.alloy", []byte(fmt.Sprintf(`import.file "shared" {
+	filename       = %q
+	poll_frequency = "10ms"
+}
+`, module)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng, err := NewEngine(Options{}, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng.Start(context.Background())
+	defer func() { _ = eng.Stop() }()
+
+	// The content which was loaded isn't a change, and modules are still
+	// polled after the components complete.
+	<-eng.Done()
+	select {
+	case <-eng.Changed():
+		t.Fatal("unexpected change before the module was modified")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeFile(t, module, `test.args "a" { value = "y" }`)
+	select {
+	case <-eng.Changed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the module change")
+	}
+}
+
+// loadConfig creates a Runner with opts and loads config into it.
+func loadConfig(t *testing.T, opts Options, config string) *Runner {
+	t.Helper()
+
+	f, err := parser.ParseFile("config.alloy", []byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(opts)
+	if err := r.Load(f); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
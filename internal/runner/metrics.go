@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+
+	"github.com/jharvey10/test-repo/internal/component"
+)
+
+// meterName is the name of the meter the runner's metrics are created by.
+const meterName = "github.com/jharvey10/test-repo/internal/runner"
+
+// metrics are the runner's metrics.
+type metrics struct {
+	meter      metric.Meter
+	components metric.Int64ObservableGauge
+	failures   metric.Int64Counter
+}
+
+// newMetrics creates the metrics of r with mp. Metrics which can't be
+// created are logged and not recorded.
+func newMetrics(mp metric.MeterProvider, r *Runner) *metrics {
+	if mp == nil {
+		mp = noop.NewMeterProvider()
+	}
+	m := &metrics{meter: mp.Meter(meterName)}
+
+	var err error
+	m.components, err = m.meter.Int64ObservableGauge("runner.components",
+		metric.WithDescription("Number of components managed by the runner, by health."),
+		metric.WithUnit("{component}"))
+	if err != nil {
+		r.logger.Warn("failed to create metric", zap.String("metric", "runner.components"), zap.Error(err))
+		m.components = noop.Int64ObservableGauge{}
+	}
+	m.failures, err = m.meter.Int64Counter("runner.component.failures",
+		metric.WithDescription("Number of times a component stopped with an error."),
+		metric.WithUnit("{failure}"))
+	if err != nil {
+		r.logger.Warn("failed to create metric", zap.String("metric", "runner.component.failures"), zap.Error(err))
+		m.failures = noop.Int64Counter{}
+	}
+	return m
+}
+
+// observe observes the components of r. The runner is replaced on reloads,
+// so observe must be unregistered once the runner stops.
+func (m *metrics) observe(r *Runner) (metric.Registration, error) {
+	return m.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		counts := make(map[component.HealthType]int64)
+		for _, info := range r.Components() {
+			counts[info.Health.Health]++
+		}
+		for _, ht := range []component.HealthType{
+			component.HealthTypeUnknown,
+			component.HealthTypeHealthy,
+			component.HealthTypeUnhealthy,
+			component.HealthTypeExited,
+		} {
+			o.ObserveInt64(m.components, counts[ht], metric.WithAttributes(attribute.String("health", ht.String())))
+		}
+		return nil
+	}, m.components)
+}
+
+// componentFailed records that the component name stopped with an error.
+func (m *metrics) componentFailed(name string) {
+	m.failures.Add(context.Background(), 1, metric.WithAttributes(attribute.String("component", name)))
+}
@@ -1,51 +1,566 @@
 package runner
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/jharvey10/test-repo/internal/component"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/internal/importsource"
+	"github.com/jharvey10/test-repo/internal/livedebugging"
+	"github.com/jharvey10/test-repo/internal/supportbundle"
 	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/printer"
+	"github.com/jharvey10/test-repo/syntax/vm"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
 )
 
+// Options configures a Runner.
+type Options struct {
+	// HTTPListenAddr is the address the HTTP server listens on. The HTTP
+	// server is disabled when HTTPListenAddr is empty.
+	HTTPListenAddr string `json:"http_listen_addr"`
+
+	// ConfigFile is the path of a configuration file declaring components
+	// to run in addition to the ones added with Add. Each declared
+	// component is built from its registration.
+	ConfigFile string `json:"config_file"`
+
+	// StoragePath is the directory where data which should persist across
+	// restarts is stored. It is created when the runner starts. Nothing is
+	// stored when StoragePath is empty.
+	StoragePath string `json:"storage_path"`
+
+	// MinStability is the minimum stability level of the components the
+	// runner runs. Defaults to generally available.
+	MinStability component.Stability `json:"min_stability"`
+
+	// LogLevel is the minimum level of the messages the runner logs.
+	LogLevel zapcore.Level `json:"log_level"`
+
+	// Logger receives the runner's logs. The runner logs to standard
+	// output when Logger is nil.
+	Logger *zap.Logger `json:"-"`
+
+	// MeterProvider creates the runner's metrics. Metrics are not recorded
+	// when MeterProvider is nil.
+	MeterProvider metric.MeterProvider `json:"-"`
+
+	// Sinks receives the data components produce. The data is dropped
+	// when Sinks is nil, as components can't be connected to each other
+	// yet.
+	Sinks Sinks `json:"-"`
+
+	// HTTPClient, if set, replaces the default client for the outgoing
+	// HTTP requests of the components loaded from config.
+	HTTPClient *http.Client `json:"-"`
+
+	// ConfigCache, if set, decodes the arguments of the components passed
+	// to Load, which must come from its latest ParseFiles call. Reloading
+	// a configuration through the same cache only decodes the components
+	// which changed.
+	ConfigCache *config.Cache `json:"-"`
+}
+
+// Sinks receives the data components produce.
+type Sinks interface {
+	// Appender returns where the metric samples the component id produces
+	// are sent.
+	Appender(id string) component.Appender
+
+	// LogReceiver returns where the log entries the component id produces
+	// are sent.
+	LogReceiver(id string) component.LogReceiver
+}
+
 // Runner manages the lifecycle of components.
 type Runner struct {
-	components []component.Component
+	opts Options
+
+	mut     sync.RWMutex
+	nodes   []*componentNode
+	decls   []declared        // Components loaded from config
+	imports []*importedModule // Modules imported by config
+
+	debug     *livedebugging.Hub
+	logs      *supportbundle.LogBuffer
+	logger    *zap.Logger
+	metrics   *metrics
+	startTime time.Time
+}
+
+// componentNode tracks a component and the health of its Run method.
+type componentNode struct {
+	component.Component
+
+	mut    sync.RWMutex
+	health component.Health
+}
+
+func (n *componentNode) setHealth(ht component.HealthType, msg string) {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+	n.health = component.Health{Health: ht, Message: msg, UpdateTime: time.Now()}
 }
 
+// CurrentHealth returns the health of the component, combining the health
+// of its Run method with the health the component reports itself.
+func (n *componentNode) CurrentHealth() component.Health {
+	n.mut.RLock()
+	h := n.health
+	n.mut.RUnlock()
+
+	if hc, ok := n.Component.(component.HealthComponent); ok && h.Health == component.HealthTypeHealthy {
+		return component.LeastHealthy(h, hc.CurrentHealth())
+	}
+	return h
+}
+
+// ComponentInfo describes a component managed by the Runner.
+type ComponentInfo = supportbundle.ComponentInfo
+
 // New creates a new Runner instance.
-func New() *Runner {
-	return &Runner{
-		components: make([]component.Component, 0),
+func New(opts Options) *Runner {
+	logs := supportbundle.NewLogBuffer(0)
+	r := &Runner{
+		opts:   opts,
+		nodes:  make([]*componentNode, 0),
+		debug:  livedebugging.NewHub(),
+		logs:   logs,
+		logger: newLogger(opts, logs),
 	}
+	r.metrics = newMetrics(opts.MeterProvider, r)
+	return r
+}
+
+// NewLogger creates a logger which logs messages at level or above to
+// standard output, in the same format as a Runner without Options.Logger.
+func NewLogger(level zapcore.Level) *zap.Logger {
+	return zap.New(zapcore.NewCore(newEncoder(), zapcore.Lock(os.Stdout), level))
+}
+
+// newLogger creates the runner's logger. It logs to opts.Logger, or to
+// standard output, as well as to logs for support bundles.
+func newLogger(opts Options, logs io.Writer) *zap.Logger {
+	encoder := newEncoder()
+	core := zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), opts.LogLevel)
+	if opts.Logger != nil {
+		// The provided logger may already be stricter than LogLevel, in
+		// which case it is used as is.
+		core = opts.Logger.Core()
+		if filtered, err := zapcore.NewIncreaseLevelCore(core, opts.LogLevel); err == nil {
+			core = filtered
+		}
+	}
+	return zap.New(zapcore.NewTee(core, zapcore.NewCore(encoder, zapcore.AddSync(logs), opts.LogLevel)))
+}
+
+func newEncoder() zapcore.Encoder {
+	return zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		MessageKey:     "msg",
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
 }
 
 // Add registers a component with the runner. Wow it's a fix.
 func (r *Runner) Add(c component.Component) {
-	r.components = append(r.components, c)
+	if ld, ok := c.(component.LiveDebuggable); ok {
+		ld.SetDebugPublisher(r.debug.Publisher(c.Name()))
+	}
+	if mp, ok := c.(component.MetricsProducer); ok && r.opts.Sinks != nil {
+		mp.SetAppender(r.opts.Sinks.Appender(c.Name()))
+	}
+	if lp, ok := c.(component.LogsProducer); ok && r.opts.Sinks != nil {
+		lp.SetLogReceiver(r.opts.Sinks.LogReceiver(c.Name()))
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.nodes = append(r.nodes, &componentNode{Component: c})
+}
+
+// load adds the components declared in the configuration file filename.
+func (r *Runner) load(filename string) error {
+	f, err := ReadConfig(filename)
+	if err != nil {
+		return err
+	}
+	return r.addFile(f)
+}
+
+// ReadConfig reads and parses the configuration file filename, and checks
+// it with config.ValidateFile.
+func ReadConfig(filename string) (*ast.File, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	f, err := config.Parse(filename, src)
+	ds := diag.FromError(err)
+	ds.Merge(config.ValidateFile(f))
+	if ds.HasErrors() {
+		return nil, fmt.Errorf("loading config file: %w", ds)
+	}
+	return f, nil
+}
+
+// Load adds the components declared in the parsed configuration f, which
+// must pass config.ValidateFile. It is an alternative to
+// Options.ConfigFile for configuration which doesn't come from a single
+// file, and must be called before Run.
+func (r *Runner) Load(f *ast.File) error {
+	validate := config.ValidateFile
+	if r.opts.ConfigCache != nil {
+		validate = r.opts.ConfigCache.ValidateFile
+	}
+	if ds := validate(f); ds.HasErrors() {
+		return fmt.Errorf("loading config: %w", ds)
+	}
+	return r.addFile(f)
+}
+
+// addFile builds the components declared in f, and in the modules it
+// imports, from their arguments and adds them. Nothing is added if any
+// component can't be built.
+//
+// Modules are imported first, so that the components they declare can be
+// instantiated anywhere in f. The exports of an instance can be referenced
+// by the blocks which follow it.
+func (r *Runner) addFile(f *ast.File) error {
+	var (
+		decls   = make([]declared, 0, len(f.Body))
+		imports []*importedModule
+		modules = make(map[string]*importedModule) // Keyed by label
+		exports = make(map[string]any)
+		scope   = vm.NewScope(syntax.RootScope(), exports)
+	)
+	for _, stmt := range f.Body {
+		block := stmt.(*ast.BlockStmt)
+		args, ok := importsource.Blocks[block.GetBlockName()]
+		if !ok {
+			continue
+		}
+		m, err := r.importModule(block, args, scope)
+		if err != nil {
+			return err
+		}
+		imports = append(imports, m)
+		modules[m.label] = m
+		exports[m.label] = m.exports
+		decls = append(decls, m.decls...)
+	}
+
+	for _, stmt := range f.Body {
+		// ValidateFile rejects anything other than registered components,
+		// import blocks and instances of the components modules declare.
+		block := stmt.(*ast.BlockStmt)
+		name := block.GetBlockName()
+		if _, ok := importsource.Blocks[name]; ok {
+			continue
+		}
+		if _, ok := component.Get(name); !ok {
+			inst, err := r.instantiate(block, modules[block.Name[0]], scope)
+			if err != nil {
+				return err
+			}
+			decls = append(decls, inst.decls...)
+			continue
+		}
+
+		d, err := r.declare(block, "", scope)
+		if err != nil {
+			return err
+		}
+		decls = append(decls, d)
+	}
+
+	comps := make([]component.Component, 0, len(decls))
+	for _, d := range decls {
+		c, err := d.reg.Build(d.opts, d.args)
+		if err != nil {
+			return fmt.Errorf("building %s: %w", d.opts.ID, err)
+		}
+		comps = append(comps, c)
+	}
+	for _, c := range comps {
+		r.Add(c)
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.decls = append(r.decls, decls...)
+	r.imports = append(r.imports, imports...)
+	return nil
+}
+
+// declare decodes the arguments of the component declared by block,
+// resolving identifiers against scope. The component's ID is prefixed with
+// the ID of the import block or module component it comes from, if any.
+func (r *Runner) declare(block *ast.BlockStmt, module string, scope *vm.Scope) (declared, error) {
+	minStability := r.opts.MinStability
+	if minStability == component.StabilityUndefined {
+		minStability = component.StabilityGenerallyAvailable
+	}
+
+	reg, _ := component.Get(block.GetBlockName())
+	if !minStability.Permits(reg.Stability) {
+		return declared{}, fmt.Errorf("%s: component %q is %s, which is below the minimum stability level %s; set --stability.level=%s to use it",
+			block.NamePos, reg.Name, reg.Stability, minStability, reg.Stability)
+	}
+
+	id := reg.Name + "." + block.Label
+	if module != "" {
+		id = module + "/" + id
+	}
+	args, err := r.decodeArgs(reg.Args, block, module, scope)
+	if err != nil {
+		return declared{}, fmt.Errorf("decoding arguments of %s: %w", id, err)
+	}
+	r.logger.Debug("loaded component", zap.String("component", id), zap.Any("arguments", args))
+	return declared{
+		opts: component.Options{
+			ID:         id,
+			Logger:     r.logger.With(zap.String("component", id)),
+			HTTPClient: r.opts.HTTPClient,
+		},
+		reg:   reg,
+		label: block.Label,
+		args:  args,
+	}, nil
+}
+
+// decodeArgs decodes the arguments of block in scope, through
+// Options.ConfigCache unless block comes from an imported module.
+func (r *Runner) decodeArgs(zero any, block *ast.BlockStmt, module string, scope *vm.Scope) (any, error) {
+	if r.opts.ConfigCache != nil && module == "" {
+		return r.opts.ConfigCache.EvaluateArgs(scope, zero, block)
+	}
+	return config.EvaluateArgs(scope, zero, block)
+}
+
+// declared is a component declared in config.
+type declared struct {
+	opts  component.Options
+	reg   component.Registration
+	label string
+	args  any // Decoded arguments, nil for components without any
+}
+
+// redactedConfig renders the components loaded from config, followed by
+// the import blocks, the components of the modules they import and the
+// instances of the components the modules declare. They are rendered from
+// their decoded arguments rather than the original source, so that
+// alloytypes.Secret arguments are redacted.
+func (r *Runner) redactedConfig() ([]byte, error) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	imported := make(map[string]bool)
+	for _, m := range r.imports {
+		for _, d := range m.decls {
+			imported[d.opts.ID] = true
+		}
+		for _, inst := range m.instances {
+			for _, d := range inst.decls {
+				imported[d.opts.ID] = true
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	writeBlock := func(comment, id, name, label string, args any) error {
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		if comment != "" {
+			buf.WriteString("// " + comment + "\n")
+		}
+		fmt.Fprintf(&buf, "%s %q {\n", name, label)
+		if args != nil {
+			body, err := marshalBody(args)
+			if err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+			buf.Write(body)
+		}
+		buf.WriteString("}\n")
+		return nil
+	}
+	writeDecls := func(comment string, decls []declared) error {
+		for i, d := range decls {
+			if i > 0 {
+				comment = ""
+			}
+			if err := writeBlock(comment, d.opts.ID, d.reg.Name, d.label, d.args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, d := range r.decls {
+		if imported[d.opts.ID] {
+			continue
+		}
+		if err := writeBlock("", d.opts.ID, d.reg.Name, d.label, d.args); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range r.imports {
+		if err := writeBlock("", m.id, m.name, m.label, m.args); err != nil {
+			return nil, err
+		}
+		if err := writeDecls(fmt.Sprintf("Components of the module imported by %s.", m.id), m.decls); err != nil {
+			return nil, err
+		}
+		for _, inst := range m.instances {
+			if err := writeBlock("", inst.id, inst.name, inst.label, inst.args); err != nil {
+				return nil, err
+			}
+			if err := writeDecls(fmt.Sprintf("Components of %s.", inst.id), inst.decls); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return printer.Format("config.alloy", buf.Bytes())
+}
+
+// marshalBody returns the body of a block with the decoded arguments args.
+// The arguments of module components are a map of attribute values.
+func marshalBody(args any) ([]byte, error) {
+	attrs, ok := args.(map[string]any)
+	if !ok {
+		return syntax.Marshal(args)
+	}
+
+	var buf bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(attrs)) {
+		val, err := syntax.MarshalValue(attrs[name])
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "%s = %s\n", name, val)
+	}
+	return buf.Bytes(), nil
+}
+
+// Components returns the components managed by the Runner and their current
+// health.
+func (r *Runner) Components() []ComponentInfo {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	infos := make([]ComponentInfo, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		infos = append(infos, ComponentInfo{Name: n.Name(), Health: n.CurrentHealth()})
+	}
+	return infos
+}
+
+// Component returns the component called id, or false if the Runner
+// doesn't manage one.
+func (r *Runner) Component(id string) (component.Component, bool) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	for _, n := range r.nodes {
+		if n.Name() == id {
+			return n.Component, true
+		}
+	}
+	return nil, false
 }
 
 // Run starts all registered components concurrently using errgroup.
-func (r *Runner) Run() error {
-	fmt.Printf("Runner started with %d registered component types\n", len(component.All()))
+//
+// If the HTTP server is enabled, Run keeps serving it after the components
+// complete, until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) error {
+	r.startTime = time.Now()
+	r.logger.Info("runner started", zap.Int("registered_component_types", len(component.All())))
 
-	syntax.Main()
+	if r.opts.ConfigFile != "" {
+		if err := r.load(r.opts.ConfigFile); err != nil {
+			return err
+		}
+	}
+	if r.opts.StoragePath != "" {
+		if err := os.MkdirAll(r.opts.StoragePath, 0o770); err != nil {
+			return fmt.Errorf("creating storage directory: %w", err)
+		}
+	}
+
+	for _, msg := range syntax.Greetings() {
+		r.logger.Info(msg)
+	}
 
-	g, _ := errgroup.WithContext(context.Background())
+	if reg, err := r.metrics.observe(r); err != nil {
+		r.logger.Warn("failed to observe component metrics", zap.Error(err))
+	} else {
+		defer func() { _ = reg.Unregister() }()
+	}
+
+	var srv *httpServer
+	if r.opts.HTTPListenAddr != "" {
+		var err error
+		if srv, err = r.startHTTP(r.opts.HTTPListenAddr); err != nil {
+			return fmt.Errorf("starting HTTP server: %w", err)
+		}
+	}
 
-	for _, c := range r.components {
-		c := c // capture for goroutine
+	g, _ := errgroup.WithContext(ctx)
+
+	r.mut.RLock()
+	nodes := r.nodes
+	r.mut.RUnlock()
+
+	for _, n := range nodes {
+		n := n // capture for goroutine
 		g.Go(func() error {
-			fmt.Printf("Running component: %s\n", c.Name())
-			return c.Run()
+			r.logger.Info("running component", zap.String("component", n.Name()))
+			n.setHealth(component.HealthTypeHealthy, "started component")
+
+			if err := n.Run(); err != nil {
+				r.logger.Error("component failed", zap.String("component", n.Name()), zap.Error(err))
+				r.metrics.componentFailed(n.Name())
+				n.setHealth(component.HealthTypeUnhealthy, err.Error())
+				return err
+			}
+			n.setHealth(component.HealthTypeExited, "component exited")
+			return nil
 		})
 	}
 
 	if err := g.Wait(); err != nil {
+		if srv != nil {
+			_ = srv.Shutdown(context.Background())
+		}
 		return fmt.Errorf("component failed: %w", err)
 	}
 
-	fmt.Println("All components completed successfully 🎉")
-	return nil
+	r.logger.Info("all components completed successfully 🎉")
+
+	if srv == nil {
+		return nil
+	}
+	<-ctx.Done()
+	return srv.Shutdown(context.Background())
 }
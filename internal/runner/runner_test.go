@@ -0,0 +1,454 @@
+package runner
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/jharvey10/test-repo/internal/component"
+	_ "github.com/jharvey10/test-repo/internal/component/prometheus"
+	"github.com/jharvey10/test-repo/internal/config"
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/alloytypes"
+	"github.com/jharvey10/test-repo/syntax/parser"
+)
+
+type testComponent struct {
+	name string
+	err  error
+}
+
+func (c *testComponent) Run() error   { return c.err }
+func (c *testComponent) Name() string { return c.name }
+
+// testArgs are the arguments of test.args.
+type testArgs struct {
+	Value string            `syntax:"value,attr"`
+	Token alloytypes.Secret `syntax:"token,attr,optional"`
+}
+
+// testArgsComponent records the arguments it is built with.
+type testArgsComponent struct {
+	testComponent
+	args testArgs
+}
+
+func init() {
+	for _, reg := range []component.Registration{
+		{Name: "test.experimental", Stability: component.StabilityExperimental},
+		{Name: "test.preview", Stability: component.StabilityPublicPreview},
+		{Name: "test.undefined"},
+	} {
+		reg.Build = func(opts component.Options, _ any) (component.Component, error) {
+			return &testComponent{name: opts.ID}, nil
+		}
+		component.Register(reg)
+	}
+
+	component.Register(component.Registration{
+		Name: "test.args",
+		Args: testArgs{},
+		Build: func(opts component.Options, args any) (component.Component, error) {
+			if args.(testArgs).Value == "invalid" {
+				return nil, errors.New("invalid value")
+			}
+			return &testArgsComponent{testComponent: testComponent{name: opts.ID}, args: args.(testArgs)}, nil
+		},
+	})
+}
+
+func TestLoad_Args(t *testing.T) {
+	f, err := parser.ParseFile("config.alloy", []byte(`test.args "a" { value = "x" }
+test.args "b" { value = string.join(["y", "z"], "") }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(Options{})
+	if err := r.Load(f); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{"test.args.a": "x", "test.args.b": "yz"}
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	if len(r.nodes) != len(expect) {
+		t.Fatalf("expected %d components, got %d", len(expect), len(r.nodes))
+	}
+	for _, n := range r.nodes {
+		c := n.Component.(*testArgsComponent)
+		if value, ok := expect[c.Name()]; !ok || c.args.Value != value {
+			t.Errorf("unexpected component %s with value %q", c.Name(), c.args.Value)
+		}
+	}
+}
+
+func TestLoad_InvalidArgs(t *testing.T) {
+	tt := []struct {
+		config string
+		expect string // Substring of the expected error.
+	}{
+		{`test.args "a" { }`, `config.alloy:2:1: missing required attribute "value"`},
+		{`test.args "a" { value = 1 }`, `config.alloy:2:25: expected string, got number`},
+		{`test.undefined "a" { value = "x" }`, `config.alloy:2:22: unrecognized attribute name "value"`},
+		{`test.args "a" { value = "invalid" }`, `building test.args.a: invalid value`},
+	}
+	for _, tc := range tt {
+		f, err := parser.ParseFile("config.alloy", []byte("test.undefined \"ok\" {}\n"+tc.config))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := New(Options{})
+		err = r.Load(f)
+		switch {
+		case err == nil || !strings.Contains(err.Error(), tc.expect):
+			t.Errorf("%s: expected error containing %q, got %v", tc.config, tc.expect, err)
+		case len(r.Components()) != 0:
+			t.Errorf("%s: expected no components to be added, got %d", tc.config, len(r.Components()))
+		}
+	}
+}
+
+func TestRedactedConfig(t *testing.T) {
+	f, err := parser.ParseFile("config.alloy", []byte(`test.args "a" {
+	value = "x"
+	token = "hunter2"
+}
+test.undefined "b" {}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(Options{})
+	if err := r.Load(f); err != nil {
+		t.Fatal(err)
+	}
+	config, err := r.redactedConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `test.args "a" {
+	value = "x"
+	token = "(secret)"
+}
+
+test.undefined "b" { }
+`
+	if string(config) != expect {
+		t.Errorf("expected config:\n%s\ngot:\n%s", expect, config)
+	}
+}
+
+func TestLoad_Stability(t *testing.T) {
+	tt := []struct {
+		component string
+		min       component.Stability
+		expect    string // Substring of the expected error, if any.
+	}{
+		{"test.undefined", component.StabilityUndefined, ""},
+		{"test.preview", component.StabilityUndefined, `component "test.preview" is public-preview, which is below the minimum stability level generally-available; set --stability.level=public-preview to use it`},
+		{"test.preview", component.StabilityPublicPreview, ""},
+		{"test.experimental", component.StabilityPublicPreview, "below the minimum stability level public-preview"},
+		{"test.experimental", component.StabilityExperimental, ""},
+	}
+	for _, tc := range tt {
+		f, err := parser.ParseFile("config.alloy", []byte("test.undefined \"a\" {}\n"+tc.component+" \"b\" {}\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := New(Options{MinStability: tc.min})
+		err = r.Load(f)
+		switch {
+		case tc.expect == "" && err != nil:
+			t.Errorf("%s at %s: unexpected error: %v", tc.component, tc.min, err)
+		case tc.expect == "" && len(r.Components()) != 2:
+			t.Errorf("%s at %s: expected 2 components, got %d", tc.component, tc.min, len(r.Components()))
+		case tc.expect != "" && (err == nil || !strings.Contains(err.Error(), tc.expect)):
+			t.Errorf("%s at %s: expected error containing %q, got %v", tc.component, tc.min, tc.expect, err)
+		case tc.expect != "" && len(r.Components()) != 0:
+			t.Errorf("%s at %s: expected no components to be added, got %d", tc.component, tc.min, len(r.Components()))
+		}
+	}
+}
+
+// collectInt64 collects the metric name from reader and returns the value
+// of each data point, keyed by the value of attribute key.
+func collectInt64(t *testing.T, reader sdkmetric.Reader, name string, key attribute.Key) map[string]int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			var points []metricdata.DataPoint[int64]
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				points = data.DataPoints
+			case metricdata.Gauge[int64]:
+				points = data.DataPoints
+			}
+			for _, dp := range points {
+				v, _ := dp.Attributes.Value(key)
+				values[v.AsString()] = dp.Value
+			}
+		}
+	}
+	return values
+}
+
+func TestRun_Telemetry(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	reader := sdkmetric.NewManualReader()
+
+	r := New(Options{
+		Logger:        zap.New(core),
+		MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	r.Add(&testComponent{name: "test.ok"})
+	r.Add(&testComponent{name: "test.failing", err: errors.New("oops")})
+	if err := r.Run(context.Background()); err == nil {
+		t.Fatal("expected the failing component to make Run fail")
+	}
+
+	failed := logs.FilterMessage("component failed").All()
+	if len(failed) != 1 || failed[0].ContextMap()["component"] != "test.failing" || failed[0].ContextMap()["error"] != "oops" {
+		t.Errorf("expected a log line for the failed component, got %v", failed)
+	}
+	if n := logs.FilterMessage("running component").Len(); n != 2 {
+		t.Errorf("expected 2 running component log lines, got %d", n)
+	}
+	if !strings.Contains(string(r.logs.Bytes()), "component failed") {
+		t.Error("expected the logs to be kept for support bundles")
+	}
+
+	failures := collectInt64(t, reader, "runner.component.failures", "component")
+	if len(failures) != 1 || failures["test.failing"] != 1 {
+		t.Errorf("expected 1 failure of test.failing, got %v", failures)
+	}
+	if components := collectInt64(t, reader, "runner.components", "health"); len(components) != 0 {
+		t.Errorf("expected components not to be observed once Run returns, got %v", components)
+	}
+}
+
+func TestRun_ComponentsMetric(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	reader := sdkmetric.NewManualReader()
+
+	r := New(Options{
+		HTTPListenAddr: "127.0.0.1:0",
+		Logger:         zap.New(core),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	r.Add(&testComponent{name: "test.ok"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	// The runner keeps serving HTTP after its components complete.
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage("all components completed successfully 🎉").Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("components didn't complete, got logs %v", logs.All())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	expect := map[string]int64{"unknown": 0, "healthy": 0, "unhealthy": 0, "exited": 1}
+	components := collectInt64(t, reader, "runner.components", "health")
+	if len(components) != len(expect) {
+		t.Errorf("expected components %v, got %v", expect, components)
+	}
+	for health, n := range expect {
+		if components[health] != n {
+			t.Errorf("expected %d %s components, got %d", n, health, components[health])
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun_LogLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	r := New(Options{Logger: zap.New(core), LogLevel: zapcore.WarnLevel})
+	r.Add(&testComponent{name: "test.ok"})
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := logs.Len(); n != 0 {
+		t.Errorf("expected info logs to be filtered out, got %v", logs.All())
+	}
+}
+
+func TestRun_ComponentLogs(t *testing.T) {
+	f, err := parser.ParseFile("config.alloy", []byte(`prometheus.scrape "a" {
+	targets = [{"__address__" = "a:9090"}, {"__address__" = "b:9090"}]
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	r := New(Options{Logger: zap.New(core)})
+	if err := r.Load(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	started := logs.FilterMessage("starting scraper").All()
+	if len(started) != 1 {
+		t.Fatalf("expected the scraper to log through the runner's logger, got %v", logs.All())
+	}
+	if fields := started[0].ContextMap(); fields["component"] != "prometheus.scrape.a" || fields["targets"] != int64(2) {
+		t.Errorf("expected the scraper to start with 2 targets, got %v", fields)
+	}
+	for _, msg := range syntax.Greetings() {
+		if logs.FilterMessage(msg).Len() != 1 {
+			t.Errorf("expected %q to be logged", msg)
+		}
+	}
+}
+
+func TestRun_RedactsSecrets(t *testing.T) {
+	const token = "hunter2"
+	f, err := parser.ParseFile("config.alloy", []byte(`prometheus.scrape "a" {
+	targets      = [{"__address__" = "a:9090"}]
+	bearer_token = "`+token+`"
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&out), zapcore.DebugLevel))
+	r := New(Options{Logger: logger, LogLevel: zapcore.DebugLevel})
+	if err := r.Load(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), `"loaded component"`) {
+		t.Fatalf("expected the component arguments to be logged, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), token) {
+		t.Errorf("secret leaked into the logs:\n%s", out.String())
+	}
+
+	rec := httptest.NewRecorder()
+	r.handleSupportBundle(rec, httptest.NewRequest(http.MethodGet, "/-/support?duration=10ms", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bb, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(bb, []byte(token)) {
+			t.Errorf("secret leaked into %s of the support bundle", zf.Name)
+		}
+		if zf.Name == "config.alloy" && !bytes.Contains(bb, []byte(`bearer_token = "(secret)"`)) {
+			t.Errorf("expected the redacted token in config.alloy, got:\n%s", bb)
+		}
+		if zf.Name == "logs.txt" && !bytes.Contains(bb, []byte("loaded component")) {
+			t.Errorf("expected the component arguments in logs.txt, got:\n%s", bb)
+		}
+	}
+}
+
+// BenchmarkCache_Reload_10kBlocks reloads a config with 10k components
+// where one component changes between versions, parsing and decoding it
+// through a config.Cache as the base engine does.
+func BenchmarkCache_Reload_10kBlocks(b *testing.B) {
+	benchmarkReload(b, true)
+}
+
+// BenchmarkReload_10kBlocks reloads the same configs as
+// BenchmarkCache_Reload_10kBlocks, parsing and decoding them in full.
+func BenchmarkReload_10kBlocks(b *testing.B) {
+	benchmarkReload(b, false)
+}
+
+func benchmarkReload(b *testing.B, cached bool) {
+	const n = 10000
+
+	versions := make([]map[string][]byte, 2)
+	for v := range versions {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			target := fmt.Sprintf("tenant-%d:9090", i)
+			if i == n/2 {
+				target = fmt.Sprintf("changed-%d:9090", v)
+			}
+			fmt.Fprintf(&sb, "prometheus.scrape \"tenant_%d\" {\n\ttargets  = [{\"__address__\" = %q}]\n\tjob_name = \"tenant\"\n}\n\n", i, target)
+		}
+		versions[v] = map[string][]byte{"config.alloy": []byte(sb.String())}
+	}
+
+	opts := Options{Logger: zap.NewNop()}
+	parse := config.ParseFiles
+	if cached {
+		opts.ConfigCache = config.NewCache()
+		parse = opts.ConfigCache.ParseFiles
+	}
+
+	i := 0
+	for b.Loop() {
+		i++
+		f, err := parse(versions[i%2])
+		if err != nil {
+			b.Fatal(err)
+		}
+		eng, err := NewEngine(opts, f)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(eng.Components()) != n {
+			b.Fatalf("expected %d components, got %d", n, len(eng.Components()))
+		}
+	}
+}
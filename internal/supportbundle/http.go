@@ -0,0 +1,69 @@
+package supportbundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// HandlerPattern is the route support bundles are served on by the
+	// runner's HTTP server.
+	HandlerPattern = "GET /-/support"
+
+	// DefaultFilename is the suggested file name for a support bundle.
+	DefaultFilename = "alloy-support-bundle.zip"
+
+	// MaxCPUProfileDuration is the longest CPU profile a bundle may request.
+	MaxCPUProfileDuration = 5 * time.Minute
+)
+
+// ParseDuration parses the CPU profile duration of a support bundle request.
+// An empty string returns DefaultCPUProfileDuration.
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return DefaultCPUProfileDuration, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	if d <= 0 || d > MaxCPUProfileDuration {
+		return 0, fmt.Errorf("duration must be positive and at most %s", MaxCPUProfileDuration)
+	}
+	return d, nil
+}
+
+// Fetch downloads a support bundle from the runner's HTTP server at addr and
+// writes it to out.
+func Fetch(ctx context.Context, addr string, duration time.Duration, out io.Writer) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("invalid server address %q: %w", addr, err)
+	}
+	u = u.JoinPath("/-/support")
+	if duration > 0 {
+		u.RawQuery = url.Values{"duration": {duration.String()}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
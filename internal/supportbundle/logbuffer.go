@@ -0,0 +1,42 @@
+package supportbundle
+
+import "sync"
+
+// DefaultLogBufferSize is the number of bytes of log output kept by a
+// LogBuffer created with a non-positive size.
+const DefaultLogBufferSize = 1 << 20
+
+// LogBuffer is an io.Writer which keeps the most recent log output, up to a
+// fixed number of bytes. It is safe for concurrent use.
+type LogBuffer struct {
+	mut  sync.Mutex
+	size int
+	buf  []byte
+}
+
+// NewLogBuffer creates a LogBuffer which keeps the last size bytes written.
+func NewLogBuffer(size int) *LogBuffer {
+	if size <= 0 {
+		size = DefaultLogBufferSize
+	}
+	return &LogBuffer{size: size}
+}
+
+// Write implements io.Writer. It never fails.
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns a copy of the buffered log output.
+func (b *LogBuffer) Bytes() []byte {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return append([]byte(nil), b.buf...)
+}
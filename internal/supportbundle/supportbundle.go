@@ -0,0 +1,185 @@
+// Package supportbundle generates zip archives with the information needed
+// to debug a running instance: its redacted configuration, component
+// health, recent logs, profiles, build information and runtime environment.
+package supportbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/jharvey10/test-repo/internal/component"
+)
+
+// DefaultCPUProfileDuration is the CPU profile duration used when
+// Sources.CPUProfileDuration is not set.
+const DefaultCPUProfileDuration = 5 * time.Second
+
+// ComponentInfo describes a component in the bundle.
+type ComponentInfo struct {
+	Name   string           `json:"name"`
+	Health component.Health `json:"health"`
+}
+
+// Sources is the information collected into a bundle.
+type Sources struct {
+	// Config is the running configuration. It must already be redacted.
+	Config []byte
+	// Options holds the options the instance was started with, encoded as
+	// JSON.
+	Options []byte
+	// Components lists the running components and their health.
+	Components []ComponentInfo
+	// Logs holds the most recent log output.
+	Logs []byte
+	// Version is the version of the running instance.
+	Version string
+	// StartTime is when the instance started.
+	StartTime time.Time
+	// CPUProfileDuration is how long the CPU is profiled for.
+	CPUProfileDuration time.Duration
+}
+
+// Write writes a support bundle built from src to w as a zip archive. Write
+// blocks for the duration of the CPU profile, or until ctx is canceled.
+func Write(ctx context.Context, w io.Writer, src Sources) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"config.alloy", writeBytes(src.Config)},
+		{"options.json", writeBytes(src.Options)},
+		{"components.json", writeJSON(src.Components)},
+		{"logs.txt", writeBytes(src.Logs)},
+		{"build-info.json", writeJSON(buildInfo(src.Version))},
+		{"runtime.json", writeJSON(runtimeInfo(src.StartTime))},
+		{"pprof/heap.pb.gz", writeProfile("heap")},
+		{"pprof/goroutine.txt", writeGoroutines},
+		{"pprof/cpu.pb.gz", writeCPUProfile(ctx, src.CPUProfileDuration)},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if err := f.write(fw); err != nil {
+			return fmt.Errorf("writing %s: %w", f.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+func writeBytes(bb []byte) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := w.Write(bb)
+		return err
+	}
+}
+
+func writeJSON(v any) func(io.Writer) error {
+	return func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+}
+
+func writeProfile(name string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		return pprof.Lookup(name).WriteTo(w, 0)
+	}
+}
+
+func writeGoroutines(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func writeCPUProfile(ctx context.Context, d time.Duration) func(io.Writer) error {
+	if d <= 0 {
+		d = DefaultCPUProfileDuration
+	}
+	return func(w io.Writer) error {
+		// The zip entry must be written sequentially, so buffer the profile
+		// while it is collected.
+		var buf bytes.Buffer
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return err
+		}
+
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+		}
+		pprof.StopCPUProfile()
+
+		_, err := buf.WriteTo(w)
+		return err
+	}
+}
+
+type buildInfoJSON struct {
+	Version   string            `json:"version"`
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path,omitempty"`
+	Settings  map[string]string `json:"settings,omitempty"`
+	Deps      map[string]string `json:"deps,omitempty"`
+}
+
+func buildInfo(version string) buildInfoJSON {
+	info := buildInfoJSON{Version: version, GoVersion: runtime.Version()}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Path = bi.Path
+	info.Settings = make(map[string]string, len(bi.Settings))
+	for _, s := range bi.Settings {
+		info.Settings[s.Key] = s.Value
+	}
+	info.Deps = make(map[string]string, len(bi.Deps))
+	for _, d := range bi.Deps {
+		info.Deps[d.Path] = d.Version
+	}
+	return info
+}
+
+type runtimeInfoJSON struct {
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	Hostname   string    `json:"hostname"`
+	NumCPU     int       `json:"num_cpu"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Goroutines int       `json:"goroutines"`
+	StartTime  time.Time `json:"start_time"`
+	Uptime     string    `json:"uptime"`
+	Now        time.Time `json:"now"`
+}
+
+func runtimeInfo(start time.Time) runtimeInfoJSON {
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	return runtimeInfoJSON{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Hostname:   hostname,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		StartTime:  start,
+		Uptime:     now.Sub(start).Round(time.Second).String(),
+		Now:        now,
+	}
+}
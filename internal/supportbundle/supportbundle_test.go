@@ -0,0 +1,92 @@
+package supportbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jharvey10/test-repo/internal/component"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(context.Background(), &buf, Sources{
+		Config:  []byte(`remote.write "default" { password = "(secret)" }`),
+		Options: []byte(`{"http_listen_addr":"127.0.0.1:12345"}`),
+		Components: []ComponentInfo{{
+			Name:   "prometheus.scrape",
+			Health: component.Health{Health: component.HealthTypeHealthy, Message: "started component"},
+		}},
+		Logs:               []byte("Running component: prometheus.scrape\n"),
+		Version:            "v1.2.3",
+		StartTime:          time.Now(),
+		CPUProfileDuration: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{
+		"config.alloy", "options.json", "components.json", "logs.txt", "build-info.json",
+		"runtime.json", "pprof/heap.pb.gz", "pprof/goroutine.txt", "pprof/cpu.pb.gz",
+	} {
+		if len(files[name]) == 0 {
+			t.Errorf("bundle is missing %s", name)
+		}
+	}
+
+	var components []struct {
+		Name   string `json:"name"`
+		Health struct {
+			State string `json:"state"`
+		} `json:"health"`
+	}
+	if err := json.Unmarshal(files["components.json"], &components); err != nil {
+		t.Fatalf("decoding components.json: %v", err)
+	}
+	if len(components) != 1 || components[0].Health.State != "healthy" {
+		t.Errorf("components.json = %s", files["components.json"])
+	}
+}
+
+func TestLogBuffer_KeepsMostRecent(t *testing.T) {
+	b := NewLogBuffer(8)
+	_, _ = b.Write([]byte("0123"))
+	_, _ = b.Write([]byte("456789"))
+
+	if got, want := string(b.Bytes()), "23456789"; got != want {
+		t.Errorf("Bytes() = %q, want %q", got, want)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	if d, err := ParseDuration(""); err != nil || d != DefaultCPUProfileDuration {
+		t.Errorf("ParseDuration(\"\") = %v, %v; want default", d, err)
+	}
+	for _, bad := range []string{"abc", "-1s", "1h"} {
+		if _, err := ParseDuration(bad); err == nil {
+			t.Errorf("ParseDuration(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package alloytypes holds the special value types which may be used in
+// component arguments.
+package alloytypes
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Redacted is the text printed in place of the value of a Secret.
+const Redacted = "(secret)"
+
+// Secret is a string value which must never be displayed to the user.
+//
+// Secret implements fmt.Formatter, encoding.TextMarshaler and
+// slog.LogValuer so that printing, logging or dumping a Secret always
+// produces Redacted instead of the underlying value. Code that needs the
+// underlying value must convert it explicitly with Reveal.
+//
+// In expressions, a Secret is a capsule: strings can be assigned to Secret
+// attributes, but a Secret cannot be used as a string unless it is passed
+// through nonsensitive.
+type Secret string
+
+// SecretFromEnv returns a Secret holding the value of the environment
+// variable name. It returns an error if the variable is not set.
+func SecretFromEnv(name string) (Secret, error) {
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", name)
+	}
+	return Secret(val), nil
+}
+
+// SecretFromFile returns a Secret holding the contents of the file at path.
+// A single trailing newline is removed, so files written by editors or
+// `echo` can be used directly.
+func SecretFromFile(path string) (Secret, error) {
+	if path == "" {
+		return "", errors.New("secret file path must not be empty")
+	}
+	bb, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file: %w", err)
+	}
+	val := strings.TrimSuffix(string(bb), "\n")
+	val = strings.TrimSuffix(val, "\r")
+	return Secret(val), nil
+}
+
+// Reveal returns the underlying value of the Secret.
+func (s Secret) Reveal() string { return string(s) }
+
+// String implements fmt.Stringer and returns Redacted.
+func (s Secret) String() string { return Redacted }
+
+// GoString implements fmt.GoStringer and returns a redacted Go
+// representation of the Secret.
+func (s Secret) GoString() string { return fmt.Sprintf("alloytypes.Secret(%q)", Redacted) }
+
+// Format implements fmt.Formatter. Every verb prints Redacted, so a Secret
+// cannot be revealed through the fmt package by accident (for example with
+// %x or %q).
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'q':
+		fmt.Fprintf(f, "%q", Redacted)
+	case 'v':
+		if f.Flag('#') {
+			_, _ = f.Write([]byte(s.GoString()))
+			return
+		}
+		_, _ = f.Write([]byte(Redacted))
+	default:
+		_, _ = f.Write([]byte(Redacted))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler and returns Redacted. This
+// also covers encoding/json and any config dump built on top of it.
+func (s Secret) MarshalText() ([]byte, error) { return []byte(Redacted), nil }
+
+// LogValue implements slog.LogValuer and returns Redacted.
+func (s Secret) LogValue() slog.Value { return slog.StringValue(Redacted) }
+
+// SyntaxCapsule marks Secret as a capsule value in the configuration
+// language.
+func (s Secret) SyntaxCapsule() {}
@@ -0,0 +1,106 @@
+package alloytypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const hidden = "hunter2"
+
+func TestSecret_Format(t *testing.T) {
+	s := Secret(hidden)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%s", Redacted},
+		{"%v", Redacted},
+		{"%+v", Redacted},
+		{"%q", `"(secret)"`},
+		{"%x", Redacted},
+		{"%#v", `alloytypes.Secret("(secret)")`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			got := fmt.Sprintf(tc.format, s)
+			if got != tc.want {
+				t.Errorf("Sprintf(%q) = %q, want %q", tc.format, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSecret_NestedFormat(t *testing.T) {
+	args := struct {
+		URL   string
+		Token Secret
+	}{"http://example.com", Secret(hidden)}
+
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		if got := fmt.Sprintf(format, args); strings.Contains(got, hidden) {
+			t.Errorf("Sprintf(%q) leaked secret: %s", format, got)
+		}
+	}
+}
+
+func TestSecret_Marshal(t *testing.T) {
+	bb, err := json.Marshal(map[string]Secret{"password": Secret(hidden)})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if got, want := string(bb), `{"password":"(secret)"}`; got != want {
+		t.Errorf("json.Marshal = %s, want %s", got, want)
+	}
+}
+
+func TestSecret_Log(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info("connecting", "password", Secret(hidden))
+
+	if strings.Contains(buf.String(), hidden) {
+		t.Errorf("log output leaked secret: %s", buf.String())
+	}
+}
+
+func TestSecretFromEnv(t *testing.T) {
+	t.Setenv("ALLOYTYPES_TEST_SECRET", hidden)
+
+	s, err := SecretFromEnv("ALLOYTYPES_TEST_SECRET")
+	if err != nil {
+		t.Fatalf("SecretFromEnv: %v", err)
+	}
+	if s.Reveal() != hidden {
+		t.Errorf("Reveal() = %q, want %q", s.Reveal(), hidden)
+	}
+
+	if _, err := SecretFromEnv("ALLOYTYPES_TEST_SECRET_UNSET"); err == nil {
+		t.Error("want error for unset environment variable, got nil")
+	}
+}
+
+func TestSecretFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(hidden+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := SecretFromFile(path)
+	if err != nil {
+		t.Fatalf("SecretFromFile: %v", err)
+	}
+	if s.Reveal() != hidden {
+		t.Errorf("Reveal() = %q, want %q", s.Reveal(), hidden)
+	}
+
+	if _, err := SecretFromFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("want error for missing file, got nil")
+	}
+}
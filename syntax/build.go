@@ -0,0 +1,100 @@
+package syntax
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Commit and BuildDate describe the source the binary was built from. Like
+// Version, they can be set at build time with -ldflags "-X ...". When they
+// are not set, the VCS information recorded by the Go toolchain is used.
+var (
+	Commit    string
+	BuildDate string
+)
+
+// BuildInfo describes how the running binary was built. It is available to
+// configuration files as constants.build, such as
+// constants.build.go_version or
+// constants.build.modules["golang.org/x/mod"].
+type BuildInfo struct {
+	// Version is the canonical semantic version of the binary, such as
+	// "v1.2.3". It is "v0.0.0" for development builds.
+	Version string `syntax:"version,attr"`
+
+	// Commit is the VCS revision the binary was built from, if known.
+	Commit string `syntax:"commit,attr"`
+
+	// BuildDate is the time the binary was built, or the time of the
+	// commit it was built from, if known.
+	BuildDate string `syntax:"build_date,attr"`
+
+	// GoVersion is the version of Go used to build the binary.
+	GoVersion string `syntax:"go_version,attr"`
+
+	// Modules maps the path of each module dependency to its version.
+	Modules map[string]string `syntax:"modules,attr"`
+}
+
+// readBuildInfo is replaced in tests.
+var readBuildInfo = debug.ReadBuildInfo
+
+// Build returns the build metadata of the running binary.
+func Build() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Modules:   map[string]string{},
+	}
+
+	if bi, ok := readBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+		for _, dep := range bi.Deps {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				info.Modules[dep.Path] = dep.Replace.Version
+			} else {
+				info.Modules[dep.Path] = dep.Version
+			}
+		}
+	}
+
+	info.Version = normalizeVersion(info.Version)
+	return info
+}
+
+// normalizeVersion returns version as a canonical semantic version with a
+// "v" prefix: "1.2" becomes "v1.2.0" and "v1.2.3-rc.1+dirty" stays as it
+// is. Build metadata is kept, unlike semver.Canonical. If version cannot be
+// parsed as a semantic version, it is returned unmodified.
+//
+// If version is empty, normalizeVersion returns "v0.0.0".
+func normalizeVersion(version string) string {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return "v0.0.0"
+	}
+
+	v := version
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if !semver.IsValid(v) {
+		return version
+	}
+	return semver.Canonical(v) + semver.Build(v)
+}
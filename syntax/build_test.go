@@ -0,0 +1,70 @@
+package syntax
+
+import (
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	tt := []struct {
+		input, expect string
+	}{
+		{"", "v0.0.0"},
+		{"  ", "v0.0.0"},
+		{"1.2.3", "v1.2.3"},
+		{"v1.2.3", "v1.2.3"},
+		{" v1.2.3\n", "v1.2.3"},
+		{"1.2", "v1.2.0"},
+		{"v1", "v1.0.0"},
+		{"1.2.3-rc.1", "v1.2.3-rc.1"},
+		{"v1.2.3+dirty", "v1.2.3+dirty"},
+		{"main", "main"},
+		{"1.2.3.4", "1.2.3.4"},
+	}
+
+	for _, tc := range tt {
+		if actual := normalizeVersion(tc.input); actual != tc.expect {
+			t.Errorf("normalizeVersion(%q): expected %q, got %q", tc.input, tc.expect, actual)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	defer func(prev func() (*debug.BuildInfo, bool)) { readBuildInfo = prev }(readBuildInfo)
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/app", Version: "1.4.0"},
+			Deps: []*debug.Module{
+				{Path: "golang.org/x/mod", Version: "v0.35.0"},
+				{Path: "example.com/forked", Version: "v1.0.0", Replace: &debug.Module{Path: "../forked", Version: "v1.0.1"}},
+			},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc123"},
+				{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+			},
+		}, true
+	}
+
+	expect := BuildInfo{
+		Version:   "v1.4.0",
+		Commit:    "abc123",
+		BuildDate: "2026-01-02T03:04:05Z",
+		GoVersion: runtime.Version(),
+		Modules: map[string]string{
+			"golang.org/x/mod":   "v0.35.0",
+			"example.com/forked": "v1.0.1",
+		},
+	}
+	if actual := Build(); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("expected %#v, got %#v", expect, actual)
+	}
+
+	// Values set with ldflags take precedence over the build information.
+	defer func(v, c string) { Version, Commit = v, c }(Version, Commit)
+	Version, Commit = "2.0.0-rc.1", "def456"
+	if actual := Build(); actual.Version != "v2.0.0-rc.1" || actual.Commit != "def456" {
+		t.Errorf("expected ldflags values to be used, got %#v", actual)
+	}
+}
@@ -0,0 +1,76 @@
+package syntax
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/jharvey10/test-repo/syntax/scanner"
+)
+
+var (
+	constantsMut  sync.RWMutex
+	constantsOnce sync.Once
+
+	constants = map[string]any{
+		"hostname": "", // Initialized via initConstants
+		"os":       runtime.GOOS,
+		"arch":     runtime.GOARCH,
+		"version":  "", // Initialized via initConstants
+		"build":    BuildInfo{},
+	}
+)
+
+// initConstants fills in the constants which can only be computed at
+// runtime. It is safe to call more than once.
+func initConstants() {
+	constantsOnce.Do(func() {
+		constantsMut.Lock()
+		defer constantsMut.Unlock()
+
+		hostname, err := os.Hostname()
+		if err == nil {
+			constants["hostname"] = hostname
+		}
+		build := Build()
+		constants["version"] = build.Version
+		constants["build"] = build
+	})
+}
+
+// RegisterConstant makes value available to every configuration file as
+// constants.<name>. value may be any Go value which can be used in
+// expressions; structs using syntax struct tags become objects.
+//
+// RegisterConstant returns an error if name is not a valid identifier or
+// is already registered, including the builtin constants hostname, os,
+// arch, version and build. Constants registered after a file was evaluated
+// are only visible to files evaluated afterwards.
+func RegisterConstant(name string, value any) error {
+	if !scanner.IsValidIdentifier(name) {
+		return fmt.Errorf("syntax: invalid constant name %q", name)
+	}
+
+	constantsMut.Lock()
+	defer constantsMut.Unlock()
+	if _, exists := constants[name]; exists {
+		return fmt.Errorf("syntax: constant %q is already registered", name)
+	}
+	constants[name] = value
+	return nil
+}
+
+// snapshotConstants returns a copy of the registered constants, so a
+// scope is not affected by constants registered while it is in use.
+func snapshotConstants() map[string]any {
+	initConstants()
+
+	constantsMut.RLock()
+	defer constantsMut.RUnlock()
+	res := make(map[string]any, len(constants))
+	for name, value := range constants {
+		res[name] = value
+	}
+	return res
+}
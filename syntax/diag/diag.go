@@ -0,0 +1,153 @@
+// Package diag holds diagnostics reported while parsing or evaluating
+// configuration files, and renders them for people and for editors.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity int
+
+// Supported Severity values.
+const (
+	SeverityLevelError Severity = iota
+	SeverityLevelWarn
+)
+
+// String returns the name of s.
+func (s Severity) String() string {
+	switch s {
+	case SeverityLevelError:
+		return "error"
+	case SeverityLevelWarn:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "error":
+		*s = SeverityLevelError
+	case "warning":
+		*s = SeverityLevelWarn
+	default:
+		return fmt.Errorf("unknown severity %q", text)
+	}
+	return nil
+}
+
+// Diagnostic is a single problem found in a configuration file.
+type Diagnostic struct {
+	Severity Severity
+	StartPos token.Position
+	EndPos   token.Position // EndPos is the position of the last character.
+	Message  string
+
+	// Hint optionally suggests how to fix the problem.
+	Hint string
+}
+
+// Error implements error.
+func (d Diagnostic) Error() string {
+	if d.StartPos.Valid() || d.StartPos.Filename != "" {
+		return fmt.Sprintf("%s: %s", d.StartPos, d.Message)
+	}
+	return d.Message
+}
+
+// Diagnostics is a list of diagnostics.
+type Diagnostics []Diagnostic
+
+// Add appends a diagnostic to the list.
+func (ds *Diagnostics) Add(d Diagnostic) { *ds = append(*ds, d) }
+
+// Merge appends every diagnostic in other to the list.
+func (ds *Diagnostics) Merge(other Diagnostics) { *ds = append(*ds, other...) }
+
+// Sort sorts the list by position: by filename, then by line and column.
+// Diagnostics without a position come first, and diagnostics at the same
+// position keep their order.
+func (ds Diagnostics) Sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		a, b := ds[i].StartPos, ds[j].StartPos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// HasErrors reports whether the list contains any diagnostic with
+// SeverityLevelError.
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityLevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// Error implements error. It returns every diagnostic on a separate line.
+func (ds Diagnostics) Error() string {
+	msgs := make([]string, len(ds))
+	for i, d := range ds {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns ds as an error if it contains any errors, and nil otherwise.
+func (ds Diagnostics) Err() error {
+	if !ds.HasErrors() {
+		return nil
+	}
+	return ds
+}
+
+// Diagnoser is implemented by errors which can describe themselves as
+// diagnostics, such as the errors returned by the parser and evaluator.
+type Diagnoser interface {
+	error
+	Diagnostics() Diagnostics
+}
+
+// FromError converts err into Diagnostics. Errors which do not implement
+// Diagnoser become a single diagnostic without a position. FromError
+// returns nil for a nil error.
+func FromError(err error) Diagnostics {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		ds Diagnostics
+		d  Diagnostic
+		dg Diagnoser
+	)
+	switch {
+	case errors.As(err, &ds):
+		return ds
+	case errors.As(err, &d):
+		return Diagnostics{d}
+	case errors.As(err, &dg):
+		return dg.Diagnostics()
+	default:
+		return Diagnostics{{Severity: SeverityLevelError, Message: err.Error()}}
+	}
+}
@@ -0,0 +1,146 @@
+package diag_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+func TestFprint(t *testing.T) {
+	src := []byte("address = \"a\"\nport = \"80\"\n\ttimeout = 1\n")
+	files := map[string][]byte{"config.alloy": src}
+
+	ds := diag.Diagnostics{
+		{
+			Severity: diag.SeverityLevelError,
+			StartPos: token.Position{Filename: "config.alloy", Line: 2, Column: 8},
+			EndPos:   token.Position{Filename: "config.alloy", Line: 2, Column: 11},
+			Message:  "expected number, got string",
+			Hint:     "remove the quotes",
+		},
+		{
+			Severity: diag.SeverityLevelWarn,
+			StartPos: token.Position{Filename: "config.alloy", Line: 3, Column: 2},
+			EndPos:   token.Position{Filename: "config.alloy", Line: 3, Column: 8},
+			Message:  "timeout is deprecated",
+		},
+		{
+			Severity: diag.SeverityLevelError,
+			Message:  "no position",
+		},
+	}
+
+	expect := `Error: config.alloy:2:8: expected number, got string
+1 | address = "a"
+2 | port = "80"
+  |        ^^^^
+hint: remove the quotes
+
+Warning: config.alloy:3:2: timeout is deprecated
+2 | port = "80"
+3 | 	timeout = 1
+  | 	^^^^^^^
+
+Error: no position
+`
+
+	var buf bytes.Buffer
+	if err := diag.Fprint(&buf, files, ds); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expect {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", buf.String(), expect)
+	}
+
+	buf.Reset()
+	p := diag.NewPrinter(diag.PrinterConfig{Color: true})
+	if err := p.Fprint(&buf, files, ds[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\x1b[31m") {
+		t.Errorf("expected colored output, got %q", buf.String())
+	}
+}
+
+func TestFromError(t *testing.T) {
+	_, err := parser.ParseFile("bad.alloy", []byte("a = )\nb = )"))
+	ds := diag.FromError(err)
+	if len(ds) != 2 || ds[0].StartPos.Line != 1 || ds[1].StartPos.Line != 2 {
+		t.Fatalf("unexpected diagnostics: %#v", ds)
+	}
+
+	ds = diag.FromError(errors.New("plain"))
+	if len(ds) != 1 || ds[0].Message != "plain" || ds[0].StartPos.Valid() {
+		t.Fatalf("unexpected diagnostics: %#v", ds)
+	}
+
+	if diag.FromError(nil) != nil {
+		t.Fatal("expected nil diagnostics for a nil error")
+	}
+}
+
+func TestSort(t *testing.T) {
+	pos := func(filename string, line, col int) token.Position {
+		return token.Position{Filename: filename, Line: line, Column: col}
+	}
+	ds := diag.Diagnostics{
+		{StartPos: pos("b.alloy", 1, 1), Message: "b"},
+		{StartPos: pos("a.alloy", 3, 1), Message: "a3"},
+		{StartPos: pos("a.alloy", 1, 5), Message: "a1:5"},
+		{Message: "no position"},
+		{StartPos: pos("a.alloy", 1, 2), Message: "a1:2"},
+	}
+	ds.Sort()
+
+	var messages []string
+	for _, d := range ds {
+		messages = append(messages, d.Message)
+	}
+	if got := strings.Join(messages, ","); got != "no position,a1:2,a1:5,a3,b" {
+		t.Errorf("unexpected order %s", got)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	ds := diag.Diagnostics{{
+		Severity: diag.SeverityLevelWarn,
+		StartPos: token.Position{Filename: "a.alloy", Offset: 4, Line: 1, Column: 5},
+		EndPos:   token.Position{Filename: "a.alloy", Offset: 6, Line: 1, Column: 7},
+		Message:  "message",
+		Hint:     "hint",
+	}}
+
+	var buf bytes.Buffer
+	if err := diag.WriteJSON(&buf, ds); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"severity": "warning"`) || !strings.Contains(buf.String(), `"line": 1`) {
+		t.Errorf("unexpected JSON: %s", buf.String())
+	}
+
+	var decoded diag.Diagnostics
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ds, decoded) {
+		t.Errorf("expected %#v, got %#v", ds, decoded)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"prometheus.scrape", "prometheus.remote_write", "loki.write"}
+
+	if hint := diag.Suggest("prometheus.scrap", candidates); hint != `did you mean "prometheus.scrape"?` {
+		t.Errorf("unexpected hint %q", hint)
+	}
+	if hint := diag.Suggest("otelcol.receiver", candidates); hint != "" {
+		t.Errorf("expected no hint, got %q", hint)
+	}
+}
@@ -0,0 +1,243 @@
+package diag
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// ANSI escape sequences used when color is enabled.
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// PrinterConfig configures a Printer.
+type PrinterConfig struct {
+	// Color enables ANSI colors. It should only be set when writing to a
+	// terminal.
+	Color bool
+
+	// ContextLinesBefore and ContextLinesAfter set how many lines of source
+	// are shown around the lines a diagnostic points at.
+	ContextLinesBefore int
+	ContextLinesAfter  int
+}
+
+// Printer renders diagnostics with snippets of the source they refer to.
+type Printer struct {
+	cfg PrinterConfig
+}
+
+// NewPrinter creates a new Printer.
+func NewPrinter(cfg PrinterConfig) *Printer {
+	return &Printer{cfg: cfg}
+}
+
+// Fprint writes ds to w. files maps filenames to their contents, and is
+// used to show the source lines each diagnostic points at. Diagnostics for
+// files missing from files are printed without a snippet.
+//
+// A diagnostic is rendered as:
+//
+//	Error: config.alloy:2:8: expected number, got string
+//	1 | address = "a"
+//	2 | port = "80"
+//	  |        ^^^^
+//	hint: ports are numbers
+func (p *Printer) Fprint(w io.Writer, files map[string][]byte, ds Diagnostics) error {
+	bw := bufio.NewWriter(w)
+	for i, d := range ds {
+		if i > 0 {
+			bw.WriteByte('\n')
+		}
+		p.printDiagnostic(bw, files, d)
+	}
+	return bw.Flush()
+}
+
+// Fprint writes ds to w using the default PrinterConfig, which shows one
+// line of context before each diagnostic and does not use color.
+func Fprint(w io.Writer, files map[string][]byte, ds Diagnostics) error {
+	return NewPrinter(PrinterConfig{ContextLinesBefore: 1}).Fprint(w, files, ds)
+}
+
+func (p *Printer) color(w *bufio.Writer, code string) {
+	if p.cfg.Color {
+		w.WriteString(code)
+	}
+}
+
+func (p *Printer) printDiagnostic(w *bufio.Writer, files map[string][]byte, d Diagnostic) {
+	label, color := "Error", colorRed
+	if d.Severity == SeverityLevelWarn {
+		label, color = "Warning", colorYellow
+	}
+
+	p.color(w, colorBold+color)
+	w.WriteString(label)
+	w.WriteString(":")
+	p.color(w, colorReset+colorBold)
+	w.WriteString(" ")
+	w.WriteString(d.Error())
+	p.color(w, colorReset)
+	w.WriteByte('\n')
+
+	if src, ok := files[d.StartPos.Filename]; ok && d.StartPos.Valid() {
+		p.printSnippet(w, src, d, color)
+	}
+
+	if d.Hint != "" {
+		p.color(w, colorCyan)
+		w.WriteString("hint:")
+		p.color(w, colorReset)
+		w.WriteString(" ")
+		w.WriteString(d.Hint)
+		w.WriteByte('\n')
+	}
+}
+
+func (p *Printer) printSnippet(w *bufio.Writer, src []byte, d Diagnostic, color string) {
+	lines := bytes.Split(src, []byte("\n"))
+
+	start, end := d.StartPos, d.EndPos
+	if !end.Valid() || end.Line < start.Line {
+		end = start
+	}
+	if start.Line > len(lines) {
+		return
+	}
+
+	first := max(1, start.Line-p.cfg.ContextLinesBefore)
+	last := min(len(lines), end.Line+p.cfg.ContextLinesAfter)
+	width := len(strconv.Itoa(last))
+
+	for n := first; n <= last; n++ {
+		text := strings.TrimRight(string(lines[n-1]), "\r")
+
+		p.color(w, colorCyan)
+		fmt.Fprintf(w, "%*d | ", width, n)
+		p.color(w, colorReset)
+		w.WriteString(text)
+		w.WriteByte('\n')
+
+		if n < start.Line || n > end.Line {
+			continue
+		}
+
+		// Underline the part of the line covered by the diagnostic.
+		from, to := 1, len(text)
+		if n == start.Line {
+			from = start.Column
+		}
+		if n == end.Line && end.Column > 0 {
+			to = end.Column
+		}
+		if from > len(text) {
+			// Diagnostics at the end of a line, such as a missing token,
+			// point just past the last character.
+			from, to = len(text)+1, len(text)+1
+		}
+		to = max(from, min(to, len(text)+1))
+
+		p.color(w, colorCyan)
+		fmt.Fprintf(w, "%*s | ", width, "")
+		p.color(w, colorReset)
+		w.WriteString(indentFor(text[:from-1]))
+		p.color(w, colorBold+color)
+		w.WriteString(strings.Repeat("^", max(1, runeCount(text, from, to))))
+		p.color(w, colorReset)
+		w.WriteByte('\n')
+	}
+}
+
+// indentFor returns whitespace with the same width as prefix, keeping
+// tabs so the underline lines up with the source.
+func indentFor(prefix string) string {
+	var sb strings.Builder
+	for _, r := range prefix {
+		if r == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	return sb.String()
+}
+
+// runeCount counts the runes between the 1-indexed byte columns from and
+// to, inclusive.
+func runeCount(text string, from, to int) int {
+	if from > len(text) {
+		return 1
+	}
+	return utf8.RuneCountInString(text[from-1 : min(to, len(text))])
+}
+
+// jsonDiagnostic is the JSON representation of a Diagnostic.
+type jsonDiagnostic struct {
+	Severity Severity     `json:"severity"`
+	Start    jsonPosition `json:"start"`
+	End      jsonPosition `json:"end"`
+	Message  string       `json:"message"`
+	Hint     string       `json:"hint,omitempty"`
+}
+
+type jsonPosition struct {
+	Filename string `json:"filename,omitempty"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func toJSONPosition(pos token.Position) jsonPosition {
+	return jsonPosition{Filename: pos.Filename, Offset: pos.Offset, Line: pos.Line, Column: pos.Column}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonDiagnostic{
+		Severity: d.Severity,
+		Start:    toJSONPosition(d.StartPos),
+		End:      toJSONPosition(d.EndPos),
+		Message:  d.Message,
+		Hint:     d.Hint,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Diagnostic) UnmarshalJSON(data []byte) error {
+	var jd jsonDiagnostic
+	if err := json.Unmarshal(data, &jd); err != nil {
+		return err
+	}
+	*d = Diagnostic{
+		Severity: jd.Severity,
+		StartPos: token.Position(jd.Start),
+		EndPos:   token.Position(jd.End),
+		Message:  jd.Message,
+		Hint:     jd.Hint,
+	}
+	return nil
+}
+
+// WriteJSON writes ds to w as a JSON array, for editors and other tools.
+// An empty list is written as [].
+func WriteJSON(w io.Writer, ds Diagnostics) error {
+	if ds == nil {
+		ds = Diagnostics{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ds)
+}
@@ -0,0 +1,406 @@
+package syntax
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jharvey10/test-repo/syntax/internal/syntaxtags"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/scanner"
+)
+
+// An ExprMarshaler is written by the Encoder as the expression returned by
+// MarshalSyntaxExpr rather than as a value. It is used for expressions
+// which have no Go value of their own, such as references to the exports
+// of other components.
+type ExprMarshaler interface {
+	MarshalSyntaxExpr() ([]byte, error)
+}
+
+// An Encoder writes Go values as configuration text to an output stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new Encoder which writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the configuration body for v, which must be a struct (or a
+// pointer to one) using syntax struct tags. It is the inverse of
+// Unmarshal.
+//
+// Attributes are written before blocks, each in the order the fields are
+// declared; within a group of attributes the = signs are aligned. Optional
+// attributes and blocks are omitted when they hold their zero value.
+//
+// Types implementing encoding.TextMarshaler, such as alloytypes.Secret,
+// are written as strings holding the marshaled text, so secrets are always
+// written redacted. Types implementing ExprMarshaler are written as the
+// expression they return.
+func (enc *Encoder) Encode(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("syntax: cannot encode %T as a body; expected a struct", v)
+	}
+
+	var p encodePrinter
+	if err := p.writeBody(rv, 0); err != nil {
+		return err
+	}
+	_, err := enc.w.Write(p.buf.Bytes())
+	return err
+}
+
+// EncodeValue writes v as a single expression followed by a newline. It is
+// the inverse of UnmarshalValue.
+func (enc *Encoder) EncodeValue(v any) error {
+	var p encodePrinter
+	if err := p.writeValue(reflect.ValueOf(v), 0); err != nil {
+		return err
+	}
+	p.buf.WriteByte('\n')
+	_, err := enc.w.Write(p.buf.Bytes())
+	return err
+}
+
+// Marshal returns the configuration text for the struct v. See
+// Encoder.Encode for details.
+func Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalValue returns the expression text for v. Unlike
+// Encoder.EncodeValue, no trailing newline is added.
+func MarshalValue(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).EncodeValue(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+var (
+	goTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	exprMarshaler   = reflect.TypeOf((*ExprMarshaler)(nil)).Elem()
+)
+
+// isMarshaler reports whether values of ty marshal themselves.
+func isMarshaler(ty reflect.Type) bool {
+	return ty.Implements(goTextMarshaler) || ty.Implements(exprMarshaler)
+}
+
+type encodePrinter struct {
+	buf bytes.Buffer
+}
+
+func (p *encodePrinter) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		p.buf.WriteByte('\t')
+	}
+}
+
+// writeBody writes the attributes and blocks of the struct rv.
+func (p *encodePrinter) writeBody(rv reflect.Value, depth int) error {
+	var (
+		attrs  []syntaxtags.Field
+		blocks []syntaxtags.Field
+	)
+	for _, f := range syntaxtags.Get(rv.Type()) {
+		field := rv.FieldByIndex(f.Index)
+		if f.IsOptional() && field.IsZero() {
+			continue
+		}
+		switch {
+		case f.IsAttr():
+			attrs = append(attrs, f)
+		case f.IsBlock():
+			blocks = append(blocks, f)
+		}
+	}
+
+	width := 0
+	for _, f := range attrs {
+		width = max(width, len(f.FullName()))
+	}
+	for _, f := range attrs {
+		p.indent(depth)
+		fmt.Fprintf(&p.buf, "%-*s = ", width, f.FullName())
+		if err := p.writeValue(rv.FieldByIndex(f.Index), depth); err != nil {
+			return fmt.Errorf("attribute %q: %w", f.FullName(), err)
+		}
+		p.buf.WriteByte('\n')
+	}
+
+	first := len(attrs) == 0
+	for _, f := range blocks {
+		field := rv.FieldByIndex(f.Index)
+
+		var elems []reflect.Value
+		if field.Kind() == reflect.Slice || field.Kind() == reflect.Array {
+			for i := 0; i < field.Len(); i++ {
+				elems = append(elems, field.Index(i))
+			}
+		} else {
+			elems = append(elems, field)
+		}
+
+		for _, elem := range elems {
+			if !first {
+				p.buf.WriteByte('\n')
+			}
+			first = false
+			if err := p.writeBlock(f.FullName(), elem, depth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *encodePrinter) writeBlock(name string, rv reflect.Value, depth int) error {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return fmt.Errorf("block %q: cannot encode nil block", name)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("block %q: cannot encode %s as a block", name, rv.Type())
+	}
+
+	p.indent(depth)
+	p.buf.WriteString(name)
+	for _, f := range syntaxtags.Get(rv.Type()) {
+		if f.IsLabel() {
+			p.buf.WriteByte(' ')
+			p.buf.WriteString(strconv.Quote(rv.FieldByIndex(f.Index).String()))
+		}
+	}
+
+	start := p.buf.Len()
+	p.buf.WriteString(" {\n")
+	if err := p.writeBody(rv, depth+1); err != nil {
+		return fmt.Errorf("block %q: %w", name, err)
+	}
+	if p.buf.Len() == start+len(" {\n") {
+		// Collapse empty blocks onto a single line.
+		p.buf.Truncate(start)
+		p.buf.WriteString(" { }\n")
+		return nil
+	}
+	p.indent(depth)
+	p.buf.WriteString("}\n")
+	return nil
+}
+
+// writeValue writes rv as an expression. Nested arrays and objects are
+// indented relative to depth.
+func (p *encodePrinter) writeValue(rv reflect.Value, depth int) error {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			p.buf.WriteString("null")
+			return nil
+		}
+		if isMarshaler(rv.Type()) {
+			break
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		p.buf.WriteString("null")
+		return nil
+	}
+
+	if rv.Type().Implements(exprMarshaler) {
+		expr, err := rv.Interface().(ExprMarshaler).MarshalSyntaxExpr()
+		if err != nil {
+			return err
+		}
+		if _, err := parser.ParseExpression(string(expr)); err != nil {
+			return fmt.Errorf("invalid expression %q: %w", expr, err)
+		}
+		p.buf.Write(expr)
+		return nil
+	}
+	if rv.Type().Implements(goTextMarshaler) {
+		text, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		p.buf.WriteString(strconv.Quote(string(text)))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		p.buf.WriteString(strconv.FormatBool(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return p.writeFloat(rv.Float())
+	case reflect.String:
+		p.buf.WriteString(strconv.Quote(rv.String()))
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			p.buf.WriteString("null")
+			return nil
+		}
+		return p.writeArray(rv, depth)
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot encode map with %s keys", rv.Type().Key())
+		}
+		if rv.IsNil() {
+			p.buf.WriteString("null")
+			return nil
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		fields := make([]objectField, len(keys))
+		for i, key := range keys {
+			fields[i] = objectField{name: key, value: rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))}
+		}
+		return p.writeObject(fields, depth)
+
+	case reflect.Struct:
+		var fields []objectField
+		for _, f := range syntaxtags.Get(rv.Type()) {
+			field := rv.FieldByIndex(f.Index)
+			if f.IsLabel() || (f.IsOptional() && field.IsZero()) {
+				continue
+			}
+			fields = append(fields, objectField{name: f.FullName(), value: field})
+		}
+		return p.writeObject(fields, depth)
+
+	default:
+		return fmt.Errorf("cannot encode Go value of type %s", rv.Type())
+	}
+	return nil
+}
+
+func (p *encodePrinter) writeFloat(f float64) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("cannot encode %v", f)
+	}
+	text := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsAny(text, ".e") {
+		// Keep the value a float when it is decoded again.
+		text += ".0"
+	}
+	p.buf.WriteString(text)
+	return nil
+}
+
+// writeArray writes an array. Arrays of scalars are written on one line;
+// arrays containing arrays or objects place each element on its own line.
+func (p *encodePrinter) writeArray(rv reflect.Value, depth int) error {
+	multiline := false
+	for i := 0; i < rv.Len(); i++ {
+		if isComposite(rv.Index(i)) {
+			multiline = true
+			break
+		}
+	}
+
+	p.buf.WriteByte('[')
+	for i := 0; i < rv.Len(); i++ {
+		if multiline {
+			p.buf.WriteByte('\n')
+			p.indent(depth + 1)
+		} else if i > 0 {
+			p.buf.WriteString(", ")
+		}
+		if err := p.writeValue(rv.Index(i), depth+1); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+		if multiline {
+			p.buf.WriteByte(',')
+		}
+	}
+	if multiline {
+		p.buf.WriteByte('\n')
+		p.indent(depth)
+	}
+	p.buf.WriteByte(']')
+	return nil
+}
+
+type objectField struct {
+	name  string
+	value reflect.Value
+}
+
+// writeObject writes an object with one field per line and aligned =
+// signs. Field names which are not valid identifiers are quoted.
+func (p *encodePrinter) writeObject(fields []objectField, depth int) error {
+	if len(fields) == 0 {
+		p.buf.WriteString("{}")
+		return nil
+	}
+
+	names := make([]string, len(fields))
+	width := 0
+	for i, f := range fields {
+		names[i] = f.name
+		if !scanner.IsValidIdentifier(f.name) {
+			names[i] = strconv.Quote(f.name)
+		}
+		width = max(width, len(names[i]))
+	}
+
+	p.buf.WriteString("{\n")
+	for i, f := range fields {
+		p.indent(depth + 1)
+		fmt.Fprintf(&p.buf, "%-*s = ", width, names[i])
+		if err := p.writeValue(f.value, depth+1); err != nil {
+			return fmt.Errorf("field %q: %w", f.name, err)
+		}
+		p.buf.WriteString(",\n")
+	}
+	p.indent(depth)
+	p.buf.WriteByte('}')
+	return nil
+}
+
+// isComposite reports whether rv is written as an array or object.
+func isComposite(rv reflect.Value) bool {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+	if isMarshaler(rv.Type()) {
+		return false
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
+}
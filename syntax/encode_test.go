@@ -0,0 +1,125 @@
+package syntax_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax"
+	"github.com/jharvey10/test-repo/syntax/alloytypes"
+)
+
+type encodeTarget struct {
+	Label   string `syntax:",label"`
+	Address string `syntax:"address,attr"`
+}
+
+type encodeTLS struct {
+	CertFile string            `syntax:"cert_file,attr"`
+	Password alloytypes.Secret `syntax:"password,attr,optional"`
+}
+
+type encodeConfig struct {
+	Name     string            `syntax:"name,attr"`
+	Interval float64           `syntax:"scrape_interval,attr,optional"`
+	Labels   map[string]string `syntax:"labels,attr,optional"`
+	Ports    []int             `syntax:"ports,attr,optional"`
+	Unset    string            `syntax:"unset,attr,optional"`
+	Targets  []encodeTarget    `syntax:"target,block,optional"`
+	TLS      *encodeTLS        `syntax:"tls,block,optional"`
+}
+
+func TestMarshal(t *testing.T) {
+	in := encodeConfig{
+		Name:     "example",
+		Interval: 15,
+		Labels:   map[string]string{"team": "infra", "app.kubernetes.io/name": "alloy"},
+		Ports:    []int{80, 443},
+		Targets: []encodeTarget{
+			{Label: "b", Address: "b:80"},
+			{Label: "a", Address: "a:80"},
+		},
+		TLS: &encodeTLS{CertFile: "/etc/cert.pem", Password: "hunter2"},
+	}
+
+	expect := `name            = "example"
+scrape_interval = 15.0
+labels          = {
+	"app.kubernetes.io/name" = "alloy",
+	team                     = "infra",
+}
+ports           = [80, 443]
+
+target "b" {
+	address = "b:80"
+}
+
+target "a" {
+	address = "a:80"
+}
+
+tls {
+	cert_file = "/etc/cert.pem"
+	password  = "(secret)"
+}
+`
+
+	out, err := syntax.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expect {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out, expect)
+	}
+
+	// The output must decode back into the original value, except for the
+	// redacted secret.
+	var actual encodeConfig
+	if err := syntax.Unmarshal(out, &actual); err != nil {
+		t.Fatal(err)
+	}
+	in.TLS.Password = alloytypes.Redacted
+	if !reflect.DeepEqual(in, actual) {
+		t.Errorf("round trip mismatch:\nexpected %#v\ngot      %#v", in, actual)
+	}
+}
+
+// exprString is written as a raw expression.
+type exprString string
+
+func (e exprString) MarshalSyntaxExpr() ([]byte, error) { return []byte(e), nil }
+
+func TestMarshalValue(t *testing.T) {
+	tt := []struct {
+		input  any
+		expect string
+	}{
+		{nil, `null`},
+		{true, `true`},
+		{-12, `-12`},
+		{1.5, `1.5`},
+		{"line\n\"quoted\"", `"line\n\"quoted\""`},
+		{alloytypes.Secret("hunter2"), `"(secret)"`},
+		{[]string{}, `[]`},
+		{map[string]any{}, `{}`},
+		{[]any{[]int{1}, "x"}, "[\n\t[1],\n\t\"x\",\n]"},
+		{map[string]any{"a": 1, "bb": []int{2}}, "{\n\ta  = 1,\n\tbb = [2],\n}"},
+		{[]exprString{"a.b.receiver", "env(\"HOME\")"}, `[a.b.receiver, env("HOME")]`},
+	}
+
+	for _, tc := range tt {
+		out, err := syntax.MarshalValue(tc.input)
+		if err != nil {
+			t.Errorf("%#v: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if string(out) != tc.expect {
+			t.Errorf("%#v: expected %q, got %q", tc.input, tc.expect, out)
+		}
+	}
+}
+
+func TestMarshalValue_InvalidExpr(t *testing.T) {
+	if _, err := syntax.MarshalValue(exprString("a.")); err == nil {
+		t.Fatal("expected an error for an invalid expression")
+	}
+}
@@ -2,4 +2,7 @@ module github.com/jharvey10/test-repo/syntax
 
 go 1.25.1
 
-require golang.org/x/mod v0.35.0
+require (
+	golang.org/x/mod v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,167 @@
+package stdlib
+
+import "fmt"
+
+// format implements string.format. Unlike fmt.Sprintf, it returns an error
+// when the verbs of the format don't match the arguments, instead of
+// writing the mismatch into the result.
+func format(format string, args ...any) (string, error) {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		// Skip flags, width and precision.
+		i++
+		for i < len(format) && isFormatModifier(format[i]) {
+			if format[i] == '*' || format[i] == '[' {
+				return "", fmt.Errorf("format %q: %c in verbs is not supported", format, format[i])
+			}
+			i++
+		}
+		if i == len(format) {
+			return "", fmt.Errorf("format %q: missing verb at end of format", format)
+		}
+
+		verb := format[i]
+		if verb == '%' {
+			continue
+		}
+		if n == len(args) {
+			return "", fmt.Errorf("format %q: expected %d arguments, got %d", format, countVerbs(format), len(args))
+		}
+		if err := checkVerb(verb, args[n]); err != nil {
+			return "", fmt.Errorf("format %q: argument %d: %w", format, n+1, err)
+		}
+		if isFloatVerb(verb) {
+			args[n] = toFloat(args[n])
+		}
+		n++
+	}
+	if n != len(args) {
+		return "", fmt.Errorf("format %q: expected %d arguments, got %d", format, n, len(args))
+	}
+	return fmt.Sprintf(format, args...), nil
+}
+
+func isFormatModifier(c byte) bool {
+	switch c {
+	case '+', '-', '#', ' ', '0', '.', '*', '[':
+		return true
+	}
+	return '1' <= c && c <= '9'
+}
+
+// countVerbs returns the number of verbs in format which take an argument.
+func countVerbs(format string) int {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && isFormatModifier(format[i]) {
+			i++
+		}
+		if i < len(format) && format[i] != '%' {
+			n++
+		}
+	}
+	return n
+}
+
+// checkVerb reports whether verb can format arg, which holds the Go
+// representation of a value.
+func checkVerb(verb byte, arg any) error {
+	switch verb {
+	case 'v', 'T':
+		return nil
+	case 't':
+		if _, ok := arg.(bool); ok {
+			return nil
+		}
+		return fmt.Errorf("%%%c expects a bool, got %s", verb, describe(arg))
+	case 'd', 'o', 'O', 'c', 'U':
+		if isInteger(arg) {
+			return nil
+		}
+		if isNumber(arg) {
+			return fmt.Errorf("%%%c expects an integer, got %v", verb, arg)
+		}
+		return fmt.Errorf("%%%c expects an integer, got %s", verb, describe(arg))
+	case 'b', 'e', 'E', 'f', 'F', 'g', 'G':
+		if isNumber(arg) {
+			return nil
+		}
+		return fmt.Errorf("%%%c expects a number, got %s", verb, describe(arg))
+	case 's', 'q':
+		if _, ok := arg.(string); ok {
+			return nil
+		}
+		return fmt.Errorf("%%%c expects a string, got %s", verb, describe(arg))
+	case 'x', 'X':
+		if _, ok := arg.(string); ok || isNumber(arg) {
+			return nil
+		}
+		return fmt.Errorf("%%%c expects a string or number, got %s", verb, describe(arg))
+	}
+	return fmt.Errorf("unknown verb %%%c", verb)
+}
+
+func isFloatVerb(verb byte) bool {
+	switch verb {
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return true
+	}
+	return false
+}
+
+// toFloat converts the number arg to a float64, as Go doesn't format
+// integers with floating-point verbs.
+func toFloat(arg any) float64 {
+	switch arg := arg.(type) {
+	case int:
+		return float64(arg)
+	case int64:
+		return float64(arg)
+	case uint64:
+		return float64(arg)
+	}
+	return arg.(float64)
+}
+
+func isNumber(arg any) bool {
+	switch arg.(type) {
+	case int, int64, uint64, float64:
+		return true
+	}
+	return false
+}
+
+func isInteger(arg any) bool {
+	switch arg.(type) {
+	case int, int64, uint64:
+		return true
+	}
+	return false
+}
+
+// describe names the type of arg as it is known in the syntax.
+func describe(arg any) string {
+	switch arg.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	if isNumber(arg) {
+		return "number"
+	}
+	return fmt.Sprintf("%T", arg)
+}
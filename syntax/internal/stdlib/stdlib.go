@@ -0,0 +1,226 @@
+// Package stdlib contains the standard library of functions available to
+// every configuration expression.
+//
+// Top-level functions:
+//
+//	env(name string) string
+//	coalesce(values ...any) any
+//	nonsensitive(secret secret) string
+//	json_decode(text string) any
+//	yaml_decode(text string) any
+//	base64_encode(text string) string
+//	base64_decode(text string) string
+//
+// Namespaced functions:
+//
+//	file.read(path string) string
+//	sys.env(name string) string
+//	array.concat(arrays ...array) array
+//	string.format(format string, args ...any) string
+//	string.join(elems []string, sep string) string
+//	string.split(s string, sep string) []string
+//	string.replace(s string, old string, new string) string
+//	string.trim(s string, cutset string) string
+//	string.trim_prefix(s string, prefix string) string
+//	string.trim_suffix(s string, suffix string) string
+//	string.trim_space(s string) string
+package stdlib
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jharvey10/test-repo/syntax/alloytypes"
+	"github.com/jharvey10/test-repo/syntax/internal/value"
+)
+
+// Identifiers holds the identifiers of the standard library. Identifiers in
+// expressions which are not found in the evaluation scope are looked up
+// here.
+var Identifiers = map[string]any{
+	// env returns the value of the environment variable name, or an empty
+	// string if it is not set.
+	"env": os.Getenv,
+
+	// coalesce returns the first argument which is not a zero value (null,
+	// false, 0, "" or an empty array or object). If every argument is a
+	// zero value, the last one is returned.
+	"coalesce": coalesce,
+
+	// nonsensitive converts a secret into a string, exposing its value.
+	"nonsensitive": func(secret alloytypes.Secret) string {
+		return secret.Reveal()
+	},
+
+	// json_decode decodes a JSON document into a value.
+	"json_decode": jsonDecode,
+
+	// yaml_decode decodes a YAML document into a value.
+	"yaml_decode": yamlDecode,
+
+	// base64_encode encodes text using standard base64 encoding.
+	"base64_encode": func(text string) string {
+		return base64.StdEncoding.EncodeToString([]byte(text))
+	},
+
+	// base64_decode decodes standard base64-encoded text.
+	"base64_decode": func(text string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(text)
+		return string(b), err
+	},
+
+	"file": map[string]any{
+		// file.read returns the contents of the file at path.
+		"read": func(path string) (string, error) {
+			b, err := os.ReadFile(path)
+			return string(b), err
+		},
+	},
+
+	"sys": map[string]any{
+		// sys.env returns the value of the environment variable name, or an
+		// empty string if it is not set.
+		"env": os.Getenv,
+	},
+
+	"array": map[string]any{
+		// array.concat concatenates arrays.
+		"concat": func(arrays ...[]value.Value) []value.Value {
+			var res []value.Value
+			for _, array := range arrays {
+				res = append(res, array...)
+			}
+			if res == nil {
+				res = []value.Value{}
+			}
+			return res
+		},
+	},
+
+	"string": map[string]any{
+		// string.format formats according to a format specifier, following
+		// the verbs of Go's fmt package. The verbs must match the number
+		// and types of the arguments.
+		"format":      format,
+		"join":        strings.Join,
+		"split":       strings.Split,
+		"replace":     strings.ReplaceAll,
+		"trim":        strings.Trim,
+		"trim_prefix": strings.TrimPrefix,
+		"trim_suffix": strings.TrimSuffix,
+		"trim_space":  strings.TrimSpace,
+	},
+}
+
+func coalesce(values ...value.Value) value.Value {
+	for _, v := range values {
+		if !isZero(v) {
+			return v
+		}
+	}
+	if len(values) == 0 {
+		return value.Null
+	}
+	return values[len(values)-1]
+}
+
+func isZero(v value.Value) bool {
+	switch v.Type() {
+	case value.TypeNull:
+		return true
+	case value.TypeBool:
+		return !v.Bool()
+	case value.TypeNumber:
+		return v.Number().Float() == 0
+	case value.TypeString:
+		return v.Text() == ""
+	case value.TypeArray, value.TypeObject:
+		return v.Len() == 0
+	default:
+		return false
+	}
+}
+
+func jsonDecode(text string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	var res any
+	if err := dec.Decode(&res); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return normalizeDecoded(res)
+}
+
+func yamlDecode(text string) (any, error) {
+	var res any
+	if err := yaml.Unmarshal([]byte(text), &res); err != nil {
+		return nil, err
+	}
+	return normalizeDecoded(res)
+}
+
+// normalizeDecoded converts the output of the JSON and YAML decoders into
+// types which can be represented as values: JSON numbers become int or
+// float64, YAML timestamps become RFC 3339 strings and YAML maps must have
+// string keys.
+func normalizeDecoded(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.Atoi(string(v)); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+
+	case []any:
+		for i, elem := range v {
+			res, err := normalizeDecoded(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = res
+		}
+		return v, nil
+
+	case map[string]any:
+		for key, elem := range v {
+			res, err := normalizeDecoded(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = res
+		}
+		return v, nil
+
+	case map[any]any:
+		res := make(map[string]any, len(v))
+		for key, elem := range v {
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("object keys must be strings, got %v", key)
+			}
+			norm, err := normalizeDecoded(elem)
+			if err != nil {
+				return nil, err
+			}
+			res[s] = norm
+		}
+		return res, nil
+
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,119 @@
+package stdlib_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/alloytypes"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+func TestStdlib(t *testing.T) {
+	t.Setenv("STDLIB_TEST_VAR", "hello")
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("contents"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	scope := vm.NewScope(nil, map[string]any{
+		"path":   path,
+		"secret": alloytypes.Secret("hunter2"),
+	})
+
+	tt := []struct {
+		input  string
+		expect any
+	}{
+		{`env("STDLIB_TEST_VAR")`, "hello"},
+		{`env("STDLIB_TEST_UNSET")`, ""},
+		{`sys.env("STDLIB_TEST_VAR")`, "hello"},
+		{`file.read(path)`, "contents"},
+
+		{`coalesce(null, "", "first", "second")`, "first"},
+		{`coalesce([], {}, 0, false)`, false},
+		{`coalesce()`, nil},
+		{`nonsensitive(secret)`, "hunter2"},
+
+		{`json_decode("{\"a\": [1, 2.5, true, null]}")`, map[string]any{"a": []any{1, 2.5, true, nil}}},
+		{`yaml_decode("a:\n  - 1\n  - text\nt: 2001-12-14\n")`, map[string]any{"a": []any{1, "text"}, "t": "2001-12-14T00:00:00Z"}},
+
+		{`base64_encode("alloy")`, "YWxsb3k="},
+		{`base64_decode("YWxsb3k=")`, "alloy"},
+
+		{`string.format("%s-%d", "port", 80)`, "port-80"},
+		{`string.format("100%% %5.1f %v %t", 2.25, [1], true)`, "100%   2.2 [1] true"},
+		{`string.format("%.1f %x", 3, 255)`, "3.0 ff"},
+		{`string.join(["a", "b", "c"], ",")`, "a,b,c"},
+		{`string.split("a,b,c", ",")`, []any{"a", "b", "c"}},
+		{`string.replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`string.trim("__a__", "_")`, "a"},
+		{`string.trim_prefix("prefix_a", "prefix_")`, "a"},
+		{`string.trim_suffix("a_suffix", "_suffix")`, "a"},
+		{`string.trim_space("  a  ")`, "a"},
+
+		{`array.concat([1, 2], [], ["three"])`, []any{1, 2, "three"}},
+		{`array.concat()`, []any{}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			expr, err := parser.ParseExpression(tc.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			var actual any
+			if err := vm.New(expr).Evaluate(scope, &actual); err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if !reflect.DeepEqual(tc.expect, actual) {
+				t.Errorf("expected %#v, got %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestStdlib_Errors(t *testing.T) {
+	tt := []struct {
+		input  string
+		expect string
+	}{
+		{`env(1)`, `1:5: expected string, got number`},
+		{`env()`, `1:1: expected 1 arguments, got 0`},
+		{`string.join([1], ",")`, `1:14: expected string, got number`},
+		{`base64_decode("!")`, `1:1: illegal base64 data at input byte 0`},
+		{`json_decode("{")`, `1:1: unexpected EOF`},
+		{`yaml_decode("1: a")`, `1:1: object keys must be strings, got 1`},
+		{`file.read("/does/not/exist")`, `1:1: open /does/not/exist: no such file or directory`},
+		{`"env"()`, `1:1: expected function, got string`},
+		{`string.format("%d")`, `1:1: format "%d": expected 1 arguments, got 0`},
+		{`string.format("%s", "a", "b")`, `1:1: format "%s": expected 1 arguments, got 2`},
+		{`string.format("%d", "a")`, `1:1: format "%d": argument 1: %d expects an integer, got string`},
+		{`string.format("%d", 1.5)`, `1:1: format "%d": argument 1: %d expects an integer, got 1.5`},
+		{`string.format("%s", 1)`, `1:1: format "%s": argument 1: %s expects a string, got number`},
+		{`string.format("%[1]s", "a")`, `1:1: format "%[1]s": [ in verbs is not supported`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			expr, err := parser.ParseExpression(tc.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			var actual any
+			err = vm.New(expr).Evaluate(vm.NewScope(nil, nil), &actual)
+			if err == nil {
+				t.Fatalf("expected error %q, got none", tc.expect)
+			}
+			if !strings.Contains(err.Error(), tc.expect) {
+				t.Errorf("expected error %q, got %q", tc.expect, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Package syntaxtags decodes the struct tags used to map configuration
+// attributes and blocks onto Go struct fields.
+//
+// Struct fields are tagged with `syntax:"name,flags"`, where flags is a
+// comma-separated list of:
+//
+//   - attr: the field is decoded from an attribute.
+//   - block: the field is decoded from one or more blocks.
+//   - label: the field holds the block label. Label fields have no name.
+//   - optional: the attribute or block may be omitted.
+//
+// Block names may contain dots to match nested block names, such as
+// `syntax:"tls.config,block"`. Fields without a syntax tag are ignored.
+package syntaxtags
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jharvey10/test-repo/syntax/scanner"
+)
+
+// Flags is a set of options for a Field.
+type Flags uint
+
+// Supported Flags values.
+const (
+	FlagAttr Flags = 1 << iota
+	FlagBlock
+	FlagLabel
+	FlagOptional
+)
+
+// Field is a tagged struct field.
+type Field struct {
+	// Name of the field. Block names may have multiple parts. Label fields
+	// have no name.
+	Name []string
+
+	// Index of the field in the struct, suitable for
+	// reflect.Value.FieldByIndex.
+	Index []int
+
+	Flags Flags
+}
+
+// IsAttr reports whether f is decoded from an attribute.
+func (f Field) IsAttr() bool { return f.Flags&FlagAttr != 0 }
+
+// IsBlock reports whether f is decoded from a block.
+func (f Field) IsBlock() bool { return f.Flags&FlagBlock != 0 }
+
+// IsLabel reports whether f holds the block label.
+func (f Field) IsLabel() bool { return f.Flags&FlagLabel != 0 }
+
+// IsOptional reports whether f may be omitted.
+func (f Field) IsOptional() bool { return f.Flags&FlagOptional != 0 }
+
+// FullName returns the dot-separated name of f.
+func (f Field) FullName() string { return strings.Join(f.Name, ".") }
+
+var cache sync.Map // map[reflect.Type][]Field
+
+// Get returns the tagged fields of the struct type ty. Get panics if ty is
+// not a struct or if any of its tags are invalid, as that is always a
+// programming error.
+func Get(ty reflect.Type) []Field {
+	if cached, ok := cache.Load(ty); ok {
+		return cached.([]Field)
+	}
+	fields := parseFields(ty)
+	cache.Store(ty, fields)
+	return fields
+}
+
+func parseFields(ty reflect.Type) []Field {
+	if ty.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("syntaxtags: Get requires struct kind, got %s", ty.Kind()))
+	}
+
+	var (
+		fields   []Field
+		seen     = make(map[string]string)
+		hasLabel bool
+	)
+
+	for i := 0; i < ty.NumField(); i++ {
+		sf := ty.Field(i)
+		tag, ok := sf.Tag.Lookup("syntax")
+		if !ok {
+			continue
+		}
+		if !sf.IsExported() {
+			panic(fmt.Sprintf("syntaxtags: syntax tag on unexported field %s.%s", ty, sf.Name))
+		}
+
+		field, err := parseTag(tag)
+		if err != nil {
+			panic(fmt.Sprintf("syntaxtags: invalid tag on field %s.%s: %s", ty, sf.Name, err))
+		}
+		field.Index = sf.Index
+
+		if field.IsLabel() {
+			if hasLabel {
+				panic(fmt.Sprintf("syntaxtags: %s has more than one label field", ty))
+			}
+			if sf.Type.Kind() != reflect.String {
+				panic(fmt.Sprintf("syntaxtags: label field %s.%s must be a string", ty, sf.Name))
+			}
+			hasLabel = true
+		} else {
+			name := field.FullName()
+			if other, exists := seen[name]; exists {
+				panic(fmt.Sprintf("syntaxtags: %q is used by both %s.%s and %s.%s", name, ty, other, ty, sf.Name))
+			}
+			seen[name] = sf.Name
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+func parseTag(tag string) (Field, error) {
+	parts := strings.Split(tag, ",")
+	if len(parts) < 2 {
+		return Field{}, fmt.Errorf("expected name and flags, got %q", tag)
+	}
+
+	var field Field
+	for _, flag := range parts[1:] {
+		switch flag {
+		case "attr":
+			field.Flags |= FlagAttr
+		case "block":
+			field.Flags |= FlagBlock
+		case "label":
+			field.Flags |= FlagLabel
+		case "optional":
+			field.Flags |= FlagOptional
+		default:
+			return Field{}, fmt.Errorf("unrecognized flag %q", flag)
+		}
+	}
+
+	switch kind := field.Flags &^ FlagOptional; kind {
+	case FlagAttr, FlagBlock:
+	case FlagLabel:
+		if parts[0] != "" {
+			return Field{}, fmt.Errorf("label fields must not have a name")
+		}
+		if field.IsOptional() {
+			return Field{}, fmt.Errorf("label fields cannot be optional")
+		}
+		return field, nil
+	default:
+		return Field{}, fmt.Errorf("exactly one of attr, block or label must be set")
+	}
+
+	if parts[0] == "" {
+		return Field{}, fmt.Errorf("missing name")
+	}
+	field.Name = strings.Split(parts[0], ".")
+	if field.IsAttr() && len(field.Name) > 1 {
+		return Field{}, fmt.Errorf("attribute name %q must not contain dots", parts[0])
+	}
+	for _, part := range field.Name {
+		if !scanner.IsValidIdentifier(part) {
+			return Field{}, fmt.Errorf("%q is not a valid identifier", parts[0])
+		}
+	}
+	return field, nil
+}
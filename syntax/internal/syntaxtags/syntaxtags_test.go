@@ -0,0 +1,66 @@
+package syntaxtags_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/internal/syntaxtags"
+)
+
+func TestGet(t *testing.T) {
+	type Struct struct {
+		IgnoreMe bool
+
+		Label    string   `syntax:",label"`
+		Required string   `syntax:"required,attr"`
+		Optional string   `syntax:"optional,attr,optional"`
+		Block    struct{} `syntax:"tls.config,block,optional"`
+	}
+
+	expect := []syntaxtags.Field{
+		{Index: []int{1}, Flags: syntaxtags.FlagLabel},
+		{Name: []string{"required"}, Index: []int{2}, Flags: syntaxtags.FlagAttr},
+		{Name: []string{"optional"}, Index: []int{3}, Flags: syntaxtags.FlagAttr | syntaxtags.FlagOptional},
+		{Name: []string{"tls", "config"}, Index: []int{4}, Flags: syntaxtags.FlagBlock | syntaxtags.FlagOptional},
+	}
+
+	actual := syntaxtags.Get(reflect.TypeOf(Struct{}))
+	if !reflect.DeepEqual(expect, actual) {
+		t.Errorf("expected %#v, got %#v", expect, actual)
+	}
+}
+
+func TestGet_Invalid(t *testing.T) {
+	tt := map[string]any{
+		"missing flags": struct {
+			A string `syntax:"a"`
+		}{},
+		"unknown flag": struct {
+			A string `syntax:"a,attr,squash"`
+		}{},
+		"attr and block": struct {
+			A string `syntax:"a,attr,block"`
+		}{},
+		"named label": struct {
+			A string `syntax:"a,label"`
+		}{},
+		"dotted attr": struct {
+			A string `syntax:"a.b,attr"`
+		}{},
+		"duplicate name": struct {
+			A string `syntax:"a,attr"`
+			B string `syntax:"a,attr"`
+		}{},
+	}
+
+	for name, v := range tt {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected Get to panic")
+				}
+			}()
+			syntaxtags.Get(reflect.TypeOf(v))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package value
+
+import (
+	"reflect"
+)
+
+// Capsule is a marker interface for Go types which are passed through
+// expressions as opaque values. Capsules can be stored in attributes and
+// passed to functions or other components, but their contents are never
+// exposed as data.
+//
+// Besides types implementing Capsule, channels, complex numbers, maps
+// whose keys aren't strings, interfaces with methods, and structs without
+// syntax struct tags (or pointers to them) are always treated as capsules.
+type Capsule interface {
+	SyntaxCapsule()
+}
+
+var goCapsule = reflect.TypeOf((*Capsule)(nil)).Elem()
+
+// isCapsuleType reports whether values of t are represented as capsules.
+func isCapsuleType(t reflect.Type) bool {
+	if t == goValue {
+		return false
+	}
+	if t.Implements(goCapsule) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Map:
+		return t.Key().Kind() != reflect.String
+	case reflect.Interface:
+		return t.NumMethod() > 0
+	case reflect.Pointer:
+		return isCapsuleType(t.Elem())
+	case reflect.Struct:
+		return !hasSyntaxTags(t)
+	}
+	return false
+}
+
+func hasSyntaxTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("syntax"); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/jharvey10/test-repo/syntax/internal/syntaxtags"
+)
+
+// Defaulter is implemented by types which have default values. SetToDefault
+// is called before a value of the type is decoded.
+type Defaulter interface {
+	SetToDefault()
+}
+
+// Validator is implemented by types which validate themselves once they
+// have been decoded.
+type Validator interface {
+	Validate() error
+}
+
+// Unmarshaler is implemented by types which customize their decoding.
+// UnmarshalSyntax is called with a function f which decodes the original
+// value into its argument, which must be a pointer.
+type Unmarshaler interface {
+	UnmarshalSyntax(f func(v any) error) error
+}
+
+var (
+	goDefaulter   = reflect.TypeOf((*Defaulter)(nil)).Elem()
+	goValidator   = reflect.TypeOf((*Validator)(nil)).Elem()
+	goUnmarshaler = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// Decode assigns a Value val to a Go pointer target. Pointers will be
+// allocated as necessary when decoding.
+//
+// Decoding into an empty interface assigns the natural Go representation of
+// val, as returned by Value.Interface. Numbers are converted into the target
+// numeric type as long as no precision is lost. Objects can be decoded into
+// structs whose fields are tagged with syntax struct tags.
+//
+// Targets implementing Unmarshaler decode themselves. Otherwise, targets
+// implementing Defaulter are reset to their defaults before decoding and
+// targets implementing Validator are validated afterwards.
+func Decode(val Value, target any) error {
+	rt := reflect.ValueOf(target)
+	if rt.Kind() != reflect.Pointer || rt.IsNil() {
+		panic("syntax/value: Decode called with non-pointer value")
+	}
+	return decode(val, rt.Elem())
+}
+
+func decode(val Value, into reflect.Value) error {
+	// Capsules and functions are assigned directly when their Go types
+	// allow it, before any pointers are dereferenced.
+	if (val.Type() == TypeCapsule || val.Type() == TypeFunction) && val.rv.Type().AssignableTo(into.Type()) {
+		into.Set(val.rv)
+		return nil
+	}
+
+	// Allocate pointers as needed, unless val is null.
+	if into.Kind() == reflect.Pointer {
+		if val.Type() == TypeNull {
+			into.Set(reflect.Zero(into.Type()))
+			return nil
+		}
+		if into.IsNil() {
+			into.Set(reflect.New(into.Type().Elem()))
+		}
+		return decode(val, into.Elem())
+	}
+
+	if into.Type() == goValue {
+		into.Set(reflect.ValueOf(val))
+		return nil
+	}
+
+	if into.CanAddr() && into.Addr().Type().Implements(goUnmarshaler) {
+		return into.Addr().Interface().(Unmarshaler).UnmarshalSyntax(func(v any) error {
+			return Decode(val, v)
+		})
+	}
+
+	if into.CanAddr() && into.Addr().Type().Implements(goDefaulter) {
+		into.Set(reflect.Zero(into.Type()))
+		into.Addr().Interface().(Defaulter).SetToDefault()
+	}
+	if err := decodeValue(val, into); err != nil {
+		return err
+	}
+	if into.CanAddr() && into.Addr().Type().Implements(goValidator) {
+		if err := into.Addr().Interface().(Validator).Validate(); err != nil {
+			return Error{Value: val, Inner: err}
+		}
+	}
+	return nil
+}
+
+func decodeValue(val Value, into reflect.Value) error {
+	if into.Kind() == reflect.Interface && into.NumMethod() == 0 {
+		if val.Type() == TypeNull {
+			into.Set(reflect.Zero(into.Type()))
+			return nil
+		}
+		into.Set(reflect.ValueOf(val.Interface()))
+		return nil
+	}
+
+	if val.Type() == TypeNull {
+		into.Set(reflect.Zero(into.Type()))
+		return nil
+	}
+
+	if isCapsuleType(into.Type()) {
+		// Strings may be converted into capsules which are strings
+		// underneath, such as secrets. The reverse is not allowed.
+		if val.Type() == TypeString && into.Kind() == reflect.String {
+			into.SetString(val.Text())
+			return nil
+		}
+		if val.Type() == TypeCapsule {
+			return Errorf(val, "expected capsule(%q), got %s", into.Type(), val.Describe())
+		}
+		return Errorf(val, "expected capsule(%q), got %s", into.Type(), val.Type())
+	}
+
+	switch into.Kind() {
+	case reflect.Bool:
+		if val.Type() != TypeBool {
+			return TypeError{Value: val, Expected: TypeBool}
+		}
+		into.SetBool(val.Bool())
+		return nil
+
+	case reflect.String:
+		if val.Type() != TypeString {
+			return TypeError{Value: val, Expected: TypeString}
+		}
+		into.SetString(val.Text())
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		if val.Type() != TypeNumber {
+			return TypeError{Value: val, Expected: TypeNumber}
+		}
+		return decodeNumber(val, into)
+
+	case reflect.Slice:
+		if val.Type() != TypeArray {
+			return TypeError{Value: val, Expected: TypeArray}
+		}
+		res := reflect.MakeSlice(into.Type(), val.Len(), val.Len())
+		for i := 0; i < val.Len(); i++ {
+			if err := decode(val.Index(i), res.Index(i)); err != nil {
+				return ElementError{Value: val, Index: i, Inner: err}
+			}
+		}
+		into.Set(res)
+		return nil
+
+	case reflect.Array:
+		if val.Type() != TypeArray {
+			return TypeError{Value: val, Expected: TypeArray}
+		}
+		if val.Len() != into.Len() {
+			return Errorf(val, "array must have exactly %d elements, got %d", into.Len(), val.Len())
+		}
+		for i := 0; i < val.Len(); i++ {
+			if err := decode(val.Index(i), into.Index(i)); err != nil {
+				return ElementError{Value: val, Index: i, Inner: err}
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if into.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if val.Type() != TypeObject {
+			return TypeError{Value: val, Expected: TypeObject}
+		}
+		res := reflect.MakeMapWithSize(into.Type(), val.Len())
+		for _, key := range val.Keys() {
+			field, _ := val.Key(key)
+			elem := reflect.New(into.Type().Elem()).Elem()
+			if err := decode(field, elem); err != nil {
+				return FieldError{Value: val, Field: key, Inner: err}
+			}
+			res.SetMapIndex(reflect.ValueOf(key).Convert(into.Type().Key()), elem)
+		}
+		into.Set(res)
+		return nil
+
+	case reflect.Struct:
+		if val.Type() != TypeObject {
+			return TypeError{Value: val, Expected: TypeObject}
+		}
+		return decodeStruct(val, into)
+
+	case reflect.Func:
+		if val.Type() != TypeFunction {
+			return TypeError{Value: val, Expected: TypeFunction}
+		}
+		fn, err := wrapFunc(val, into.Type())
+		if err != nil {
+			return err
+		}
+		into.Set(fn)
+		return nil
+	}
+
+	panic(fmt.Sprintf("syntax/value: cannot decode into Go type %s", into.Type()))
+}
+
+// decodeStruct decodes an object into a struct with syntax struct tags.
+// Blocks are decoded from fields of the same name, and label fields are
+// ignored.
+func decodeStruct(val Value, into reflect.Value) error {
+	fields := syntaxtags.Get(into.Type())
+
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if f.IsLabel() {
+			continue
+		}
+		name := f.FullName()
+		known[name] = true
+
+		field, ok := val.Key(name)
+		if !ok {
+			if !f.IsOptional() {
+				return Errorf(val, "missing required attribute %q", name)
+			}
+			continue
+		}
+		if err := decode(field, into.FieldByIndex(f.Index)); err != nil {
+			return FieldError{Value: val, Field: name, Inner: err}
+		}
+	}
+
+	for _, key := range val.Keys() {
+		if !known[key] {
+			return Errorf(val, "unrecognized attribute name %q", key)
+		}
+	}
+	return nil
+}
+
+func decodeNumber(val Value, into reflect.Value) error {
+	num := val.Number()
+
+	switch into.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := num.Int()
+		if !ok || into.OverflowInt(i) {
+			return Errorf(val, "%s cannot be represented as %s", num, into.Type())
+		}
+		into.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, ok := num.Uint()
+		if !ok || into.OverflowUint(u) {
+			return Errorf(val, "%s cannot be represented as %s", num, into.Type())
+		}
+		into.SetUint(u)
+
+	default:
+		f := num.Float()
+		if into.Kind() == reflect.Float32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+			return Errorf(val, "%s cannot be represented as %s", num, into.Type())
+		}
+		into.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package value
+
+import "fmt"
+
+// TypeError is used for reporting on a value having an unexpected type.
+type TypeError struct {
+	// Value which caused the error.
+	Value    Value
+	Expected Type
+}
+
+// Error returns the string form of the TypeError.
+func (te TypeError) Error() string {
+	return fmt.Sprintf("expected %s, got %s", te.Expected, te.Value.Type())
+}
+
+// ElementError is used to report on an error inside of an array.
+type ElementError struct {
+	Value Value // The Array value
+	Index int   // The index of the element with the issue
+	Inner error // The error from the element
+}
+
+// Error returns the text of the inner error.
+func (ee ElementError) Error() string { return ee.Inner.Error() }
+
+// Unwrap returns the inner error.
+func (ee ElementError) Unwrap() error { return ee.Inner }
+
+// FieldError is used to report on an invalid field inside an object.
+type FieldError struct {
+	Value Value  // The Object value
+	Field string // The field name with the issue
+	Inner error  // The error from the field
+}
+
+// Error returns the text of the inner error.
+func (fe FieldError) Error() string { return fe.Inner.Error() }
+
+// Unwrap returns the inner error.
+func (fe FieldError) Unwrap() error { return fe.Inner }
+
+// Error is used for reporting on a value-level error. It is similar to
+// TypeError, but for errors which aren't related to the type of a value.
+type Error struct {
+	Value Value
+	Inner error
+}
+
+// Errorf creates a new Error for v with the given message.
+func Errorf(v Value, format string, args ...any) Error {
+	return Error{Value: v, Inner: fmt.Errorf(format, args...)}
+}
+
+// Error returns the message of the inner error.
+func (err Error) Error() string { return err.Inner.Error() }
+
+// Unwrap returns the inner error.
+func (err Error) Unwrap() error { return err.Inner }
@@ -0,0 +1,139 @@
+package value
+
+import (
+	"reflect"
+)
+
+var goError = reflect.TypeOf((*error)(nil)).Elem()
+
+// ArgError is used to report on an invalid argument to a function call.
+type ArgError struct {
+	Function Value // The function being called
+	Argument Value // The invalid argument
+	Index    int   // Index of the argument
+	Inner    error // The error from the argument
+}
+
+// Error returns the text of the inner error.
+func (ae ArgError) Error() string { return ae.Inner.Error() }
+
+// Unwrap returns the inner error.
+func (ae ArgError) Unwrap() error { return ae.Inner }
+
+// callError is the panic value of a function value decoded into a Go
+// function without an error result, when calling it fails. Call recovers it
+// and returns the error.
+type callError struct{ err error }
+
+// callable reports whether ft returns exactly one value, optionally
+// followed by an error.
+func callable(ft reflect.Type) bool {
+	return ft.NumOut() == 1 || (ft.NumOut() == 2 && ft.Out(1) == goError)
+}
+
+// Call invokes the function fn with args. Arguments are decoded into the
+// Go types of fn's parameters, so functions declare the types they accept
+// in their signatures. fn must return exactly one value, optionally
+// followed by an error; Call returns an error for other signatures. Call
+// panics if fn is not a function.
+func Call(fn Value, args []Value) (res Value, err error) {
+	fn.mustBe(TypeFunction)
+
+	ft := fn.rv.Type()
+	if !callable(ft) {
+		return Null, Errorf(fn, "cannot call function of Go type %s: it must return a value, optionally followed by an error", ft)
+	}
+
+	numIn := ft.NumIn()
+	switch {
+	case ft.IsVariadic() && len(args) < numIn-1:
+		return Null, Errorf(fn, "expected at least %d arguments, got %d", numIn-1, len(args))
+	case !ft.IsVariadic() && len(args) != numIn:
+		return Null, Errorf(fn, "expected %d arguments, got %d", numIn, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var argType reflect.Type
+		if ft.IsVariadic() && i >= numIn-1 {
+			argType = ft.In(numIn - 1).Elem()
+		} else {
+			argType = ft.In(i)
+		}
+
+		in[i] = reflect.New(argType).Elem()
+		if err := decode(arg, in[i]); err != nil {
+			return Null, ArgError{Function: fn, Argument: arg, Index: i, Inner: err}
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ce, ok := r.(callError)
+			if !ok {
+				panic(r)
+			}
+			res, err = Null, Error{Value: fn, Inner: ce.err}
+		}
+	}()
+	out := fn.rv.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return Null, Error{Value: fn, Inner: out[1].Interface().(error)}
+	}
+	return makeValue(out[0]), nil
+}
+
+// wrapFunc adapts the function value fn into a Go function of type ft.
+// Arguments are converted into values and the result is decoded into ft's
+// return type, so functions with different but compatible signatures can
+// be assigned to each other.
+//
+// ft must return one value, optionally followed by an error, and must
+// return an error if fn does. Errors from the call are returned through the
+// error result; if ft has no error result, the function panics with a
+// callError, which Call recovers when the function is called from an
+// expression.
+func wrapFunc(fn Value, ft reflect.Type) (reflect.Value, error) {
+	if !callable(ft) {
+		return reflect.Value{}, Errorf(fn, "cannot decode function into Go type %s: it must return a value, optionally followed by an error", ft)
+	}
+	if ft.NumOut() == 1 && fn.rv.Type().NumOut() == 2 {
+		return reflect.Value{}, Errorf(fn, "cannot decode function into Go type %s: the function can fail, so it must also return an error", ft)
+	}
+
+	return reflect.MakeFunc(ft, func(in []reflect.Value) []reflect.Value {
+		var args []Value
+		for i, arg := range in {
+			if ft.IsVariadic() && i == len(in)-1 {
+				for j := 0; j < arg.Len(); j++ {
+					args = append(args, makeValue(arg.Index(j)))
+				}
+				continue
+			}
+			args = append(args, makeValue(arg))
+		}
+
+		out := []reflect.Value{reflect.New(ft.Out(0)).Elem()}
+		res, err := Call(fn, args)
+		if argErr, ok := err.(ArgError); ok {
+			// The index of an invalid argument refers to the wrapped
+			// call, not to the call the error is returned from.
+			err = argErr.Inner
+		}
+		if err == nil {
+			err = decode(res, out[0])
+		}
+
+		if ft.NumOut() == 1 {
+			if err != nil {
+				panic(callError{err: err})
+			}
+			return out
+		}
+		errValue := reflect.New(goError).Elem()
+		if err != nil {
+			errValue.Set(reflect.ValueOf(err))
+		}
+		return append(out, errValue)
+	}), nil
+}
@@ -0,0 +1,134 @@
+package value
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// NumberKind categorizes a number by the Go type it is stored as.
+type NumberKind uint8
+
+// Supported NumberKind values.
+const (
+	NumberKindInt NumberKind = iota
+	NumberKindUint
+	NumberKindFloat
+)
+
+// Number is a generic representation of Go numbers.
+type Number struct {
+	kind NumberKind
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func newNumberFromValue(rv reflect.Value) Number {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Number{kind: NumberKindInt, i: rv.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Number{kind: NumberKindUint, u: rv.Uint()}
+	default:
+		return Number{kind: NumberKindFloat, f: rv.Float()}
+	}
+}
+
+// Kind returns the kind of n.
+func (n Number) Kind() NumberKind { return n.kind }
+
+// Int returns n as an int64 and reports whether it could be converted
+// exactly.
+func (n Number) Int() (int64, bool) {
+	switch n.kind {
+	case NumberKindInt:
+		return n.i, true
+	case NumberKindUint:
+		return int64(n.u), n.u <= math.MaxInt64
+	default:
+		i := int64(n.f)
+		return i, float64(i) == n.f && !math.IsInf(n.f, 0)
+	}
+}
+
+// Uint returns n as a uint64 and reports whether it could be converted
+// exactly.
+func (n Number) Uint() (uint64, bool) {
+	switch n.kind {
+	case NumberKindInt:
+		return uint64(n.i), n.i >= 0
+	case NumberKindUint:
+		return n.u, true
+	default:
+		u := uint64(n.f)
+		return u, n.f >= 0 && float64(u) == n.f && !math.IsInf(n.f, 0)
+	}
+}
+
+// Float returns n as a float64.
+func (n Number) Float() float64 {
+	switch n.kind {
+	case NumberKindInt:
+		return float64(n.i)
+	case NumberKindUint:
+		return float64(n.u)
+	default:
+		return n.f
+	}
+}
+
+// IsIntegral reports whether n holds a whole number that fits in an int64.
+func (n Number) IsIntegral() bool {
+	if n.kind == NumberKindFloat {
+		return false
+	}
+	_, ok := n.Int()
+	return ok
+}
+
+// Value converts n back into a Value.
+func (n Number) Value() Value {
+	switch n.kind {
+	case NumberKindInt:
+		return Int(n.i)
+	case NumberKindUint:
+		return Uint(n.u)
+	default:
+		return Float(n.f)
+	}
+}
+
+// String returns the text form of n.
+func (n Number) String() string {
+	switch n.kind {
+	case NumberKindInt:
+		return strconv.FormatInt(n.i, 10)
+	case NumberKindUint:
+		return strconv.FormatUint(n.u, 10)
+	default:
+		return strconv.FormatFloat(n.f, 'f', -1, 64)
+	}
+}
+
+// ParseNumber parses the text of a NUMBER literal. Values which do not fit
+// in an int64 are parsed as a uint64.
+func ParseNumber(s string) (Value, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Int(i), nil
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return Null, err
+	}
+	return Uint(u), nil
+}
+
+// ParseFloat parses the text of a FLOAT literal.
+func ParseFloat(s string) (Value, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Null, err
+	}
+	return Float(f), nil
+}
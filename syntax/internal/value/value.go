@@ -0,0 +1,319 @@
+// Package value holds the internal representation of values used when
+// evaluating configuration expressions.
+//
+// A Value wraps a Go value and classifies it into one of the language's
+// types. Values produced by Go code are wrapped lazily: an array or object
+// only has its elements converted when they are accessed.
+package value
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/jharvey10/test-repo/syntax/internal/syntaxtags"
+)
+
+// Type is the type of a Value.
+type Type uint8
+
+// Supported Type values.
+const (
+	TypeNull Type = iota
+	TypeNumber
+	TypeString
+	TypeBool
+	TypeArray
+	TypeObject
+	TypeFunction
+	TypeCapsule
+)
+
+var typeStrings = [...]string{
+	TypeNull:     "null",
+	TypeNumber:   "number",
+	TypeString:   "string",
+	TypeBool:     "bool",
+	TypeArray:    "array",
+	TypeObject:   "object",
+	TypeFunction: "function",
+	TypeCapsule:  "capsule",
+}
+
+// String returns the name of t.
+func (t Type) String() string {
+	if int(t) < len(typeStrings) {
+		return typeStrings[t]
+	}
+	return fmt.Sprintf("Type(%d)", t)
+}
+
+// GoString returns the name of t.
+func (t Type) GoString() string { return t.String() }
+
+// Value represents a value in the configuration language.
+type Value struct {
+	rv reflect.Value
+	ty Type
+}
+
+// Null is the null value.
+var Null = Value{}
+
+var goValue = reflect.TypeOf(Value{})
+
+// Int returns a Value from an int64.
+func Int(i int64) Value { return makeNumber(reflect.ValueOf(normalizeInt(i))) }
+
+// Uint returns a Value from a uint64.
+func Uint(u uint64) Value { return makeNumber(reflect.ValueOf(u)) }
+
+// Float returns a Value from a float64.
+func Float(f float64) Value { return makeNumber(reflect.ValueOf(f)) }
+
+// String returns a Value from a string.
+func String(s string) Value { return Value{rv: reflect.ValueOf(s), ty: TypeString} }
+
+// Bool returns a Value from a bool.
+func Bool(b bool) Value { return Value{rv: reflect.ValueOf(b), ty: TypeBool} }
+
+// Array creates an array from the given values.
+func Array(vv ...Value) Value {
+	if vv == nil {
+		vv = []Value{}
+	}
+	return Value{rv: reflect.ValueOf(vv), ty: TypeArray}
+}
+
+// Object returns a new object value from m.
+func Object(m map[string]Value) Value {
+	if m == nil {
+		m = map[string]Value{}
+	}
+	return Value{rv: reflect.ValueOf(m), ty: TypeObject}
+}
+
+// normalizeInt returns i as an int when it fits, so that integers produced
+// by literals and arithmetic share one Go type.
+func normalizeInt(i int64) any {
+	if int64(int(i)) == i {
+		return int(i)
+	}
+	return i
+}
+
+func makeNumber(rv reflect.Value) Value { return Value{rv: rv, ty: TypeNumber} }
+
+// Encode creates a new Value from v. If v is a pointer, v is dereferenced
+// before encoding. Go values with no equivalent in the syntax are encoded
+// as capsules.
+func Encode(v any) Value {
+	if v == nil {
+		return Null
+	}
+	return makeValue(reflect.ValueOf(v))
+}
+
+// makeValue converts a reflect.Value into a Value.
+func makeValue(rv reflect.Value) Value {
+	for {
+		if !rv.IsValid() {
+			return Null
+		}
+		if rv.Type() == goValue {
+			return rv.Interface().(Value)
+		}
+
+		// Capsules keep their pointers so that the identity of the wrapped
+		// Go value is preserved.
+		if isCapsuleType(rv.Type()) {
+			switch rv.Kind() {
+			case reflect.Interface:
+				if rv.IsNil() {
+					return Null
+				}
+				rv = rv.Elem()
+			case reflect.Pointer, reflect.Chan:
+				if rv.IsNil() {
+					return Null
+				}
+			}
+			return Value{rv: rv, ty: TypeCapsule}
+		}
+
+		// Unwrap pointers and interfaces.
+		if rv.Kind() != reflect.Pointer && rv.Kind() != reflect.Interface {
+			break
+		}
+		if rv.IsNil() {
+			return Null
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return makeNumber(rv)
+	case reflect.String:
+		return Value{rv: rv, ty: TypeString}
+	case reflect.Bool:
+		return Value{rv: rv, ty: TypeBool}
+	case reflect.Slice, reflect.Array:
+		return Value{rv: rv, ty: TypeArray}
+	case reflect.Map:
+		return Value{rv: rv, ty: TypeObject}
+	case reflect.Struct:
+		// Structs with syntax struct tags are objects keyed by their
+		// attribute and block names; other structs are capsules.
+		return Value{rv: rv, ty: TypeObject}
+	case reflect.Func:
+		return Value{rv: rv, ty: TypeFunction}
+	}
+
+	panic(fmt.Sprintf("syntax/value: cannot encode Go value of type %s", rv.Type()))
+}
+
+// Type returns the type of v.
+func (v Value) Type() Type { return v.ty }
+
+// Describe returns a short description of v for error messages, such as
+// `"foo"` or `array`.
+func (v Value) Describe() string {
+	switch v.ty {
+	case TypeNull:
+		return "null"
+	case TypeNumber:
+		return fmt.Sprint(v.rv.Interface())
+	case TypeString:
+		return fmt.Sprintf("%q", v.Text())
+	case TypeBool:
+		return fmt.Sprint(v.Bool())
+	case TypeCapsule:
+		return fmt.Sprintf("capsule(%q)", v.rv.Type())
+	default:
+		return v.ty.String()
+	}
+}
+
+// Bool returns the boolean value of v. It panics if v is not a bool.
+func (v Value) Bool() bool {
+	v.mustBe(TypeBool)
+	return v.rv.Bool()
+}
+
+// Number returns the number value of v. It panics if v is not a number.
+func (v Value) Number() Number {
+	v.mustBe(TypeNumber)
+	return newNumberFromValue(v.rv)
+}
+
+// Text returns the string value of v. It panics if v is not a string.
+func (v Value) Text() string {
+	v.mustBe(TypeString)
+	return v.rv.String()
+}
+
+// Len returns the length of v. It panics if v is not an array or object.
+func (v Value) Len() int {
+	switch {
+	case v.ty == TypeObject && v.rv.Kind() == reflect.Struct:
+		return len(v.structFields())
+	case v.ty == TypeArray, v.ty == TypeObject:
+		return v.rv.Len()
+	}
+	panic(fmt.Sprintf("syntax/value: Len called on %s value", v.ty))
+}
+
+// Index returns the element at index i of v. It panics if v is not an
+// array or if i is out of range.
+func (v Value) Index(i int) Value {
+	v.mustBe(TypeArray)
+	return makeValue(v.rv.Index(i))
+}
+
+// Keys returns the sorted keys of v. It panics if v is not an object.
+func (v Value) Keys() []string {
+	v.mustBe(TypeObject)
+
+	if v.rv.Kind() == reflect.Struct {
+		var keys []string
+		for _, f := range v.structFields() {
+			keys = append(keys, f.FullName())
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	keys := make([]string, 0, v.rv.Len())
+	for _, k := range v.rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Key returns the value of key in v. It panics if v is not an object. ok
+// is false if the key does not exist.
+func (v Value) Key(key string) (index Value, ok bool) {
+	v.mustBe(TypeObject)
+
+	if v.rv.Kind() == reflect.Struct {
+		for _, f := range v.structFields() {
+			if f.FullName() == key {
+				return makeValue(v.rv.FieldByIndex(f.Index)), true
+			}
+		}
+		return Null, false
+	}
+
+	val := v.rv.MapIndex(reflect.ValueOf(key).Convert(v.rv.Type().Key()))
+	if !val.IsValid() {
+		return Null, false
+	}
+	return makeValue(val), true
+}
+
+// Interface returns the Go representation of v: nil for null, the
+// underlying Go number, string, bool, function or capsule, []any for
+// arrays and map[string]any for objects.
+func (v Value) Interface() any {
+	switch v.ty {
+	case TypeNull:
+		return nil
+	case TypeNumber, TypeString, TypeBool, TypeFunction, TypeCapsule:
+		return v.rv.Interface()
+	case TypeArray:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = v.Index(i).Interface()
+		}
+		return out
+	case TypeObject:
+		out := make(map[string]any, v.Len())
+		for _, key := range v.Keys() {
+			val, _ := v.Key(key)
+			out[key] = val.Interface()
+		}
+		return out
+	}
+	panic(fmt.Sprintf("syntax/value: unhandled type %s", v.ty))
+}
+
+// structFields returns the fields of a struct object, excluding its label.
+func (v Value) structFields() []syntaxtags.Field {
+	var fields []syntaxtags.Field
+	for _, f := range syntaxtags.Get(v.rv.Type()) {
+		if !f.IsLabel() {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (v Value) mustBe(t Type) {
+	if v.ty != t {
+		panic(fmt.Sprintf("syntax/value: expected %s value, got %s", t, v.ty))
+	}
+}
@@ -1,44 +1,21 @@
 package syntax
 
-import (
-	"os"
-	"runtime"
-	"strings"
-
-	"golang.org/x/mod/semver"
-)
-
+// Version is the version of the binary. It is set at build time with
+// -ldflags "-X github.com/jharvey10/test-repo/syntax.Version=v1.2.3". When
+// it is not set, the version of the main module from the binary's build
+// information is used instead.
 var Version string
 
-var constants = map[string]string{
-	"hostname": "", // Initialized via init function
-	"os":       runtime.GOOS,
-	"arch":     runtime.GOARCH,
-	"version":  Version,
+// Greetings returns the messages printed by Main, so that programs with
+// their own logging can log them instead.
+func Greetings() []string {
+	return []string{"hello", "hello there wow"}
 }
 
 func Main() {
-	hostname, err := os.Hostname()
-	if err == nil {
-		constants["hostname"] = hostname
-	}
-	constants["version"] = normalizeVersion(Version)
+	initConstants()
 
-	print("hello")
-	print("hello there wow")
-}
-
-// normalizeVersion normalizes the version string to always contain a "v"
-// prefix. If version cannot be parsed as a semantic version, version is returned unmodified.
-//
-// if version is empty, normalizeVersion returns "v0.0.0".
-func normalizeVersion(version string) string {
-	version = strings.TrimSpace(version)
-	if version == "" {
-		return "v0.0.0"
+	for _, msg := range Greetings() {
+		print(msg)
 	}
-
-	parsed := semver.Canonical(version)
-
-	return "v" + parsed
 }
@@ -0,0 +1,39 @@
+// Package parser implements utilities for parsing configuration files.
+package parser
+
+import (
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// ParseFile parses an entire file and returns the corresponding AST.
+//
+// If the file contains syntax errors, ParseFile returns an ErrorList along
+// with a partial AST covering every statement which could be parsed.
+func ParseFile(filename string, src []byte) (*ast.File, error) {
+	p := newParser(filename, src)
+
+	body := p.parseBody(token.EOF)
+	p.errors.Sort()
+
+	return &ast.File{
+		Name:     filename,
+		Body:     body,
+		Comments: p.comments,
+	}, p.errors.Err()
+}
+
+// ParseExpression parses a single expression. expr must not have any
+// trailing tokens other than newlines.
+func ParseExpression(expr string) (ast.Expr, error) {
+	p := newParser("", []byte(expr))
+
+	e := p.ParseExpression()
+	p.skipTerminators()
+	if p.tok != token.EOF {
+		p.addErrorf(p.pos, "expected end of expression, got %s", p.describe())
+	}
+	p.errors.Sort()
+
+	return e, p.errors.Err()
+}
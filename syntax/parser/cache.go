@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"unicode/utf8"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// Cache parses successive versions of a configuration file incrementally.
+// It keeps the AST of each top-level statement keyed by a hash of the
+// statement's source text, so parsing a new version of the file only parses the
+// statements which changed.
+//
+// Unchanged statements are returned as the same AST nodes as before,
+// which lets evaluators skip them; see vm.Cache. Their positions are moved
+// to wherever the statement is in the new version, so positions in files
+// returned by earlier calls must not be relied on. A Cache must not be
+// used concurrently.
+type Cache struct {
+	filename string
+	stmts    map[[sha256.Size]byte]*cachedStmts
+}
+
+// cachedStmts is a parsed top-level statement along with the comments
+// preceding it and on its last line.
+type cachedStmts struct {
+	file     *token.File
+	body     ast.Body
+	comments []ast.CommentGroup
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{stmts: make(map[[sha256.Size]byte]*cachedStmts)}
+}
+
+// ParseFile is like the ParseFile function, but reuses the ASTs of
+// statements which did not change since the previous call. Statements
+// with syntax errors are never reused.
+//
+// Cached statements are dropped when filename changes, and when they are
+// not part of the latest version of the file.
+func (c *Cache) ParseFile(filename string, src []byte) (*ast.File, error) {
+	if filename != c.filename {
+		c.filename, c.stmts = filename, make(map[[sha256.Size]byte]*cachedStmts)
+	}
+
+	var (
+		f    = &ast.File{Name: filename}
+		errs ErrorList
+		next = make(map[[sha256.Size]byte]*cachedStmts, len(c.stmts))
+
+		start, line = 0, 1
+	)
+	for _, end := range statementEnds(src) {
+		text := src[start:end]
+		key := sha256.Sum256(text)
+
+		// Text which appears more than once is only cached once, since
+		// its AST can only be at one place in the file.
+		cached, ok := c.stmts[key]
+		if ok && next[key] == nil {
+			cached.file.SetBase(start, line)
+			next[key] = cached
+		} else {
+			var stmtErrs ErrorList
+			cached, stmtErrs = parseStatements(filename, text, start, line)
+			if len(stmtErrs) == 0 && next[key] == nil {
+				next[key] = cached
+			}
+			errs = append(errs, stmtErrs...)
+		}
+
+		f.Body = append(f.Body, cached.body...)
+		f.Comments = append(f.Comments, cached.comments...)
+		start, line = end, line+bytes.Count(text, []byte{'\n'})
+	}
+	c.stmts = next
+
+	errs.Sort()
+	return f, errs.Err()
+}
+
+// parseStatements parses text, which starts at the given offset and line
+// of the file.
+func parseStatements(filename string, text []byte, off, line int) (*cachedStmts, ErrorList) {
+	file := token.NewFile(filename)
+	file.SetBase(off, line)
+
+	p := newParserAt(file, text, 0)
+	body := p.parseBody(token.EOF)
+	return &cachedStmts{file: file, body: body, comments: p.comments}, p.errors
+}
+
+// statementEnds returns the offsets just past the end of every top-level
+// statement in src: the newlines which terminate them. The last offset is
+// always the end of src, so any trailing comments are included. Each
+// statement starts at the beginning of a line, so its leading comments
+// are kept with it.
+//
+// statementEnds only tracks nesting, strings and comments, and applies the
+// scanner's rule for which newlines are terminators. It is much cheaper
+// than scanning the file, since it does not produce tokens.
+func statementEnds(src []byte) []int {
+	var (
+		ends  []int
+		depth int
+		term  bool // Whether a newline here would be a terminator.
+	)
+	for i := 0; i < len(src); i++ {
+		switch ch := src[i]; ch {
+		case ' ', '\t', '\r':
+		case '\n':
+			if term && depth == 0 && i+1 < len(src) {
+				ends = append(ends, i+1)
+			}
+			term = false
+		case '{', '(', '[':
+			depth, term = depth+1, false
+		case '}', ')', ']':
+			depth, term = max(depth-1, 0), true
+		case '"', '`':
+			i = skipString(src, i)
+			term = true
+		case '#':
+			i = skipUntil(src, i, "\n") - 1
+		case '/':
+			switch {
+			case i+1 < len(src) && src[i+1] == '/':
+				i = skipUntil(src, i, "\n") - 1
+			case i+1 < len(src) && src[i+1] == '*':
+				i = skipUntil(src, i+2, "*/") + 1
+			default:
+				term = false
+			}
+		default:
+			// Identifiers, keywords and numbers allow a terminator after
+			// them; operators and other punctuation do not.
+			term = ch == '_' || ch >= utf8.RuneSelf ||
+				'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+		}
+	}
+	return append(ends, len(src))
+}
+
+// skipString returns the offset of the quote which closes the string
+// starting at src[start]. Unterminated strings end at the end of their
+// line, or at the end of src for raw strings.
+func skipString(src []byte, start int) int {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		switch {
+		case src[i] == quote:
+			return i
+		case quote == '"' && src[i] == '\\':
+			i++
+		case quote == '"' && src[i] == '\n':
+			return i - 1
+		}
+	}
+	return len(src) - 1
+}
+
+// skipUntil returns the offset of the first occurrence of sep at or after
+// start, or the end of src if there is none.
+func skipUntil(src []byte, start int, sep string) int {
+	if i := bytes.Index(src[start:], []byte(sep)); i >= 0 {
+		return start + i
+	}
+	return len(src)
+}
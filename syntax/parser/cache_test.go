@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+func TestCache_ParseFile(t *testing.T) {
+	v1 := `// Leading comment.
+a "x" {
+	value = [1, 2] // trailing
+}
+
+b "y" {
+	value = {
+		nested = true,
+	}
+}
+`
+	// Insert a statement at the top and change a.
+	v2 := `attr = 1
+
+// Leading comment.
+a "x" {
+	value = [1, 2, 3] // trailing
+}
+
+b "y" {
+	value = {
+		nested = true,
+	}
+}
+`
+
+	c := NewCache()
+	f1, err := c.ParseFile("config.alloy", []byte(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := c.ParseFile("config.alloy", []byte(v2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f2.Body[1] == f1.Body[0] {
+		t.Error("changed statement a was reused")
+	}
+	if f2.Body[2] != f1.Body[1] {
+		t.Error("unchanged statement b was not reused")
+	}
+
+	// The result, including the positions of reused statements, must
+	// match parsing the file from scratch.
+	expect, err := ParseFile("config.alloy", []byte(v2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := positions(f2), positions(expect); got != want {
+		t.Errorf("positions:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCache_ParseFile_Errors(t *testing.T) {
+	src := "a {\n\tb = [1 2]\n}\n\nc = \n"
+
+	c := NewCache()
+	for i := 0; i < 2; i++ {
+		_, err := c.ParseFile("config.alloy", []byte(src))
+		_, expect := ParseFile("config.alloy", []byte(src))
+		if err == nil || err.Error() != expect.Error() {
+			t.Errorf("parse %d: got error %v, want %v", i, err, expect)
+		}
+	}
+}
+
+// positions returns the position of every node and comment in f.
+func positions(f *ast.File) string {
+	var sb strings.Builder
+	ast.Walk(positionWriter{&sb}, f)
+	for _, group := range f.Comments {
+		for _, comment := range group {
+			fmt.Fprintf(&sb, "%s %s\n", comment.StartPos.Position(), comment.Text)
+		}
+	}
+	return sb.String()
+}
+
+type positionWriter struct{ sb *strings.Builder }
+
+func (w positionWriter) Visit(n ast.Node) ast.Visitor {
+	if n != nil {
+		fmt.Fprintf(w.sb, "%T %s-%s\n", n, ast.StartPos(n).Position(), ast.EndPos(n).Position())
+	}
+	return w
+}
+
+// generateBlocks returns a file with n blocks, each of which has a few
+// attributes. Block i has the given value for its last attribute.
+func generateBlocks(n int, lastValue func(i int) string) []byte {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "prometheus.scrape \"tenant_%d\" {\n", i)
+		fmt.Fprintf(&sb, "\ttargets    = [{\"__address__\" = \"tenant-%d:9090\", \"tenant\" = \"%d\"}]\n", i, i)
+		fmt.Fprintf(&sb, "\tforward_to = [prometheus.remote_write.default.receiver]\n")
+		fmt.Fprintf(&sb, "\tinterval   = %s\n", lastValue(i))
+		sb.WriteString("}\n\n")
+	}
+	return []byte(sb.String())
+}
+
+func BenchmarkParseFile_10kBlocks(b *testing.B) {
+	src := generateBlocks(10000, func(int) string { return `"15s"` })
+	b.SetBytes(int64(len(src)))
+	for b.Loop() {
+		if _, err := ParseFile("config.alloy", src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCache_ParseFile_10kBlocks reparses a file with 10k blocks where
+// one block changes between versions.
+func BenchmarkCache_ParseFile_10kBlocks(b *testing.B) {
+	versions := make([][]byte, 2)
+	for v := range versions {
+		versions[v] = generateBlocks(10000, func(i int) string {
+			if i == 5000 {
+				return fmt.Sprintf(`"%ds"`, v+1)
+			}
+			return `"15s"`
+		})
+	}
+
+	c := NewCache()
+	if _, err := c.ParseFile("config.alloy", versions[0]); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(versions[0])))
+	i := 0
+	for b.Loop() {
+		i++
+		if _, err := c.ParseFile("config.alloy", versions[i%2]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
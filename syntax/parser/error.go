@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// Error is a syntax error found while parsing a file or expression.
+type Error struct {
+	StartPos token.Position
+	EndPos   token.Position
+	Message  string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.StartPos, e.Message)
+}
+
+// ErrorList is a list of syntax errors. The parser recovers from errors
+// where it can, so a single parse may report several of them.
+type ErrorList []*Error
+
+// Add appends an error to the list.
+func (l *ErrorList) Add(e *Error) { *l = append(*l, e) }
+
+// Sort sorts the list by position.
+func (l ErrorList) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].StartPos.Filename != l[j].StartPos.Filename {
+			return l[i].StartPos.Filename < l[j].StartPos.Filename
+		}
+		return l[i].StartPos.Offset < l[j].StartPos.Offset
+	})
+}
+
+// Error implements error. It returns every error in the list on a separate
+// line.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Diagnostics converts the list into diagnostics. It implements
+// diag.Diagnoser.
+func (l ErrorList) Diagnostics() diag.Diagnostics {
+	ds := make(diag.Diagnostics, len(l))
+	for i, e := range l {
+		ds[i] = diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			StartPos: e.StartPos,
+			EndPos:   e.EndPos,
+			Message:  e.Message,
+		}
+	}
+	return ds
+}
+
+// Err returns an error equivalent to this error list. If the list is empty,
+// Err returns nil.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
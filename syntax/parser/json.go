@@ -0,0 +1,476 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/scanner"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// ParseJSON parses a configuration file written in its JSON
+// representation and returns the corresponding AST. printer.FprintJSON
+// writes the JSON representation of an AST.
+//
+// A file is a JSON array of statements. Each statement is an object which
+// is either an attribute:
+//
+//	{"attribute": "name", "value": EXPR}
+//
+// or a block, whose "label" and "body" keys are optional:
+//
+//	{"block": "prometheus.scrape", "label": "default", "body": [STATEMENT, ...]}
+//
+// Each EXPR is an object whose "type" key determines the form of its
+// "value" key:
+//
+//	{"type": "null"}
+//	{"type": "bool", "value": true}
+//	{"type": "number", "value": 15.5}
+//	{"type": "string", "value": "text"}
+//	{"type": "array", "value": [EXPR, ...]}
+//	{"type": "object", "value": [{"key": "name", "value": EXPR}, ...]}
+//	{"type": "expr", "value": "env(\"HOME\") + \"/data\""}
+//
+// Object fields are a list rather than a JSON object so their order is
+// kept. An "expr" value is any expression in the configuration language,
+// which is how references, function calls and operators are represented.
+//
+// Positions in the returned AST and in errors refer to the JSON document.
+// The JSON representation has no comments. As with ParseFile, errors are
+// returned as an ErrorList along with a partial AST.
+func ParseJSON(filename string, src []byte) (*ast.File, error) {
+	p := &jsonParser{src: src, file: token.NewFile(filename)}
+	for i, ch := range src {
+		if ch == '\n' {
+			p.file.AddLine(i + 1)
+		}
+	}
+
+	f := &ast.File{Name: filename}
+	if root := p.read(); root != nil {
+		f.Body = p.parseBody(root)
+	}
+	p.errors.Sort()
+	return f, p.errors.Err()
+}
+
+// jsonParser converts a JSON document into an AST.
+type jsonParser struct {
+	src     []byte
+	file    *token.File
+	errors  ErrorList
+	scratch []byte // Buffer for parsing embedded expressions.
+}
+
+// jsonNode is a JSON value along with its location in the source.
+type jsonNode struct {
+	start, end int // Offset of the first byte and just past the last byte.
+
+	// One of nil, bool, json.Number, string, []*jsonNode or *jsonObject.
+	value any
+}
+
+// jsonObject is a JSON object whose keys are kept in order.
+type jsonObject struct {
+	keys   []*jsonNode
+	values []*jsonNode
+}
+
+// get returns the value of key, or nil if it is not set.
+func (o *jsonObject) get(key string) *jsonNode {
+	for i, k := range o.keys {
+		if k.value == key {
+			return o.values[i]
+		}
+	}
+	return nil
+}
+
+// read decodes the whole document, reporting an error and returning nil if
+// it is not valid JSON.
+func (p *jsonParser) read() *jsonNode {
+	dec := json.NewDecoder(bytes.NewReader(p.src))
+	dec.UseNumber()
+
+	root, err := p.readValue(dec)
+	if err == nil {
+		if _, err = dec.Token(); err == io.EOF {
+			return root
+		} else if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+	}
+
+	off := p.skip(dec)
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		off = max(int(syntaxErr.Offset)-1, 0)
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		off, err = len(p.src), errors.New("unexpected end of JSON input")
+	}
+	p.errorf(off, "%s", err)
+	return nil
+}
+
+// skip returns the offset of the next token of dec, skipping whitespace and
+// the separators which the decoder does not return as tokens.
+func (p *jsonParser) skip(dec *json.Decoder) int {
+	off := int(dec.InputOffset())
+	for off < len(p.src) && strings.IndexByte(" \t\r\n,:", p.src[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+func (p *jsonParser) readValue(dec *json.Decoder) (*jsonNode, error) {
+	n := &jsonNode{start: p.skip(dec)}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		elems := []*jsonNode{}
+		for dec.More() {
+			elem, err := p.readValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		n.value = elems
+
+	case json.Delim('{'):
+		obj := &jsonObject{}
+		for dec.More() {
+			key, err := p.readValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.readValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key)
+			obj.values = append(obj.values, value)
+		}
+		n.value = obj
+
+	default:
+		n.value = tok
+	}
+
+	if _, ok := tok.(json.Delim); ok {
+		// Consume the closing delimiter.
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	}
+	n.end = int(dec.InputOffset())
+	return n, nil
+}
+
+func (p *jsonParser) pos(off int) token.Pos { return p.file.Pos(off) }
+
+func (p *jsonParser) errorf(off int, format string, args ...any) {
+	pos := p.pos(off).Position()
+	p.errors.Add(&Error{
+		StartPos: pos,
+		EndPos:   pos,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// describe returns the kind of a JSON value for error messages.
+func describe(n *jsonNode) string {
+	switch n.value.(type) {
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []*jsonNode:
+		return "array"
+	case *jsonObject:
+		return "object"
+	default:
+		return "null"
+	}
+}
+
+// object returns n as an object, reporting an error if any of its keys are
+// not in allowed.
+func (p *jsonParser) object(n *jsonNode, what string, allowed ...string) (*jsonObject, bool) {
+	obj, ok := n.value.(*jsonObject)
+	if !ok {
+		p.errorf(n.start, "expected %s object, got %s", what, describe(n))
+		return nil, false
+	}
+	for _, key := range obj.keys {
+		name := key.value.(string)
+		if !slices.Contains(allowed, name) {
+			p.errorf(key.start, "unknown key %q in %s", name, what)
+		}
+	}
+	return obj, true
+}
+
+// str returns the string value of key in obj, reporting an error if it is
+// missing or is not a string.
+func (p *jsonParser) str(n *jsonNode, obj *jsonObject, key string) (*jsonNode, string, bool) {
+	v := obj.get(key)
+	if v == nil {
+		p.errorf(n.start, "missing %q key", key)
+		return nil, "", false
+	}
+	s, ok := v.value.(string)
+	if !ok {
+		p.errorf(v.start, "%q must be a string, got %s", key, describe(v))
+	}
+	return v, s, ok
+}
+
+func (p *jsonParser) parseBody(n *jsonNode) ast.Body {
+	elems, ok := n.value.([]*jsonNode)
+	if !ok {
+		p.errorf(n.start, "expected array of statements, got %s", describe(n))
+		return nil
+	}
+
+	var body ast.Body
+	for _, elem := range elems {
+		if stmt := p.parseStatement(elem); stmt != nil {
+			body = append(body, stmt)
+		}
+	}
+	return body
+}
+
+func (p *jsonParser) parseStatement(n *jsonNode) ast.Stmt {
+	obj, ok := n.value.(*jsonObject)
+	if !ok {
+		p.errorf(n.start, "expected statement object, got %s", describe(n))
+		return nil
+	}
+
+	switch attr, block := obj.get("attribute"), obj.get("block"); {
+	case attr != nil && block != nil:
+		p.errorf(n.start, "statement must not have both \"attribute\" and \"block\" keys")
+		return nil
+	case attr != nil:
+		return p.parseAttribute(n)
+	case block != nil:
+		return p.parseBlock(n)
+	default:
+		p.errorf(n.start, "statement must have an \"attribute\" or a \"block\" key")
+		return nil
+	}
+}
+
+func (p *jsonParser) parseAttribute(n *jsonNode) ast.Stmt {
+	obj, _ := p.object(n, "attribute", "attribute", "value")
+
+	nameNode, name, ok := p.str(n, obj, "attribute")
+	if !ok {
+		return nil
+	}
+	if !scanner.IsValidIdentifier(name) {
+		p.errorf(nameNode.start, "attribute name %q must be a valid identifier", name)
+	}
+
+	value := obj.get("value")
+	if value == nil {
+		p.errorf(n.start, "missing %q key", "value")
+		return nil
+	}
+	return &ast.AttributeStmt{
+		Name:  &ast.Ident{Name: name, NamePos: p.pos(nameNode.start + 1)},
+		Value: p.parseExpr(value),
+	}
+}
+
+func (p *jsonParser) parseBlock(n *jsonNode) ast.Stmt {
+	obj, _ := p.object(n, "block", "block", "label", "body")
+
+	nameNode, name, ok := p.str(n, obj, "block")
+	if !ok {
+		return nil
+	}
+	parts := strings.Split(name, ".")
+	for _, part := range parts {
+		if !scanner.IsValidIdentifier(part) {
+			p.errorf(nameNode.start, "block name %q must be identifiers separated by .", name)
+			return nil
+		}
+	}
+
+	block := &ast.BlockStmt{
+		Name:      parts,
+		NamePos:   p.pos(nameNode.start + 1),
+		LCurlyPos: p.pos(n.start),
+		RCurlyPos: p.pos(n.end - 1),
+	}
+	if obj.get("label") != nil {
+		labelNode, label, ok := p.str(n, obj, "label")
+		if ok && !scanner.IsValidIdentifier(label) {
+			p.errorf(labelNode.start, "block label %q must be a valid identifier", label)
+		}
+		block.Label, block.LabelPos = label, p.pos(labelNode.start)
+	}
+	if body := obj.get("body"); body != nil {
+		block.Body = p.parseBody(body)
+	}
+	return block
+}
+
+// parseExpr converts an expression object. Invalid expressions are
+// reported and replaced with null so callers always get a complete tree.
+func (p *jsonParser) parseExpr(n *jsonNode) ast.Expr {
+	null := &ast.LiteralExpr{Kind: token.NULL, ValuePos: p.pos(n.start), Value: "null"}
+
+	obj, ok := p.object(n, "expression", "type", "value")
+	if !ok {
+		return null
+	}
+	typeNode, typ, ok := p.str(n, obj, "type")
+	if !ok {
+		return null
+	}
+	if typ == "null" {
+		return null
+	}
+
+	v := obj.get("value")
+	if v == nil {
+		p.errorf(n.start, "missing %q key", "value")
+		return null
+	}
+	mismatch := func() ast.Expr {
+		p.errorf(v.start, "value of %s expression must not be %s", typ, describe(v))
+		return null
+	}
+
+	switch typ {
+	case "bool":
+		b, ok := v.value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		return &ast.LiteralExpr{Kind: token.BOOL, ValuePos: p.pos(v.start), Value: strconv.FormatBool(b)}
+
+	case "number":
+		num, ok := v.value.(json.Number)
+		if !ok {
+			return mismatch()
+		}
+		return p.parseNumber(v.start, string(num))
+
+	case "string":
+		s, ok := v.value.(string)
+		if !ok {
+			return mismatch()
+		}
+		return &ast.LiteralExpr{Kind: token.STRING, ValuePos: p.pos(v.start), Value: strconv.Quote(s)}
+
+	case "array":
+		elems, ok := v.value.([]*jsonNode)
+		if !ok {
+			return mismatch()
+		}
+		arr := &ast.ArrayExpr{LBrackPos: p.pos(v.start), RBrackPos: p.pos(v.end - 1)}
+		for _, elem := range elems {
+			arr.Elements = append(arr.Elements, p.parseExpr(elem))
+		}
+		return arr
+
+	case "object":
+		fields, ok := v.value.([]*jsonNode)
+		if !ok {
+			return mismatch()
+		}
+		res := &ast.ObjectExpr{LCurlyPos: p.pos(v.start), RCurlyPos: p.pos(v.end - 1)}
+		for _, field := range fields {
+			if f := p.parseField(field); f != nil {
+				res.Fields = append(res.Fields, f)
+			}
+		}
+		return res
+
+	case "expr":
+		s, ok := v.value.(string)
+		if !ok {
+			return mismatch()
+		}
+		return p.parseSource(v, s)
+
+	default:
+		p.errorf(typeNode.start, "unknown expression type %q", typ)
+		return null
+	}
+}
+
+// parseNumber converts the text of a JSON number, which is always a valid
+// number literal once its sign is removed.
+func (p *jsonParser) parseNumber(off int, num string) ast.Expr {
+	if rest, ok := strings.CutPrefix(num, "-"); ok {
+		return &ast.UnaryExpr{Kind: token.SUB, KindPos: p.pos(off), Value: p.parseNumber(off+1, rest)}
+	}
+
+	kind := token.NUMBER
+	if strings.ContainsAny(num, ".eE") {
+		kind = token.FLOAT
+	}
+	return &ast.LiteralExpr{Kind: kind, ValuePos: p.pos(off), Value: num}
+}
+
+func (p *jsonParser) parseField(n *jsonNode) *ast.ObjectField {
+	obj, ok := p.object(n, "field", "key", "value")
+	if !ok {
+		return nil
+	}
+	keyNode, key, ok := p.str(n, obj, "key")
+	if !ok {
+		return nil
+	}
+	value := obj.get("value")
+	if value == nil {
+		p.errorf(n.start, "missing %q key", "value")
+		return nil
+	}
+	return &ast.ObjectField{
+		Name:   &ast.Ident{Name: key, NamePos: p.pos(keyNode.start + 1)},
+		Quoted: !scanner.IsValidIdentifier(key),
+		Value:  p.parseExpr(value),
+	}
+}
+
+// parseSource parses the text of an "expr" value. The text is parsed in
+// place of the JSON string holding it, so positions refer to the JSON
+// document. Its unescaped form is never longer than the quoted string.
+func (p *jsonParser) parseSource(n *jsonNode, expr string) ast.Expr {
+	start := n.start + 1
+	if cap(p.scratch) < len(p.src) {
+		p.scratch = make([]byte, len(p.src))
+	}
+	src := p.scratch[:start+copy(p.scratch[start:len(p.src)], expr)]
+
+	ep := newParserAt(p.file, src, start)
+	e := ep.ParseExpression()
+	ep.skipTerminators()
+	if ep.tok != token.EOF {
+		ep.addErrorf(ep.pos, "expected end of expression, got %s", ep.describe())
+	}
+	p.errors = append(p.errors, ep.errors...)
+	return e
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+func TestParseJSON(t *testing.T) {
+	src := `[
+  {
+    "block": "prometheus.scrape",
+    "label": "default",
+    "body": [
+      {"attribute": "targets", "value": {"type": "array", "value": [
+        {"type": "object", "value": [
+          {"key": "__address__", "value": {"type": "string", "value": "localhost:9090"}},
+          {"key": "not ident", "value": {"type": "number", "value": -1.5}}
+        ]}
+      ]}},
+      {"attribute": "forward_to", "value": {"type": "expr", "value": "[a.b, f(\"x\")]"}},
+      {"attribute": "enabled", "value": {"type": "bool", "value": true}},
+      {"attribute": "unset", "value": {"type": "null"}}
+    ]
+  }
+]`
+	f, err := ParseJSON("config.json", []byte(src))
+	if err != nil {
+		t.Fatalf("ParseJSON: %v", err)
+	}
+	if len(f.Body) != 1 {
+		t.Fatalf("got %d statements, want 1", len(f.Body))
+	}
+
+	block, ok := f.Body[0].(*ast.BlockStmt)
+	if !ok {
+		t.Fatalf("statement 0 is %T, want *ast.BlockStmt", f.Body[0])
+	}
+	if got := block.GetBlockName(); got != "prometheus.scrape" || block.Label != "default" {
+		t.Errorf("block = %s %q, want prometheus.scrape \"default\"", got, block.Label)
+	}
+	if got := block.RCurlyPos.Position().String(); got != "config.json:16:3" {
+		t.Errorf("block end = %s, want config.json:16:3", got)
+	}
+
+	var got []string
+	for _, stmt := range block.Body {
+		attr := stmt.(*ast.AttributeStmt)
+		got = append(got, attr.Name.Name+" = "+render(attr.Value))
+	}
+	want := []string{
+		`targets = [{__address__ = "localhost:9090", "not ident" = (-1.5)}]`,
+		`forward_to = [a.b, f("x")]`,
+		`enabled = true`,
+		`unset = null`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("attributes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Positions inside "expr" values refer to the JSON document.
+	forward := block.Body[1].(*ast.AttributeStmt)
+	call := forward.Value.(*ast.ArrayExpr).Elements[1]
+	if got := ast.StartPos(call).Position().String(); got != "config.json:12:77" {
+		t.Errorf("call starts at %s, want config.json:12:77", got)
+	}
+}
+
+func TestParseJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // One "line:col: message" per expected error.
+	}{
+		{
+			name:  "invalid JSON",
+			input: "[\n  {\"attribute\" \"a\"}\n]",
+			want:  []string{"2:16: invalid character '\"' after object key"},
+		},
+		{
+			name:  "truncated JSON",
+			input: "[\n  {\"attribute\": \"a\"",
+			want:  []string{"2:19: unexpected end of JSON input"},
+		},
+		{
+			name:  "not a list of statements",
+			input: `{"attribute": "a"}`,
+			want:  []string{"1:1: expected array of statements, got object"},
+		},
+		{
+			name:  "unknown statement",
+			input: `[{"name": "a"}]`,
+			want:  []string{`1:2: statement must have an "attribute" or a "block" key`},
+		},
+		{
+			name:  "invalid names",
+			input: `[{"attribute": "a.b", "value": {"type": "null"}}, {"block": "b", "label": "not valid"}]`,
+			want: []string{
+				`1:16: attribute name "a.b" must be a valid identifier`,
+				`1:75: block label "not valid" must be a valid identifier`,
+			},
+		},
+		{
+			name: "invalid expressions",
+			input: `[
+  {"attribute": "a", "value": {"type": "number", "value": "1"}},
+  {"attribute": "b", "value": {"type": "expr", "value": "1 +"}},
+  {"attribute": "c", "value": {"type": "duration", "value": "1s"}, "extra": 1}
+]`,
+			want: []string{
+				"2:59: value of number expression must not be string",
+				"3:61: expected expression, got EOF",
+				"4:40: unknown expression type \"duration\"",
+				"4:68: unknown key \"extra\" in attribute",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseJSON("", []byte(tc.input))
+			errs, ok := err.(ErrorList)
+			if !ok {
+				t.Fatalf("got error %v (%T), want ErrorList", err, err)
+			}
+
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,596 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/scanner"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// maxDepth is the maximum depth of the tree returned by the parser. Deeper
+// input is reported as an error instead of overflowing the stack, whether
+// while parsing or in anything which walks the tree afterwards.
+const maxDepth = 1000
+
+// parser implements the configuration language parser.
+//
+// It is a recursive-descent parser which reports errors and keeps going: on
+// an unexpected token it records an error, skips to the end of the current
+// statement and continues with the next one.
+type parser struct {
+	file    *token.File
+	errors  ErrorList
+	scanner *scanner.Scanner
+
+	comments []ast.CommentGroup
+
+	pos token.Pos   // Current token position
+	tok token.Token // Current token
+	lit string      // Current token literal
+
+	// Position of the end of the last non-comment token, used to tell
+	// trailing comments apart from leading ones.
+	lastEnd token.Pos
+
+	depth   int  // Depth of the tree being parsed
+	tooDeep bool // Whether maxDepth was exceeded
+}
+
+// newParser creates a new parser which will parse the provided src.
+func newParser(filename string, src []byte) *parser {
+	p := &parser{file: token.NewFile(filename)}
+	p.scanner = scanner.New(p.file, src, p.scanError)
+
+	p.next()
+	return p
+}
+
+// newParserAt creates a new parser which parses src starting at byte
+// offset off, recording positions in an existing file.
+func newParserAt(file *token.File, src []byte, off int) *parser {
+	p := &parser{file: file}
+	p.scanner = scanner.NewAt(file, src, off, p.scanError)
+
+	p.next()
+	return p
+}
+
+// scanError records an error reported by the scanner.
+func (p *parser) scanError(pos token.Pos, msg string) {
+	p.errors.Add(&Error{
+		StartPos: pos.Position(),
+		EndPos:   pos.Position(),
+		Message:  msg,
+	})
+}
+
+// next advances the parser to the next non-comment token, collecting any
+// comments along the way.
+func (p *parser) next() {
+	if p.pos.Valid() {
+		p.lastEnd = p.pos
+		if p.lit != "" {
+			p.lastEnd = p.pos.Add(len(p.lit) - 1)
+		}
+	}
+
+	p.next0()
+	if p.tok != token.COMMENT {
+		return
+	}
+
+	// A comment on the same line as the previous token is a trailing
+	// comment and gets its own group.
+	if p.lastEnd.Valid() && p.line(p.pos) == p.line(p.lastEnd) {
+		p.consumeCommentGroup(0)
+	}
+	for p.tok == token.COMMENT {
+		p.consumeCommentGroup(1)
+	}
+}
+
+// next0 advances to the next token, including comments.
+func (p *parser) next0() {
+	p.pos, p.tok, p.lit = p.scanner.Scan()
+}
+
+// consumeCommentGroup consumes a group of adjacent comments. A comment
+// belongs to the group if it starts no more than n lines after the end of
+// the previous comment.
+func (p *parser) consumeCommentGroup(n int) {
+	var (
+		group   ast.CommentGroup
+		endLine = p.line(p.pos)
+	)
+	for p.tok == token.COMMENT && p.line(p.pos) <= endLine+n {
+		c := &ast.Comment{StartPos: p.pos, Text: p.lit}
+		group = append(group, c)
+		endLine = p.line(ast.EndPos(c))
+		p.next0()
+	}
+	p.comments = append(p.comments, group)
+}
+
+func (p *parser) line(pos token.Pos) int { return p.file.PositionFor(pos).Line }
+
+// addErrorf records a syntax error at pos. Only the first error on each line
+// is kept, since later ones are usually caused by the first.
+func (p *parser) addErrorf(pos token.Pos, format string, args ...any) {
+	if p.tooDeep {
+		return
+	}
+	position := p.file.PositionFor(pos)
+	if n := len(p.errors); n > 0 && p.errors[n-1].StartPos.Line == position.Line {
+		return
+	}
+	p.errors.Add(&Error{
+		StartPos: position,
+		EndPos:   position,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// push increases the depth of the tree being parsed. If that exceeds
+// maxDepth, push reports an error at pos, skips the rest of the input and
+// returns false. Callers restore the depth once their node is parsed.
+func (p *parser) push(pos token.Pos) bool {
+	p.depth++
+	if p.depth <= maxDepth {
+		return true
+	}
+	p.addErrorf(pos, "exceeded the maximum nesting depth of %d", maxDepth)
+	p.tooDeep = true
+	for p.tok != token.EOF {
+		p.next()
+	}
+	return false
+}
+
+// describe returns a description of the current token for error messages.
+func (p *parser) describe() string {
+	switch {
+	case p.tok == token.TERMINATOR:
+		return "newline"
+	case p.tok == token.EOF:
+		return "EOF"
+	case p.tok.IsLiteral() || p.tok == token.ILLEGAL:
+		return fmt.Sprintf("%s %s", p.tok, p.lit)
+	default:
+		return p.tok.String()
+	}
+}
+
+// expect consumes the current token if it is t and returns its position.
+// Otherwise an error is recorded and the parser does not advance.
+func (p *parser) expect(t token.Token) token.Pos {
+	pos := p.pos
+	if p.tok != t {
+		p.addErrorf(p.pos, "expected %s, got %s", t, p.describe())
+		return pos
+	}
+	p.next()
+	return pos
+}
+
+// skipTerminators skips over newlines, which are allowed between the
+// elements of arrays, objects and argument lists.
+func (p *parser) skipTerminators() {
+	for p.tok == token.TERMINATOR {
+		p.next()
+	}
+}
+
+// advance skips tokens until the end of the current statement: a
+// TERMINATOR, or the until token, at the nesting depth advance was called
+// at.
+func (p *parser) advance(until token.Token) {
+	depth := 0
+	for p.tok != token.EOF {
+		switch p.tok {
+		case token.LCURLY, token.LPAREN, token.LBRACK:
+			depth++
+		case token.RCURLY, token.RPAREN, token.RBRACK:
+			if depth == 0 && p.tok == until {
+				return
+			}
+			if depth > 0 {
+				depth--
+			}
+		case token.TERMINATOR:
+			if depth == 0 {
+				return
+			}
+		}
+		p.next()
+	}
+}
+
+// parseBody parses a series of statements up to the until token, which is
+// not consumed.
+//
+//	Body = [ Statement { TERMINATOR Statement } ] [ TERMINATOR ] .
+func (p *parser) parseBody(until token.Token) ast.Body {
+	var body ast.Body
+
+	for p.skipTerminators(); p.tok != until && p.tok != token.EOF; p.skipTerminators() {
+		if stmt := p.parseStatement(until); stmt != nil {
+			body = append(body, stmt)
+		}
+
+		switch p.tok {
+		case token.TERMINATOR, until, token.EOF:
+		default:
+			p.addErrorf(p.pos, "expected newline after statement, got %s", p.describe())
+			p.advance(until)
+		}
+	}
+
+	return body
+}
+
+// parseStatement parses an individual statement within a body.
+//
+//	Statement = Attribute | Block .
+//	Attribute = identifier "=" Expression .
+//	Block     = BlockName [ string ] "{" Body "}" .
+//	BlockName = identifier { "." identifier } .
+func (p *parser) parseStatement(until token.Token) ast.Stmt {
+	if p.tok != token.IDENT {
+		p.addErrorf(p.pos, "expected attribute assignment or block, got %s", p.describe())
+		p.advance(until)
+		return nil
+	}
+
+	namePos := p.pos
+	name := []string{p.lit}
+	p.next()
+	for p.tok == token.DOT {
+		p.next()
+		if p.tok != token.IDENT {
+			p.addErrorf(p.pos, "expected identifier after . in block name, got %s", p.describe())
+			p.advance(until)
+			return nil
+		}
+		name = append(name, p.lit)
+		p.next()
+	}
+
+	switch p.tok {
+	case token.ASSIGN:
+		if len(name) > 1 {
+			p.addErrorf(namePos, "attribute names may only consist of a single identifier, got %s", strings.Join(name, "."))
+		}
+		p.next()
+		return &ast.AttributeStmt{
+			Name:  &ast.Ident{Name: strings.Join(name, "."), NamePos: namePos},
+			Value: p.ParseExpression(),
+		}
+
+	case token.STRING, token.LCURLY:
+		block := &ast.BlockStmt{Name: name, NamePos: namePos}
+
+		if p.tok == token.STRING {
+			block.LabelPos = p.pos
+			block.Label = p.parseLabel()
+		}
+
+		block.LCurlyPos = p.expect(token.LCURLY)
+		if p.tok == token.STRING {
+			p.addErrorf(p.pos, "blocks may only have one label")
+			p.advance(until)
+			return nil
+		}
+
+		depth := p.depth
+		if !p.push(block.LCurlyPos) {
+			return nil
+		}
+		block.Body = p.parseBody(token.RCURLY)
+		block.RCurlyPos = p.expect(token.RCURLY)
+		p.depth = depth
+		return block
+
+	default:
+		p.addErrorf(p.pos, "expected = or { after %s, got %s", strings.Join(name, "."), p.describe())
+		p.advance(until)
+		return nil
+	}
+}
+
+// parseLabel parses and validates a block label.
+func (p *parser) parseLabel() string {
+	pos, lit := p.pos, p.lit
+	p.next()
+
+	label, err := strconv.Unquote(lit)
+	if err != nil {
+		p.addErrorf(pos, "invalid block label %s: %s", lit, err)
+		return ""
+	}
+	if !scanner.IsValidIdentifier(label) {
+		p.addErrorf(pos, "block label %q must be a valid identifier", label)
+	}
+	return label
+}
+
+// ParseExpression parses a single expression.
+//
+//	Expression = BinaryExpr .
+func (p *parser) ParseExpression() ast.Expr {
+	return p.parseBinOp(1)
+}
+
+// parseBinOp parses binary operators with at least the given precedence.
+//
+//	BinaryExpr = UnaryExpr | BinaryExpr binary_op BinaryExpr .
+func (p *parser) parseBinOp(inPrec int) ast.Expr {
+	depth := p.depth
+	defer func() { p.depth = depth }()
+
+	lhs := p.parseUnaryExpr()
+
+	for {
+		tok, pos, prec := p.tok, p.pos, p.tok.BinaryPrecedence()
+		if prec < inPrec {
+			return lhs
+		}
+		// Each operator nests lhs one level deeper.
+		if !p.push(pos) {
+			return lhs
+		}
+		p.next()
+
+		// ^ is right-associative; every other operator is
+		// left-associative.
+		nextPrec := prec + 1
+		if tok == token.POW {
+			nextPrec = prec
+		}
+		rhs := p.parseBinOp(nextPrec)
+
+		lhs = &ast.BinaryExpr{
+			Kind:    tok,
+			KindPos: pos,
+			Left:    lhs,
+			Right:   rhs,
+		}
+	}
+}
+
+// parseUnaryExpr parses a unary expression.
+//
+//	UnaryExpr = PrimaryExpr | unary_op UnaryExpr .
+//	unary_op  = "!" | "-" .
+func (p *parser) parseUnaryExpr() ast.Expr {
+	depth := p.depth
+	defer func() { p.depth = depth }()
+
+	if !p.push(p.pos) {
+		return &ast.LiteralExpr{Kind: token.NULL, ValuePos: p.pos, Value: "null"}
+	}
+	if p.tok == token.NOT || p.tok == token.SUB {
+		op, pos := p.tok, p.pos
+		p.next()
+		return &ast.UnaryExpr{
+			Kind:    op,
+			KindPos: pos,
+			Value:   p.parseUnaryExpr(),
+		}
+	}
+	return p.parsePrimaryExpr()
+}
+
+// parsePrimaryExpr parses an operand followed by any number of accessors,
+// indexes and calls.
+//
+//	PrimaryExpr = Operand
+//	            | PrimaryExpr "." identifier
+//	            | PrimaryExpr "[" Expression "]"
+//	            | PrimaryExpr "(" [ ExpressionList ] ")" .
+func (p *parser) parsePrimaryExpr() ast.Expr {
+	depth := p.depth
+	defer func() { p.depth = depth }()
+
+	expr := p.parseOperand()
+
+	for {
+		// Each accessor, index or call nests expr one level deeper.
+		switch p.tok {
+		case token.DOT, token.LBRACK, token.LPAREN:
+			if !p.push(p.pos) {
+				return expr
+			}
+		}
+
+		switch p.tok {
+		case token.DOT:
+			p.next()
+			if p.tok != token.IDENT {
+				p.addErrorf(p.pos, "expected identifier after ., got %s", p.describe())
+				return expr
+			}
+			expr = &ast.AccessExpr{
+				Value: expr,
+				Name:  &ast.Ident{Name: p.lit, NamePos: p.pos},
+			}
+			p.next()
+
+		case token.LBRACK:
+			lbrack := p.pos
+			p.next()
+			p.skipTerminators()
+			index := p.ParseExpression()
+			p.skipTerminators()
+			rbrack := p.expect(token.RBRACK)
+
+			expr = &ast.IndexExpr{
+				Value:     expr,
+				Index:     index,
+				LBrackPos: lbrack,
+				RBrackPos: rbrack,
+			}
+
+		case token.LPAREN:
+			lparen := p.pos
+			p.next()
+			args := p.parseExpressionList(token.RPAREN)
+			rparen := p.expect(token.RPAREN)
+
+			expr = &ast.CallExpr{
+				Value:     expr,
+				Args:      args,
+				LParenPos: lparen,
+				RParenPos: rparen,
+			}
+
+		default:
+			return expr
+		}
+	}
+}
+
+// parseOperand parses a single operand.
+//
+//	Operand = literal | identifier | ArrayExpr | ObjectExpr | "(" Expression ")" .
+//	literal = number | float | string | bool | null .
+func (p *parser) parseOperand() ast.Expr {
+	switch p.tok {
+	case token.IDENT:
+		expr := &ast.IdentifierExpr{Ident: &ast.Ident{Name: p.lit, NamePos: p.pos}}
+		p.next()
+		return expr
+
+	case token.NUMBER, token.FLOAT, token.STRING, token.BOOL, token.NULL:
+		expr := &ast.LiteralExpr{Kind: p.tok, ValuePos: p.pos, Value: p.lit}
+		p.next()
+		return expr
+
+	case token.LBRACK:
+		return p.parseArray()
+
+	case token.LCURLY:
+		return p.parseObject()
+
+	case token.LPAREN:
+		lparen := p.pos
+		p.next()
+		p.skipTerminators()
+		inner := p.ParseExpression()
+		p.skipTerminators()
+		rparen := p.expect(token.RPAREN)
+
+		return &ast.ParenExpr{Inner: inner, LParenPos: lparen, RParenPos: rparen}
+	}
+
+	// Report the error and return a null literal in place of the missing
+	// expression, so callers always get a complete tree.
+	pos := p.pos
+	p.addErrorf(pos, "expected expression, got %s", p.describe())
+	if p.tok != token.TERMINATOR && p.tok != token.EOF && p.tok != token.RCURLY {
+		p.next()
+	}
+	return &ast.LiteralExpr{Kind: token.NULL, ValuePos: pos, Value: "null"}
+}
+
+// parseArray parses an array expression.
+//
+//	ArrayExpr = "[" [ ExpressionList ] "]" .
+func (p *parser) parseArray() ast.Expr {
+	lbrack := p.expect(token.LBRACK)
+	elems := p.parseExpressionList(token.RBRACK)
+	rbrack := p.expect(token.RBRACK)
+
+	return &ast.ArrayExpr{Elements: elems, LBrackPos: lbrack, RBrackPos: rbrack}
+}
+
+// parseExpressionList parses a comma-separated list of expressions ending
+// with the until token, which is not consumed. Newlines are allowed between
+// elements and a trailing comma is permitted.
+//
+//	ExpressionList = Expression { "," Expression } [ "," ] .
+func (p *parser) parseExpressionList(until token.Token) []ast.Expr {
+	var exprs []ast.Expr
+
+	p.skipTerminators()
+	for p.tok != until && p.tok != token.EOF {
+		exprs = append(exprs, p.ParseExpression())
+		p.skipTerminators()
+
+		if p.tok != token.COMMA {
+			if p.tok != until {
+				p.addErrorf(p.pos, "expected , or %s, got %s", until, p.describe())
+			}
+			break
+		}
+		p.next()
+		p.skipTerminators()
+	}
+
+	return exprs
+}
+
+// parseObject parses an object expression.
+//
+//	ObjectExpr  = "{" [ FieldList ] "}" .
+//	FieldList   = Field { "," Field } [ "," ] .
+//	Field       = ( string | identifier ) "=" Expression .
+func (p *parser) parseObject() ast.Expr {
+	lcurly := p.expect(token.LCURLY)
+
+	var fields []*ast.ObjectField
+	p.skipTerminators()
+	for p.tok != token.RCURLY && p.tok != token.EOF {
+		field := p.parseField()
+		if field == nil {
+			p.advance(token.RCURLY)
+			p.skipTerminators()
+			continue
+		}
+		fields = append(fields, field)
+		p.skipTerminators()
+
+		if p.tok != token.COMMA {
+			if p.tok != token.RCURLY {
+				p.addErrorf(p.pos, "expected , or }, got %s", p.describe())
+			}
+			break
+		}
+		p.next()
+		p.skipTerminators()
+	}
+
+	rcurly := p.expect(token.RCURLY)
+	return &ast.ObjectExpr{Fields: fields, LCurlyPos: lcurly, RCurlyPos: rcurly}
+}
+
+func (p *parser) parseField() *ast.ObjectField {
+	field := &ast.ObjectField{Name: &ast.Ident{NamePos: p.pos}}
+
+	switch p.tok {
+	case token.IDENT:
+		field.Name.Name = p.lit
+	case token.STRING:
+		name, err := strconv.Unquote(p.lit)
+		if err != nil {
+			p.addErrorf(p.pos, "invalid field name %s: %s", p.lit, err)
+		}
+		field.Name.Name = name
+		field.Quoted = true
+	default:
+		p.addErrorf(p.pos, "expected field name (string or identifier), got %s", p.describe())
+		return nil
+	}
+	p.next()
+
+	if p.tok != token.ASSIGN {
+		p.addErrorf(p.pos, "expected = after field name, got %s", p.describe())
+		return nil
+	}
+	p.next()
+
+	field.Value = p.ParseExpression()
+	return field
+}
@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+func TestParseFile(t *testing.T) {
+	src := `// Scrape the local instance.
+prometheus.scrape "default" {
+	targets    = [{"__address__" = "localhost:9090"}]
+	forward_to = [prometheus.remote_write.default.receiver] // trailing
+}
+
+logging {
+	level = "info"
+}
+`
+	f, err := ParseFile("config.alloy", []byte(src))
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if len(f.Body) != 2 {
+		t.Fatalf("got %d statements, want 2", len(f.Body))
+	}
+
+	scrape, ok := f.Body[0].(*ast.BlockStmt)
+	if !ok {
+		t.Fatalf("statement 0 is %T, want *ast.BlockStmt", f.Body[0])
+	}
+	if got := scrape.GetBlockName(); got != "prometheus.scrape" {
+		t.Errorf("block name = %q, want prometheus.scrape", got)
+	}
+	if scrape.Label != "default" {
+		t.Errorf("block label = %q, want default", scrape.Label)
+	}
+	if got := scrape.RCurlyPos.Position().String(); got != "config.alloy:5:1" {
+		t.Errorf("block end = %s, want config.alloy:5:1", got)
+	}
+
+	forward := scrape.Body[1].(*ast.AttributeStmt)
+	if forward.Name.Name != "forward_to" {
+		t.Errorf("attribute name = %q, want forward_to", forward.Name.Name)
+	}
+	if got := ast.StartPos(forward.Value).Position().String(); got != "config.alloy:4:15" {
+		t.Errorf("forward_to value starts at %s, want config.alloy:4:15", got)
+	}
+
+	if len(f.Comments) != 2 {
+		t.Fatalf("got %d comment groups, want 2", len(f.Comments))
+	}
+	if got := f.Comments[1][0].Text; got != "// trailing" {
+		t.Errorf("second comment = %q, want // trailing", got)
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // Fully parenthesized form of the parsed expression.
+	}{
+		{`1 + 2 * 3`, `(1 + (2 * 3))`},
+		{`(1 + 2) * 3`, `(((1 + 2)) * 3)`},
+		{`2 ^ 3 ^ 2`, `(2 ^ (3 ^ 2))`},
+		{`1 - 2 - 3`, `((1 - 2) - 3)`},
+		{`!a || b && c == d`, `((!a) || (b && (c == d)))`},
+		{`-x.y[0](1, "s")`, `(-x.y[0](1, "s"))`},
+		{`a.b.c`, `a.b.c`},
+		{"[\n\t1,\n\t2,\n]", `[1, 2]`},
+		{`{ a = 1, "b c" = null }`, `{a = 1, "b c" = null}`},
+		{`f()`, `f()`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			expr, err := ParseExpression(tc.input)
+			if err != nil {
+				t.Fatalf("ParseExpression: %v", err)
+			}
+			if got := render(expr); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFile_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // One "line:col: message" per expected error.
+	}{
+		{
+			name:  "missing value",
+			input: "a = \n",
+			want:  []string{"2:1: expected expression, got EOF"},
+		},
+		{
+			name:  "dotted attribute",
+			input: "a.b = 1\n",
+			want:  []string{"1:1: attribute names may only consist of a single identifier, got a.b"},
+		},
+		{
+			name:  "invalid label",
+			input: "block \"not valid\" {}\n",
+			want:  []string{`1:7: block label "not valid" must be a valid identifier`},
+		},
+		{
+			name:  "two labels",
+			input: "block \"a\" \"b\" {}\n",
+			want:  []string{`1:11: expected {, got STRING "b"`},
+		},
+		{
+			name:  "missing comma in array",
+			input: "a = [1 2]\n",
+			want:  []string{"1:8: expected , or ], got NUMBER 2"},
+		},
+		{
+			name: "recovers and reports later errors",
+			input: `a = 1 +
+b {
+	c = [1 2]
+	d = }
+}
+e = )
+f = "ok"
+`,
+			want: []string{
+				"2:3: expected newline after statement, got {",
+				"5:1: expected attribute assignment or block, got }",
+				"6:5: expected expression, got )",
+			},
+		},
+		{
+			name:  "recovers inside blocks",
+			input: "b {\n\tc = [1 2]\n\td = 3\n\te = ]\n}\n",
+			want: []string{
+				"2:9: expected , or ], got NUMBER 2",
+				"4:6: expected expression, got ]",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseFile("", []byte(tc.input))
+			errs, ok := err.(ErrorList)
+			if !ok {
+				t.Fatalf("got error %v (%T), want ErrorList", err, err)
+			}
+
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseFile_PartialAST(t *testing.T) {
+	f, err := ParseFile("", []byte("a = [1 2]\nb = 2\nc {\n\td = \n}\ne = 3\n"))
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	var names []string
+	for _, stmt := range f.Body {
+		switch stmt := stmt.(type) {
+		case *ast.AttributeStmt:
+			names = append(names, stmt.Name.Name)
+		case *ast.BlockStmt:
+			names = append(names, stmt.GetBlockName())
+		}
+	}
+	if got := strings.Join(names, ","); got != "a,b,c,e" {
+		t.Errorf("parsed statements = %s, want a,b,c,e", got)
+	}
+}
+
+// render returns a fully parenthesized representation of an expression.
+func render(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.LiteralExpr:
+		return e.Value
+	case *ast.IdentifierExpr:
+		return e.Ident.Name
+	case *ast.AccessExpr:
+		return render(e.Value) + "." + e.Name.Name
+	case *ast.IndexExpr:
+		return render(e.Value) + "[" + render(e.Index) + "]"
+	case *ast.CallExpr:
+		return render(e.Value) + "(" + renderList(e.Args) + ")"
+	case *ast.ArrayExpr:
+		return "[" + renderList(e.Elements) + "]"
+	case *ast.ObjectExpr:
+		fields := make([]string, len(e.Fields))
+		for i, f := range e.Fields {
+			name := f.Name.Name
+			if f.Quoted {
+				name = `"` + name + `"`
+			}
+			fields[i] = name + " = " + render(f.Value)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case *ast.UnaryExpr:
+		return "(" + e.Kind.String() + render(e.Value) + ")"
+	case *ast.BinaryExpr:
+		return "(" + render(e.Left) + " " + e.Kind.String() + " " + render(e.Right) + ")"
+	case *ast.ParenExpr:
+		return "(" + render(e.Inner) + ")"
+	default:
+		return "?"
+	}
+}
+
+func renderList(exprs []ast.Expr) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = render(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func TestParseFile_MaxDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"arrays", "a = " + strings.Repeat("[", 1e6)},
+		{"objects", "a = " + strings.Repeat("{ a = ", 1e6)},
+		{"parentheses", "a = " + strings.Repeat("(", 1e6) + "1" + strings.Repeat(")", 1e6)},
+		{"unary operators", "a = " + strings.Repeat("!", 1e6) + "true"},
+		{"binary operators", "a = 1" + strings.Repeat(" + 1", 1e6)},
+		{"accessors", "a = b" + strings.Repeat(".c", 1e6)},
+		{"calls", "a = f" + strings.Repeat("()", 1e6)},
+		{"blocks", strings.Repeat("b {\n", 1e6)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseFile("", []byte(tc.input))
+			errs, ok := err.(ErrorList)
+			if !ok || len(errs) != 1 || !strings.Contains(errs[0].Message, "exceeded the maximum nesting depth of 1000") {
+				t.Errorf("got error %v, want a single maximum nesting depth error", err)
+			}
+		})
+	}
+
+	// Nesting up to the limit is allowed.
+	if _, err := ParseExpression(strings.Repeat("[", 500) + strings.Repeat("]", 500)); err != nil {
+		t.Errorf("unexpected error for nested arrays: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// FprintJSON writes the JSON representation of node to w, in the schema
+// documented by parser.ParseJSON. node must be an *ast.File, an ast.Body,
+// an ast.Stmt or an ast.Expr; files and bodies are written as arrays of
+// statements.
+//
+// Literals, arrays and objects are written as structured values. Any
+// other expression is written as an "expr" holding its canonical form on
+// a single line.
+// Comments are not written.
+func FprintJSON(w io.Writer, node ast.Node) error {
+	var v any
+	switch node := node.(type) {
+	case *ast.File:
+		v = jsonBody(node.Body)
+	case ast.Body:
+		v = jsonBody(node)
+	case ast.Stmt:
+		v = jsonStatement(node)
+	case ast.Expr:
+		v = jsonExpression(node)
+	default:
+		return fmt.Errorf("printer: unsupported node type %T", node)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type jsonStmt struct {
+	Attribute string    `json:"attribute,omitempty"`
+	Value     *jsonExpr `json:"value,omitempty"`
+
+	Block string      `json:"block,omitempty"`
+	Label string      `json:"label,omitempty"`
+	Body  []*jsonStmt `json:"body,omitempty"`
+}
+
+type jsonExpr struct {
+	Type  string `json:"type"`
+	Value any    `json:"value,omitempty"`
+}
+
+type jsonField struct {
+	Key   string    `json:"key"`
+	Value *jsonExpr `json:"value"`
+}
+
+func jsonBody(body ast.Body) []*jsonStmt {
+	res := []*jsonStmt{}
+	for _, stmt := range body {
+		res = append(res, jsonStatement(stmt))
+	}
+	return res
+}
+
+func jsonStatement(stmt ast.Stmt) *jsonStmt {
+	switch stmt := stmt.(type) {
+	case *ast.AttributeStmt:
+		return &jsonStmt{Attribute: stmt.Name.Name, Value: jsonExpression(stmt.Value)}
+	case *ast.BlockStmt:
+		return &jsonStmt{Block: stmt.GetBlockName(), Label: stmt.Label, Body: jsonBody(stmt.Body)}
+	default:
+		panic(fmt.Sprintf("printer: unexpected ast.Stmt type %T", stmt))
+	}
+}
+
+func jsonExpression(expr ast.Expr) *jsonExpr {
+	switch expr := expr.(type) {
+	case *ast.LiteralExpr:
+		switch expr.Kind {
+		case token.NULL:
+			return &jsonExpr{Type: "null"}
+		case token.BOOL:
+			return &jsonExpr{Type: "bool", Value: expr.Value == "true"}
+		case token.NUMBER, token.FLOAT:
+			// Literals such as 1. and 007 are not valid JSON numbers.
+			if json.Valid([]byte(expr.Value)) {
+				return &jsonExpr{Type: "number", Value: json.Number(expr.Value)}
+			}
+		case token.STRING:
+			if s, err := strconv.Unquote(expr.Value); err == nil {
+				return &jsonExpr{Type: "string", Value: s}
+			}
+		}
+
+	case *ast.UnaryExpr:
+		if lit, ok := expr.Value.(*ast.LiteralExpr); ok && expr.Kind == token.SUB && (lit.Kind == token.NUMBER || lit.Kind == token.FLOAT) {
+			if num := "-" + lit.Value; json.Valid([]byte(num)) {
+				return &jsonExpr{Type: "number", Value: json.Number(num)}
+			}
+		}
+
+	case *ast.ArrayExpr:
+		elems := []*jsonExpr{}
+		for _, elem := range expr.Elements {
+			elems = append(elems, jsonExpression(elem))
+		}
+		return &jsonExpr{Type: "array", Value: elems}
+
+	case *ast.ObjectExpr:
+		fields := []*jsonField{}
+		for _, f := range expr.Fields {
+			fields = append(fields, &jsonField{Key: f.Name.Name, Value: jsonExpression(f.Value)})
+		}
+		return &jsonExpr{Type: "object", Value: fields}
+	}
+
+	p := printer{oneLine: true}
+	p.printExpr(expr)
+	return &jsonExpr{Type: "expr", Value: p.buf.String()}
+}
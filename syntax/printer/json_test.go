@@ -0,0 +1,105 @@
+package printer_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/printer"
+)
+
+func TestFprintJSON(t *testing.T) {
+	expr, err := parser.ParseExpression(`{ a = [-1, 2.5, 1.], "b c" = true, d = null, e = env("HOME") + "/data" }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.FprintJSON(&buf, expr); err != nil {
+		t.Fatal(err)
+	}
+	expect := `{
+  "type": "object",
+  "value": [
+    {
+      "key": "a",
+      "value": {
+        "type": "array",
+        "value": [
+          {
+            "type": "number",
+            "value": -1
+          },
+          {
+            "type": "number",
+            "value": 2.5
+          },
+          {
+            "type": "expr",
+            "value": "1."
+          }
+        ]
+      }
+    },
+    {
+      "key": "b c",
+      "value": {
+        "type": "bool",
+        "value": true
+      }
+    },
+    {
+      "key": "d",
+      "value": {
+        "type": "null"
+      }
+    },
+    {
+      "key": "e",
+      "value": {
+        "type": "expr",
+        "value": "env(\"HOME\") + \"/data\""
+      }
+    }
+  ]
+}
+`
+	if buf.String() != expect {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", buf.String(), expect)
+	}
+}
+
+// TestFprintJSON_RoundTrip exports each testdata/*.in file to JSON and
+// checks that parsing the JSON produces the same file.
+func TestFprintJSON_RoundTrip(t *testing.T) {
+	for _, input := range []string{"testdata/blocks.in", "testdata/comments.in"} {
+		t.Run(input, func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := parser.ParseFile(input, src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var exported bytes.Buffer
+			if err := printer.FprintJSON(&exported, f); err != nil {
+				t.Fatal(err)
+			}
+			imported, err := parser.ParseJSON(input+".json", exported.Bytes())
+			if err != nil {
+				t.Fatalf("ParseJSON: %v\n%s", err, exported.String())
+			}
+
+			var again bytes.Buffer
+			if err := printer.FprintJSON(&again, imported); err != nil {
+				t.Fatal(err)
+			}
+			if again.String() != exported.String() {
+				t.Errorf("round trip changed the file:\n%s\nexpected:\n%s", again.String(), exported.String())
+			}
+		})
+	}
+}
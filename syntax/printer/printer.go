@@ -0,0 +1,392 @@
+// Package printer formats configuration ASTs in their canonical form.
+//
+// The canonical form indents nested blocks, arrays and objects with one
+// tab per level, aligns the = of adjacent attributes and object fields,
+// collapses runs of blank lines into a single blank line and preserves
+// every comment. Arrays, objects and function calls written on a single
+// line stay on a single line unless they contain comments. Printing the
+// output of the printer again produces the same text.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// Fprint writes the canonical form of node to w. node must be an
+// *ast.File, an ast.Body, an ast.Stmt or an ast.Expr. Comments are only
+// printed for *ast.File nodes, since other nodes do not carry them.
+func Fprint(w io.Writer, node ast.Node) error {
+	var p printer
+
+	switch node := node.(type) {
+	case *ast.File:
+		p.comments = node.Comments
+		p.printBody(node.Body, token.NoPos)
+	case ast.Body:
+		p.printBody(node, token.NoPos)
+	case ast.Stmt:
+		p.printBody(ast.Body{node}, token.NoPos)
+	case ast.Expr:
+		p.printExpr(node)
+		p.buf.WriteByte('\n')
+	default:
+		return fmt.Errorf("printer: unsupported node type %T", node)
+	}
+
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// Format parses the configuration file src and returns its canonical form.
+// Files with syntax errors are not formatted; the parser's ErrorList is
+// returned instead.
+func Format(filename string, src []byte) ([]byte, error) {
+	f, err := parser.ParseFile(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := Fprint(&buf, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type printer struct {
+	buf      bytes.Buffer
+	comments []ast.CommentGroup
+	next     int // Index of the next comment group to print.
+	depth    int // Current indentation level.
+
+	// Source line of the last printed token, used to preserve blank lines
+	// and to detect trailing comments.
+	lastLine int
+
+	// Print every array, object and call on a single line.
+	oneLine bool
+}
+
+func line(pos token.Pos) int {
+	if !pos.Valid() {
+		return 0
+	}
+	return pos.Position().Line
+}
+
+func (p *printer) indent() {
+	for i := 0; i < p.depth; i++ {
+		p.buf.WriteByte('\t')
+	}
+}
+
+// startLine begins a new output line for an item starting at the source
+// line srcLine. A blank line is kept when the source had one, unless the
+// item is the first in its list.
+func (p *printer) startLine(srcLine int, first bool) {
+	if !first && p.lastLine > 0 && srcLine > p.lastLine+1 {
+		p.buf.WriteByte('\n')
+	}
+	p.indent()
+}
+
+// hasComments reports whether a comment starts before end.
+func (p *printer) hasComments(end token.Pos) bool {
+	return p.next < len(p.comments) && (!end.Valid() || p.comments[p.next][0].StartPos.Before(end))
+}
+
+// printComments prints every remaining comment group starting before end,
+// each on its own lines. first reports whether nothing has been printed in
+// the current list yet. It returns the updated value of first.
+func (p *printer) printComments(end token.Pos, first bool) bool {
+	for p.hasComments(end) {
+		group := p.comments[p.next]
+		p.next++
+
+		p.startLine(line(group[0].StartPos), first)
+		first = false
+		p.printCommentGroup(group)
+		p.buf.WriteByte('\n')
+	}
+	return first
+}
+
+func (p *printer) printCommentGroup(group ast.CommentGroup) {
+	for i, c := range group {
+		if i > 0 {
+			if line(c.StartPos) == p.lastLine {
+				p.buf.WriteByte(' ')
+			} else {
+				p.buf.WriteByte('\n')
+				p.indent()
+			}
+		}
+		p.buf.WriteString(c.Text)
+		p.lastLine = line(ast.EndPos(c))
+	}
+}
+
+// printTrailingComment prints a comment which starts before end on the
+// same source line as the last printed token.
+func (p *printer) printTrailingComment(end token.Pos) {
+	if p.hasComments(end) && line(p.comments[p.next][0].StartPos) == p.lastLine {
+		p.buf.WriteByte(' ')
+		p.printCommentGroup(p.comments[p.next])
+		p.next++
+	}
+}
+
+// alignWidths returns the width each name should be padded to. Names are
+// aligned with their neighbors on the same or adjacent source lines, which
+// end up on adjacent output lines; a blank line, a comment line or an entry
+// which is not aligned (width -1) starts a new group.
+func alignWidths(widths []int, startLines, endLines []int) []int {
+	out := make([]int, len(widths))
+	for i := 0; i < len(widths); {
+		j := i + 1
+		for j < len(widths) && widths[j] >= 0 && widths[j-1] >= 0 && startLines[j] <= endLines[j-1]+1 {
+			j++
+		}
+		width := 0
+		for k := i; k < j; k++ {
+			width = max(width, widths[k])
+		}
+		for k := i; k < j; k++ {
+			out[k] = width
+		}
+		i = j
+	}
+	return out
+}
+
+// printBody prints the statements of body followed by any comments before
+// end. An invalid end prints all remaining comments.
+func (p *printer) printBody(body ast.Body, end token.Pos) {
+	widths := make([]int, len(body))
+	startLines := make([]int, len(body))
+	endLines := make([]int, len(body))
+	for i, stmt := range body {
+		widths[i] = -1
+		if attr, ok := stmt.(*ast.AttributeStmt); ok {
+			widths[i] = len(attr.Name.Name)
+		}
+		startLines[i] = line(ast.StartPos(stmt))
+		endLines[i] = line(ast.EndPos(stmt))
+	}
+	aligned := alignWidths(widths, startLines, endLines)
+
+	first := true
+	for i, stmt := range body {
+		first = p.printComments(ast.StartPos(stmt), first)
+		p.startLine(startLines[i], first)
+		first = false
+
+		switch stmt := stmt.(type) {
+		case *ast.AttributeStmt:
+			p.buf.WriteString(stmt.Name.Name)
+			p.buf.WriteString(strings.Repeat(" ", aligned[i]-len(stmt.Name.Name)))
+			p.buf.WriteString(" = ")
+			p.printExpr(stmt.Value)
+		case *ast.BlockStmt:
+			p.printBlock(stmt)
+		}
+		p.lastLine = endLines[i]
+		p.printTrailingComment(end)
+		p.buf.WriteByte('\n')
+	}
+
+	p.printComments(end, first)
+}
+
+func (p *printer) printBlock(block *ast.BlockStmt) {
+	p.buf.WriteString(block.GetBlockName())
+	if block.Label != "" {
+		p.buf.WriteByte(' ')
+		p.buf.WriteString(strconv.Quote(block.Label))
+	}
+
+	if len(block.Body) == 0 && !p.hasComments(block.RCurlyPos) {
+		p.buf.WriteString(" { }")
+		return
+	}
+
+	p.buf.WriteString(" {")
+	p.lastLine = line(block.LCurlyPos)
+	p.printTrailingComment(block.RCurlyPos)
+	p.buf.WriteByte('\n')
+
+	p.depth++
+	p.printBody(block.Body, block.RCurlyPos)
+	p.depth--
+
+	p.indent()
+	p.buf.WriteByte('}')
+}
+
+func (p *printer) printExpr(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case *ast.LiteralExpr:
+		p.buf.WriteString(expr.Value)
+
+	case *ast.IdentifierExpr:
+		p.buf.WriteString(expr.Ident.Name)
+
+	case *ast.AccessExpr:
+		p.printExpr(expr.Value)
+		p.buf.WriteByte('.')
+		p.buf.WriteString(expr.Name.Name)
+
+	case *ast.IndexExpr:
+		p.printExpr(expr.Value)
+		p.buf.WriteByte('[')
+		p.printExpr(expr.Index)
+		p.buf.WriteByte(']')
+
+	case *ast.CallExpr:
+		p.printExpr(expr.Value)
+		p.printList("(", ")", expr.Args, expr.LParenPos, expr.RParenPos)
+
+	case *ast.ArrayExpr:
+		p.printList("[", "]", expr.Elements, expr.LBrackPos, expr.RBrackPos)
+
+	case *ast.ObjectExpr:
+		p.printObject(expr)
+
+	case *ast.UnaryExpr:
+		p.buf.WriteString(expr.Kind.String())
+		p.printExpr(expr.Value)
+
+	case *ast.BinaryExpr:
+		p.printExpr(expr.Left)
+		p.buf.WriteByte(' ')
+		p.buf.WriteString(expr.Kind.String())
+		p.buf.WriteByte(' ')
+		p.printExpr(expr.Right)
+
+	case *ast.ParenExpr:
+		p.buf.WriteByte('(')
+		p.printExpr(expr.Inner)
+		p.buf.WriteByte(')')
+
+	default:
+		panic(fmt.Sprintf("printer: unexpected ast.Expr type %T", expr))
+	}
+}
+
+// printList prints the elements of an array or the arguments of a call.
+// Lists which span multiple lines in the source, or which contain
+// comments, place each element on its own line with a trailing comma.
+func (p *printer) printList(open, close string, elems []ast.Expr, openPos, closePos token.Pos) {
+	p.buf.WriteString(open)
+
+	if p.oneLine || line(openPos) == line(closePos) && !p.hasComments(closePos) {
+		for i, elem := range elems {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.printExpr(elem)
+		}
+		p.buf.WriteString(close)
+		return
+	}
+
+	p.lastLine = line(openPos)
+	p.printTrailingComment(closePos)
+	p.buf.WriteByte('\n')
+
+	p.depth++
+	first := true
+	for _, elem := range elems {
+		first = p.printComments(ast.StartPos(elem), first)
+		p.startLine(line(ast.StartPos(elem)), first)
+		first = false
+
+		p.printExpr(elem)
+		p.buf.WriteByte(',')
+		p.lastLine = line(ast.EndPos(elem))
+		p.printTrailingComment(closePos)
+		p.buf.WriteByte('\n')
+	}
+	p.printComments(closePos, first)
+	p.depth--
+
+	p.indent()
+	p.buf.WriteString(close)
+}
+
+// printObject prints an object. Objects which span multiple lines in the
+// source, or which contain comments, place each field on its own line with
+// aligned = signs and a trailing comma.
+func (p *printer) printObject(obj *ast.ObjectExpr) {
+	if len(obj.Fields) == 0 && !p.hasComments(obj.RCurlyPos) {
+		p.buf.WriteString("{}")
+		return
+	}
+
+	names := make([]string, len(obj.Fields))
+	for i, f := range obj.Fields {
+		names[i] = f.Name.Name
+		if f.Quoted {
+			names[i] = strconv.Quote(f.Name.Name)
+		}
+	}
+
+	if p.oneLine || line(obj.LCurlyPos) == line(obj.RCurlyPos) && !p.hasComments(obj.RCurlyPos) {
+		p.buf.WriteString("{ ")
+		for i, f := range obj.Fields {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			p.buf.WriteString(names[i])
+			p.buf.WriteString(" = ")
+			p.printExpr(f.Value)
+		}
+		p.buf.WriteString(" }")
+		return
+	}
+
+	widths := make([]int, len(obj.Fields))
+	startLines := make([]int, len(obj.Fields))
+	endLines := make([]int, len(obj.Fields))
+	for i, f := range obj.Fields {
+		widths[i] = len(names[i])
+		startLines[i] = line(f.Name.NamePos)
+		endLines[i] = line(ast.EndPos(f.Value))
+	}
+	aligned := alignWidths(widths, startLines, endLines)
+
+	p.buf.WriteByte('{')
+	p.lastLine = line(obj.LCurlyPos)
+	p.printTrailingComment(obj.RCurlyPos)
+	p.buf.WriteByte('\n')
+
+	p.depth++
+	first := true
+	for i, f := range obj.Fields {
+		first = p.printComments(f.Name.NamePos, first)
+		p.startLine(startLines[i], first)
+		first = false
+
+		p.buf.WriteString(names[i])
+		p.buf.WriteString(strings.Repeat(" ", aligned[i]-len(names[i])))
+		p.buf.WriteString(" = ")
+		p.printExpr(f.Value)
+		p.buf.WriteByte(',')
+		p.lastLine = endLines[i]
+		p.printTrailingComment(obj.RCurlyPos)
+		p.buf.WriteByte('\n')
+	}
+	p.printComments(obj.RCurlyPos, first)
+	p.depth--
+
+	p.indent()
+	p.buf.WriteByte('}')
+}
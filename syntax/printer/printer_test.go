@@ -0,0 +1,56 @@
+package printer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/printer"
+)
+
+// TestFormat formats each testdata/*.in file and compares the result
+// against the matching .expect file. Formatting the expected output again
+// must not change it.
+func TestFormat(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".in")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect, err := os.ReadFile(strings.TrimSuffix(input, ".in") + ".expect")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := printer.Format(input, src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != string(expect) {
+				t.Fatalf("unexpected output:\n%s\nexpected:\n%s", actual, expect)
+			}
+
+			again, err := printer.Format(input, actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(actual) {
+				t.Errorf("formatting is not idempotent:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormat_SyntaxError(t *testing.T) {
+	if _, err := printer.Format("bad.alloy", []byte("a = ")); err == nil {
+		t.Fatal("expected an error for invalid input")
+	}
+}
@@ -0,0 +1,512 @@
+// Package scanner implements a lexical scanner for configuration files.
+package scanner
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// EBNF for the scanner:
+//
+//   letter           = /* any unicode letter */ | "_" .
+//   number           = /* any unicode number */ .
+//   digit            = "0" ... "9" .
+//
+//   COMMENT          = line_comment | block_comment .
+//   line_comment     = ( "//" | "#" ) { character } newline .
+//   block_comment    = "/*" { character | newline } "*/" .
+//
+//   IDENT            = letter { letter | number } .
+//   NUMBER           = digit { digit } .
+//   FLOAT            = digit { digit } ( "." { digit } [ exponent ] | exponent ) .
+//   exponent         = ( "e" | "E" ) [ "+" | "-" ] digit { digit } .
+//   STRING           = '"' { character | escape_sequence } '"'
+//                    | "`" { character | newline } "`" .
+//
+//   TERMINATOR       = newline after IDENT, a literal, ")", "]" or "}" .
+
+// ErrorHandler is invoked whenever there is an error.
+type ErrorHandler func(pos token.Pos, msg string)
+
+// Scanner holds the internal state for the tokenizer while processing
+// configs.
+type Scanner struct {
+	file  *token.File  // Config file handle for tracking line offsets
+	input []byte       // Input config
+	err   ErrorHandler // Error reporting (may be nil)
+
+	// scanning state variables:
+
+	ch         rune // Current character
+	offset     int  // Byte offset of ch
+	readOffset int  // Byte offset of first character *after* ch
+	insertTerm bool // Insert a newline before the next newline
+	numErrors  int  // Number of errors encountered during scanning
+}
+
+// New creates a new scanner to tokenize the provided input config. The
+// scanner uses the provided file for adding line information for each
+// token.
+//
+// Calls to Scan will invoke the error handler eh when a lexical error is
+// found if eh is not nil.
+func New(file *token.File, input []byte, eh ErrorHandler) *Scanner {
+	return NewAt(file, input, 0, eh)
+}
+
+// NewAt is like New, but starts scanning input at byte offset off. It is
+// used to scan part of a larger document, so token positions refer to the
+// document rather than to the part being scanned.
+func NewAt(file *token.File, input []byte, off int, eh ErrorHandler) *Scanner {
+	s := &Scanner{
+		file:       file,
+		input:      input,
+		err:        eh,
+		readOffset: off,
+	}
+
+	// Preload first character.
+	s.next()
+	if s.ch == bom && off == 0 {
+		s.next() // Ignore BOM if it's the first character.
+	}
+	return s
+}
+
+// NumErrors returns the number of errors found by the scanner.
+func (s *Scanner) NumErrors() int { return s.numErrors }
+
+const (
+	bom = 0xFEFF // byte order mark, permitted as very first character
+	eof = -1     // end of file
+)
+
+// next reads the next Unicode character into s.ch. s.ch == eof indicates
+// end of file.
+func (s *Scanner) next() {
+	if s.readOffset >= len(s.input) {
+		s.offset = len(s.input)
+		if s.ch == '\n' {
+			// Make sure we track final newlines at the end of the file
+			s.file.AddLine(s.offset)
+		}
+		s.ch = eof
+		return
+	}
+
+	s.offset = s.readOffset
+	if s.ch == '\n' {
+		s.file.AddLine(s.offset)
+	}
+
+	r, width := rune(s.input[s.readOffset]), 1
+	switch {
+	case r == 0:
+		s.onError(s.offset, "illegal character NUL")
+	case r >= utf8.RuneSelf:
+		r, width = utf8.DecodeRune(s.input[s.readOffset:])
+		if r == utf8.RuneError && width == 1 {
+			s.onError(s.offset, "illegal UTF-8 encoding")
+		} else if r == bom && s.offset > 0 {
+			s.onError(s.offset, "illegal byte order mark")
+		}
+	}
+	s.readOffset += width
+	s.ch = r
+}
+
+func (s *Scanner) onError(offset int, msg string) {
+	if s.err != nil {
+		s.err(s.file.Pos(offset), msg)
+	}
+	s.numErrors++
+}
+
+// Scan scans the next token and returns the token's position, the token
+// itself, and the token's literal string (when applicable). The end of the
+// input is indicated by token.EOF.
+//
+// If the returned token is a literal (such as token.STRING), then lit
+// contains the corresponding literal text (including surrounding quotes).
+//
+// If the returned token is a keyword, lit is the keyword text that was
+// scanned.
+//
+// If the returned token is token.TERMINATOR, lit will contain "\n".
+//
+// If the returned token is token.ILLEGAL, lit contains the offending
+// character.
+//
+// In all other cases, lit will be an empty string.
+//
+// For more tolerant parsing, Scan returns a valid token character whenever
+// possible when a syntax error was encountered. Callers must check
+// NumErrors or the number of times the provided ErrorHandler was invoked to
+// ensure there were no errors found during scanning.
+//
+// Scan will inject line information to the file provided by New.
+// Returned token positions are relative to that file.
+func (s *Scanner) Scan() (pos token.Pos, tok token.Token, lit string) {
+	s.skipWhitespace()
+
+	// Start of current token.
+	off := s.offset
+	pos = s.file.Pos(off)
+
+	var insertTerm bool
+
+	switch ch := s.ch; {
+	case isLetter(ch):
+		lit = s.scanIdentifier()
+		tok = token.Lookup(lit)
+		insertTerm = true
+
+	case isDecimal(ch):
+		tok, lit = s.scanNumber()
+		insertTerm = true
+
+	default:
+		s.next() // Always make progress
+
+		switch ch {
+		case eof:
+			if s.insertTerm {
+				s.insertTerm = false // Consumed EOF
+				return pos, token.TERMINATOR, "\n"
+			}
+			tok = token.EOF
+
+		case '\n':
+			// This case is only reachable when s.insertTerm is true, since
+			// otherwise skipWhitespace consumes all other newlines.
+			s.insertTerm = false // Consumed newline
+			return pos, token.TERMINATOR, "\n"
+
+		case '"':
+			insertTerm = true
+			tok = token.STRING
+			lit = s.scanString()
+
+		case '`':
+			insertTerm = true
+			tok = token.STRING
+			lit = s.scanRawString()
+
+		case '#':
+			// A comment never changes whether a terminator is inserted.
+			insertTerm = s.insertTerm
+			tok, lit = token.COMMENT, s.scanLineComment(off)
+
+		case '/':
+			switch s.ch {
+			case '/':
+				insertTerm = s.insertTerm
+				tok, lit = token.COMMENT, s.scanLineComment(off)
+			case '*':
+				insertTerm = s.insertTerm
+				tok, lit = token.COMMENT, s.scanBlockComment(off)
+			default:
+				tok = token.DIV
+			}
+
+		case '|':
+			if s.ch != '|' {
+				s.onError(s.offset, "missing second | in ||")
+			} else {
+				s.next() // consume second '|'
+			}
+			tok = token.OR
+		case '&':
+			if s.ch != '&' {
+				s.onError(s.offset, "missing second & in &&")
+			} else {
+				s.next() // consume second '&'
+			}
+			tok = token.AND
+
+		case '!': // !, !=
+			tok = s.switch2(token.NOT, token.NEQ, '=')
+		case '=': // =, ==
+			tok = s.switch2(token.ASSIGN, token.EQ, '=')
+		case '<': // <, <=
+			tok = s.switch2(token.LT, token.LTE, '=')
+		case '>': // >, >=
+			tok = s.switch2(token.GT, token.GTE, '=')
+		case '+':
+			tok = token.ADD
+		case '-':
+			tok = token.SUB
+		case '*':
+			tok = token.MUL
+		case '%':
+			tok = token.MOD
+		case '^':
+			tok = token.POW
+
+		case '{':
+			tok = token.LCURLY
+		case '}':
+			insertTerm = true
+			tok = token.RCURLY
+		case '(':
+			tok = token.LPAREN
+		case ')':
+			insertTerm = true
+			tok = token.RPAREN
+		case '[':
+			tok = token.LBRACK
+		case ']':
+			insertTerm = true
+			tok = token.RBRACK
+		case ',':
+			tok = token.COMMA
+		case '.':
+			tok = token.DOT
+
+		default:
+			// s.next() reports invalid BOMs so we don't need to repeat the
+			// error.
+			if ch != bom {
+				s.onError(off, fmt.Sprintf("illegal character %#U", ch))
+			}
+			insertTerm = s.insertTerm // Preserve previous s.insertTerm state
+			tok = token.ILLEGAL
+			lit = string(ch)
+		}
+	}
+
+	s.insertTerm = insertTerm
+	return
+}
+
+func (s *Scanner) skipWhitespace() {
+	for s.ch == ' ' || s.ch == '\t' || s.ch == '\r' || (s.ch == '\n' && !s.insertTerm) {
+		s.next()
+	}
+}
+
+func isLetter(ch rune) bool {
+	return 'a' <= lower(ch) && lower(ch) <= 'z' || ch == '_' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+func isDigit(ch rune) bool {
+	return isDecimal(ch) || ch >= utf8.RuneSelf && unicode.IsDigit(ch)
+}
+
+func isDecimal(ch rune) bool { return '0' <= ch && ch <= '9' }
+
+func isHex(ch rune) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= lower(ch) && lower(ch) <= 'f'
+}
+
+// lower returns the lowercase version of ch, if ch is an ASCII letter.
+func lower(ch rune) rune { return ('a' - 'A') | ch }
+
+func (s *Scanner) scanIdentifier() string {
+	off := s.offset
+	for isLetter(s.ch) || isDigit(s.ch) {
+		s.next()
+	}
+	return string(s.input[off:s.offset])
+}
+
+func (s *Scanner) scanNumber() (tok token.Token, lit string) {
+	tok = token.NUMBER
+	off := s.offset
+
+	for isDecimal(s.ch) {
+		s.next()
+	}
+
+	if s.ch == '.' {
+		tok = token.FLOAT
+		s.next()
+		for isDecimal(s.ch) {
+			s.next()
+		}
+	}
+
+	if lower(s.ch) == 'e' {
+		tok = token.FLOAT
+		s.next()
+		if s.ch == '+' || s.ch == '-' {
+			s.next()
+		}
+		if !isDecimal(s.ch) {
+			s.onError(s.offset, "exponent has no digits")
+		}
+		for isDecimal(s.ch) {
+			s.next()
+		}
+	}
+
+	return tok, string(s.input[off:s.offset])
+}
+
+// scanString scans a double-quoted string. The opening quote has already
+// been consumed.
+func (s *Scanner) scanString() string {
+	off := s.offset - 1 // '"' opening already consumed
+
+	for {
+		ch := s.ch
+		if ch == '\n' || ch == eof {
+			s.onError(off, "string literal not terminated")
+			break
+		}
+		s.next()
+		if ch == '"' {
+			break
+		}
+		if ch == '\\' {
+			s.scanEscape()
+		}
+	}
+
+	return string(s.input[off:s.offset])
+}
+
+// scanEscape parses an escape sequence. In case of a syntax error, it stops
+// at the offending character (without consuming it) and returns false.
+// Otherwise it returns true.
+func (s *Scanner) scanEscape() bool {
+	off := s.offset
+
+	var (
+		n         int
+		base, max uint32
+	)
+
+	switch s.ch {
+	case 'a', 'b', 'f', 'n', 'r', 't', 'v', '\\', '"':
+		s.next()
+		return true
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		n, base, max = 3, 8, 255
+	case 'x':
+		s.next()
+		n, base, max = 2, 16, 255
+	case 'u':
+		s.next()
+		n, base, max = 4, 16, unicode.MaxRune
+	case 'U':
+		s.next()
+		n, base, max = 8, 16, unicode.MaxRune
+	default:
+		msg := "unknown escape sequence"
+		if s.ch < 0 {
+			msg = "escape sequence not terminated"
+		}
+		s.onError(off, msg)
+		return false
+	}
+
+	var x uint32
+	for n > 0 {
+		d := uint32(digitVal(s.ch))
+		if d >= base {
+			msg := fmt.Sprintf("illegal character %#U in escape sequence", s.ch)
+			if s.ch < 0 {
+				msg = "escape sequence not terminated"
+			}
+			s.onError(s.offset, msg)
+			return false
+		}
+		x = x*base + d
+		s.next()
+		n--
+	}
+
+	if x > max || 0xD800 <= x && x < 0xE000 {
+		s.onError(off, "escape sequence is invalid Unicode code point")
+		return false
+	}
+	return true
+}
+
+func digitVal(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case isHex(ch):
+		return int(lower(ch) - 'a' + 10)
+	}
+	return 16 // larger than any legal digit val
+}
+
+// scanRawString scans a backtick-quoted string. The opening backtick has
+// already been consumed.
+func (s *Scanner) scanRawString() string {
+	off := s.offset - 1 // '`' opening already consumed
+
+	for {
+		ch := s.ch
+		if ch == eof {
+			s.onError(off, "raw string literal not terminated")
+			break
+		}
+		s.next()
+		if ch == '`' {
+			break
+		}
+	}
+
+	return string(s.input[off:s.offset])
+}
+
+// scanLineComment scans a comment up to the end of the line. The newline is
+// not part of the comment.
+func (s *Scanner) scanLineComment(off int) string {
+	for s.ch != '\n' && s.ch != eof {
+		s.next()
+	}
+	lit := s.input[off:s.offset]
+	// Strip carriage returns from Windows line endings.
+	if n := len(lit); n > 0 && lit[n-1] == '\r' {
+		lit = lit[:n-1]
+	}
+	return string(lit)
+}
+
+// scanBlockComment scans a /* */ comment. The leading '/' has already been
+// consumed and s.ch is the '*'.
+func (s *Scanner) scanBlockComment(off int) string {
+	s.next() // Consume '*'
+	for {
+		if s.ch == eof {
+			s.onError(off, "block comment not terminated")
+			break
+		}
+		ch := s.ch
+		s.next()
+		if ch == '*' && s.ch == '/' {
+			s.next()
+			break
+		}
+	}
+	return string(s.input[off:s.offset])
+}
+
+func (s *Scanner) switch2(tok0, tok1 token.Token, ch2 rune) token.Token {
+	if s.ch == ch2 {
+		s.next()
+		return tok1
+	}
+	return tok0
+}
+
+// IsValidIdentifier returns true if the string is a valid identifier.
+func IsValidIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, ch := range s {
+		if !isLetter(ch) && (i == 0 || !isDigit(ch)) {
+			return false
+		}
+	}
+	return token.Lookup(s) == token.IDENT
+}
@@ -0,0 +1,206 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+type tokenExample struct {
+	tok token.Token
+	lit string
+}
+
+func TestScanner(t *testing.T) {
+	input := `// comment
+prometheus.scrape "default" {
+	targets = [1, 2.5, 3e2]
+	enabled = !false && true || null
+	math = (1 + 2 - 3 * 4 / 5 % 6) ^ 7
+	cmp  = a == b != c < d <= e > f >= g
+	raw  = ` + "`multi\nline`" + ` # hash comment
+	esc  = "tab\té\x41"
+}
+`
+
+	expect := []tokenExample{
+		{token.COMMENT, "// comment"},
+		{token.IDENT, "prometheus"},
+		{token.DOT, ""},
+		{token.IDENT, "scrape"},
+		{token.STRING, `"default"`},
+		{token.LCURLY, ""},
+
+		{token.IDENT, "targets"},
+		{token.ASSIGN, ""},
+		{token.LBRACK, ""},
+		{token.NUMBER, "1"},
+		{token.COMMA, ""},
+		{token.FLOAT, "2.5"},
+		{token.COMMA, ""},
+		{token.FLOAT, "3e2"},
+		{token.RBRACK, ""},
+		{token.TERMINATOR, "\n"},
+
+		{token.IDENT, "enabled"},
+		{token.ASSIGN, ""},
+		{token.NOT, ""},
+		{token.BOOL, "false"},
+		{token.AND, ""},
+		{token.BOOL, "true"},
+		{token.OR, ""},
+		{token.NULL, "null"},
+		{token.TERMINATOR, "\n"},
+
+		{token.IDENT, "math"},
+		{token.ASSIGN, ""},
+		{token.LPAREN, ""},
+		{token.NUMBER, "1"},
+		{token.ADD, ""},
+		{token.NUMBER, "2"},
+		{token.SUB, ""},
+		{token.NUMBER, "3"},
+		{token.MUL, ""},
+		{token.NUMBER, "4"},
+		{token.DIV, ""},
+		{token.NUMBER, "5"},
+		{token.MOD, ""},
+		{token.NUMBER, "6"},
+		{token.RPAREN, ""},
+		{token.POW, ""},
+		{token.NUMBER, "7"},
+		{token.TERMINATOR, "\n"},
+
+		{token.IDENT, "cmp"},
+		{token.ASSIGN, ""},
+		{token.IDENT, "a"},
+		{token.EQ, ""},
+		{token.IDENT, "b"},
+		{token.NEQ, ""},
+		{token.IDENT, "c"},
+		{token.LT, ""},
+		{token.IDENT, "d"},
+		{token.LTE, ""},
+		{token.IDENT, "e"},
+		{token.GT, ""},
+		{token.IDENT, "f"},
+		{token.GTE, ""},
+		{token.IDENT, "g"},
+		{token.TERMINATOR, "\n"},
+
+		{token.IDENT, "raw"},
+		{token.ASSIGN, ""},
+		{token.STRING, "`multi\nline`"},
+		{token.COMMENT, "# hash comment"},
+		{token.TERMINATOR, "\n"},
+
+		{token.IDENT, "esc"},
+		{token.ASSIGN, ""},
+		{token.STRING, `"tab\té\x41"`},
+		{token.TERMINATOR, "\n"},
+
+		{token.RCURLY, ""},
+		{token.TERMINATOR, "\n"},
+		{token.EOF, ""},
+	}
+
+	file := token.NewFile("test.alloy")
+	s := New(file, []byte(input), func(pos token.Pos, msg string) {
+		t.Errorf("unexpected error at %s: %s", pos, msg)
+	})
+
+	for i, want := range expect {
+		_, tok, lit := s.Scan()
+		if tok != want.tok || lit != want.lit {
+			t.Fatalf("token %d: got %s %q, want %s %q", i, tok, lit, want.tok, want.lit)
+		}
+	}
+}
+
+func TestScanner_Positions(t *testing.T) {
+	file := token.NewFile("test.alloy")
+	s := New(file, []byte("a = 1\n\nblock {\n  b = \"x\"\n}"), nil)
+
+	expect := []struct {
+		tok       token.Token
+		line, col int
+	}{
+		{token.IDENT, 1, 1},
+		{token.ASSIGN, 1, 3},
+		{token.NUMBER, 1, 5},
+		{token.TERMINATOR, 1, 6},
+		{token.IDENT, 3, 1},
+		{token.LCURLY, 3, 7},
+		{token.IDENT, 4, 3},
+		{token.ASSIGN, 4, 5},
+		{token.STRING, 4, 7},
+		{token.TERMINATOR, 4, 10},
+		{token.RCURLY, 5, 1},
+	}
+
+	for _, want := range expect {
+		pos, tok, _ := s.Scan()
+		got := pos.Position()
+		if tok != want.tok || got.Line != want.line || got.Column != want.col {
+			t.Errorf("got %s at %d:%d, want %s at %d:%d", tok, got.Line, got.Column, want.tok, want.line, want.col)
+		}
+	}
+}
+
+func TestScanner_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+		col   int
+	}{
+		{`"unterminated`, "string literal not terminated", 1},
+		{"`raw", "raw string literal not terminated", 1},
+		{`"\q"`, "unknown escape sequence", 3},
+		{`"\xZZ"`, "illegal character U+005A 'Z' in escape sequence", 4},
+		{"/* open", "block comment not terminated", 1},
+		{"a | b", "missing second | in ||", 4},
+		{"a & b", "missing second & in &&", 4},
+		{"1e", "exponent has no digits", 3},
+		{"a @ b", "illegal character U+0040 '@'", 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			var (
+				gotMsg string
+				gotCol int
+			)
+			s := New(token.NewFile(""), []byte(tc.input), func(pos token.Pos, msg string) {
+				if gotMsg == "" {
+					gotMsg, gotCol = msg, pos.Position().Column
+				}
+			})
+			for {
+				if _, tok, _ := s.Scan(); tok == token.EOF {
+					break
+				}
+			}
+			if gotMsg != tc.msg || gotCol != tc.col {
+				t.Errorf("got error %q at column %d, want %q at column %d", gotMsg, gotCol, tc.msg, tc.col)
+			}
+		})
+	}
+}
+
+func TestIsValidIdentifier(t *testing.T) {
+	tests := map[string]bool{
+		"foo":     true,
+		"_foo1":   true,
+		"héllo":   true,
+		"1foo":    false,
+		"foo-bar": false,
+		"":        false,
+		"true":    false,
+		"null":    false,
+	}
+	for in, want := range tests {
+		if got := IsValidIdentifier(in); got != want {
+			t.Errorf("IsValidIdentifier(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package syntax
+
+import (
+	"reflect"
+
+	"github.com/jharvey10/test-repo/syntax/internal/syntaxtags"
+)
+
+// Field describes an attribute or block accepted by a struct using syntax
+// struct tags.
+type Field struct {
+	// Name of the attribute or block. Nested block names are dot-separated,
+	// such as "tls.config".
+	Name string
+
+	// Block reports whether the field is set by a block rather than an
+	// attribute.
+	Block bool
+
+	// Optional reports whether the field may be omitted.
+	Optional bool
+
+	// Type is the Go type of the struct field.
+	Type reflect.Type
+}
+
+// Fields returns the attributes and blocks accepted by the struct type ty,
+// in declaration order. Label fields are not included.
+//
+// ty may also be a pointer, slice or array of a struct type, such as the
+// Type of a block Field, so the fields of nested blocks can be listed.
+// Fields returns nil for any other type.
+func Fields(ty reflect.Type) []Field {
+	for ty != nil && (ty.Kind() == reflect.Pointer || ty.Kind() == reflect.Slice || ty.Kind() == reflect.Array) {
+		ty = ty.Elem()
+	}
+	if ty == nil || ty.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []Field
+	for _, f := range syntaxtags.Get(ty) {
+		if f.IsLabel() {
+			continue
+		}
+		fields = append(fields, Field{
+			Name:     f.FullName(),
+			Block:    f.IsBlock(),
+			Optional: f.IsOptional(),
+			Type:     ty.FieldByIndex(f.Index).Type,
+		})
+	}
+	return fields
+}
@@ -0,0 +1,48 @@
+package syntax_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax"
+)
+
+func TestFields(t *testing.T) {
+	type field struct {
+		name     string
+		block    bool
+		optional bool
+	}
+	names := func(fields []syntax.Field) []field {
+		var res []field
+		for _, f := range fields {
+			res = append(res, field{f.Name, f.Block, f.Optional})
+		}
+		return res
+	}
+
+	fields := syntax.Fields(reflect.TypeOf(encodeConfig{}))
+	expect := []field{
+		{"name", false, false},
+		{"scrape_interval", false, true},
+		{"labels", false, true},
+		{"ports", false, true},
+		{"unset", false, true},
+		{"target", true, true},
+		{"tls", true, true},
+	}
+	if got := names(fields); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expected %v, got %v", expect, got)
+	}
+
+	// The fields of nested blocks are listed through the block's type,
+	// skipping the label.
+	nested := syntax.Fields(fields[5].Type)
+	if got, expect := names(nested), []field{{"address", false, false}}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected %v, got %v", expect, got)
+	}
+
+	if fields := syntax.Fields(reflect.TypeOf("")); fields != nil {
+		t.Errorf("expected no fields for a string, got %v", fields)
+	}
+}
@@ -0,0 +1,13 @@
+package syntax
+
+import "github.com/jharvey10/test-repo/syntax/vm"
+
+// RootScope returns the scope that every configuration file is evaluated
+// in. It exposes the constants map, so expressions can refer to
+// constants.hostname, constants.os, constants.arch, constants.version,
+// constants.build and any constant added with RegisterConstant.
+func RootScope() *vm.Scope {
+	return vm.NewScope(nil, map[string]any{
+		"constants": snapshotConstants(),
+	})
+}
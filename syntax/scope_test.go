@@ -0,0 +1,64 @@
+package syntax
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+func TestRootScope(t *testing.T) {
+	if err := RegisterConstant("test_environment", "staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := map[string]string{
+		"constants.os":               runtime.GOOS,
+		"constants.arch":             runtime.GOARCH,
+		"constants.build.go_version": runtime.Version(),
+		"constants.build.version":    Build().Version,
+		"constants.test_environment": "staging",
+	}
+
+	for input, expect := range tt {
+		expr, err := parser.ParseExpression(input)
+		if err != nil {
+			t.Fatalf("parse %s: %v", input, err)
+		}
+
+		var actual string
+		if err := vm.New(expr).Evaluate(RootScope(), &actual); err != nil {
+			t.Fatalf("evaluate %s: %v", input, err)
+		}
+		if actual != expect {
+			t.Errorf("%s: expected %q, got %q", input, expect, actual)
+		}
+	}
+}
+
+func TestRegisterConstant_Errors(t *testing.T) {
+	for _, name := range []string{"os", "build", "not-an-identifier", ""} {
+		if err := RegisterConstant(name, 1); err == nil {
+			t.Errorf("expected an error registering %q", name)
+		}
+	}
+}
+
+func TestRootScope_UnsupportedConstant(t *testing.T) {
+	if err := RegisterConstant("test_ports", map[int]string{80: "http"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expr, err := parser.ParseExpression("constants.test_ports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual map[int]string
+	if err := vm.New(expr).Evaluate(RootScope(), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual[80] != "http" {
+		t.Errorf("unexpected result %v", actual)
+	}
+}
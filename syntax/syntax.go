@@ -0,0 +1,63 @@
+// Package syntax implements the configuration language: its parser,
+// evaluator and the decoding of configuration into Go values.
+package syntax
+
+import (
+	"github.com/jharvey10/test-repo/syntax/internal/value"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+// Defaulter is implemented by types which have default values. SetToDefault
+// is called before the type is decoded.
+type Defaulter = value.Defaulter
+
+// Validator is implemented by types which validate themselves after being
+// decoded. Errors returned by Validate point at the block or value which
+// was decoded.
+type Validator = value.Validator
+
+// Unmarshaler is implemented by types which customize how they are decoded.
+// UnmarshalSyntax is given a function which decodes the original block or
+// value into a pointer.
+type Unmarshaler = value.Unmarshaler
+
+// Capsule is a marker interface for Go types which are passed through
+// expressions as opaque values, such as appenders or receivers exported by
+// one component and consumed by another. Channels, interfaces with methods
+// and structs without syntax struct tags are capsules without needing to
+// implement Capsule.
+type Capsule = value.Capsule
+
+// Unmarshal parses the configuration file in and decodes its body into v,
+// which must be a pointer to a struct using syntax struct tags:
+//
+//	type Config struct {
+//		Name    string   `syntax:"name,attr"`
+//		Timeout int      `syntax:"timeout,attr,optional"`
+//		Targets []Target `syntax:"target,block,optional"`
+//	}
+//
+//	type Target struct {
+//		Label   string `syntax:",label"`
+//		Address string `syntax:"address,attr"`
+//	}
+//
+// Expressions are evaluated in the RootScope. Errors point at the range of
+// source which caused them.
+func Unmarshal(in []byte, v any) error {
+	f, err := parser.ParseFile("", in)
+	if err != nil {
+		return err
+	}
+	return vm.New(f.Body).Evaluate(RootScope(), v)
+}
+
+// UnmarshalValue evaluates the expression in and decodes the result into v.
+func UnmarshalValue(in []byte, v any) error {
+	expr, err := parser.ParseExpression(string(in))
+	if err != nil {
+		return err
+	}
+	return vm.New(expr).Evaluate(RootScope(), v)
+}
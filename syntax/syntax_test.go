@@ -0,0 +1,71 @@
+package syntax_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax"
+)
+
+type endpoint struct {
+	URL     string            `syntax:"url,attr"`
+	Options map[string]string `syntax:"options,attr,optional"`
+}
+
+type config struct {
+	Name      string     `syntax:"name,attr"`
+	Endpoints []endpoint `syntax:"endpoints,attr,optional"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	in := `
+		name      = "example"
+		endpoints = [
+			{ url = "http://a" },
+			{ url = "http://b", options = { retries = "3" } },
+		]
+	`
+
+	var actual config
+	if err := syntax.Unmarshal([]byte(in), &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := config{
+		Name: "example",
+		Endpoints: []endpoint{
+			{URL: "http://a"},
+			{URL: "http://b", Options: map[string]string{"retries": "3"}},
+		},
+	}
+	if !reflect.DeepEqual(expect, actual) {
+		t.Errorf("expected %#v, got %#v", expect, actual)
+	}
+}
+
+func TestUnmarshal_ObjectErrors(t *testing.T) {
+	tt := map[string]string{
+		`name = "x"
+endpoints = [{ url = 5 }]`: `2:22: expected string, got number`,
+		`name = "x"
+endpoints = [{ uri = "http://a" }]`: `2:14: missing required attribute "url"`,
+	}
+
+	for in, expect := range tt {
+		var c config
+		err := syntax.Unmarshal([]byte(in), &c)
+		if err == nil || err.Error() != expect {
+			t.Errorf("expected error %q, got %v", expect, err)
+		}
+	}
+}
+
+func TestUnmarshalValue(t *testing.T) {
+	var actual endpoint
+	if err := syntax.UnmarshalValue([]byte(`{ url = "http://" + constants.os }`), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.URL == "http://" {
+		t.Errorf("expected constants to be resolved, got %q", actual.URL)
+	}
+}
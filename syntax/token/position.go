@@ -0,0 +1,173 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// NoPos is the zero value for Pos. It has no file or line information
+// associated with it, and NoPos.Valid is false.
+var NoPos = Pos{}
+
+// Pos is a compact representation of a position within a file. It can be
+// converted into a Position for a more convenient, but larger,
+// representation.
+type Pos struct {
+	file *File
+	off  int
+}
+
+// String returns the string form of the Position that p represents.
+func (p Pos) String() string { return p.Position().String() }
+
+// File returns the file used by the Pos. This will be nil for invalid
+// positions.
+func (p Pos) File() *File { return p.file }
+
+// Position converts the Pos into a Position.
+func (p Pos) Position() Position { return p.file.PositionFor(p) }
+
+// Add creates a new Pos relative to p.
+func (p Pos) Add(n int) Pos {
+	return Pos{file: p.file, off: p.off + n}
+}
+
+// Offset returns the byte offset associated with Pos. The offset accounts
+// for the base of the file set with File.SetBase.
+func (p Pos) Offset() int {
+	if p.file == nil {
+		return p.off
+	}
+	return p.file.base() + p.off
+}
+
+// Valid reports whether p is a valid position.
+func (p Pos) Valid() bool { return p.file != nil }
+
+// Before reports whether p is before other in the same file.
+func (p Pos) Before(other Pos) bool { return p.Offset() < other.Offset() }
+
+// Position holds full position information for a location within an
+// individual file.
+type Position struct {
+	Filename string // Filename (if any)
+	Offset   int    // Byte offset (starting at 0)
+	Line     int    // Line number (starting at 1)
+	Column   int    // Offset from start of line (starting at 1)
+}
+
+// Valid reports whether the position is valid. Valid positions must have a
+// Line value greater than 0.
+func (pos *Position) Valid() bool { return pos.Line > 0 }
+
+// String returns a string in one of the following forms:
+//
+//	file:line:column   Valid position with file name
+//	file:line          Valid position with file name but no column
+//	line:column        Valid position with no file name
+//	line               Valid position with no file name or column
+//	file               Invalid position with file name
+//	-                  Invalid position with no file name
+func (pos Position) String() string {
+	s := pos.Filename
+
+	if pos.Valid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d", pos.Line)
+		if pos.Column != 0 {
+			s += fmt.Sprintf(":%d", pos.Column)
+		}
+	}
+
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// File holds position information for a specific file.
+type File struct {
+	filename string
+
+	mut   sync.RWMutex
+	lines []int // Byte offset of each line number (first element is always 0).
+
+	// Offset and number of lines before the start of the file, when it is
+	// part of a larger document.
+	baseOffset, baseLines int
+}
+
+// NewFile creates a new File for storing position information.
+func NewFile(filename string) *File {
+	return &File{
+		filename: filename,
+		lines:    []int{0},
+	}
+}
+
+// Pos returns a Pos given a byte offset. Pos panics if off is < 0.
+func (f *File) Pos(off int) Pos {
+	if off < 0 {
+		panic("Pos: illegal offset")
+	}
+	return Pos{file: f, off: off}
+}
+
+// Name returns the name of the file.
+func (f *File) Name() string { return f.filename }
+
+// SetBase places the file within a larger document, with the first byte of
+// the file at byte offset off of the document, at the start of line line.
+// Positions in the file, including ones created before the call, are then
+// reported relative to the document. SetBase allows the AST of a part of
+// the document to be reused after text before it changed.
+func (f *File) SetBase(off, line int) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.baseOffset, f.baseLines = off, line-1
+}
+
+func (f *File) base() int {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return f.baseOffset
+}
+
+// AddLine tracks a new line from a byte offset. The line offset must be
+// larger than the offset for the previous line, otherwise the line offset is
+// ignored.
+func (f *File) AddLine(offset int) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if len(f.lines) == 0 || f.lines[len(f.lines)-1] < offset {
+		f.lines = append(f.lines, offset)
+	}
+}
+
+// PositionFor returns a Position from an offset.
+func (f *File) PositionFor(p Pos) Position {
+	if p == NoPos || f == nil {
+		return Position{}
+	}
+
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+
+	// Search for the line containing the offset: the last line which starts
+	// at or before it.
+	i := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > p.off }) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return Position{
+		Filename: f.filename,
+		Offset:   f.baseOffset + p.off,
+		Line:     f.baseLines + i + 1,
+		Column:   p.off - f.lines[i] + 1,
+	}
+}
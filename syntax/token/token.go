@@ -0,0 +1,158 @@
+// Package token defines the lexical elements of the configuration language
+// and the source positions they are found at.
+package token
+
+import "strconv"
+
+// Token is a lexical token of the configuration language.
+type Token int
+
+// List of all lexical tokens and examples that represent them.
+const (
+	ILLEGAL Token = iota // Invalid token.
+	LITERAL              // Start of literals.
+
+	EOF
+	COMMENT // Comment (// or /* */ or #)
+
+	IDENT  // foobar
+	NUMBER // 1234
+	FLOAT  // 1234.0
+	STRING // "foobar" or `foobar`
+
+	BOOL // true or false
+	NULL // null
+
+	OR  // ||
+	AND // &&
+	NOT // !
+
+	ASSIGN // =
+
+	EQ  // ==
+	NEQ // !=
+	LT  // <
+	LTE // <=
+	GT  // >
+	GTE // >=
+
+	ADD // +
+	SUB // -
+	MUL // *
+	DIV // /
+	MOD // %
+	POW // ^
+
+	LCURLY // {
+	RCURLY // }
+	LPAREN // (
+	RPAREN // )
+	LBRACK // [
+	RBRACK // ]
+	COMMA  // ,
+	DOT    // .
+
+	TERMINATOR // \n
+)
+
+var tokenNames = [...]string{
+	ILLEGAL: "ILLEGAL",
+	LITERAL: "LITERAL",
+
+	EOF:     "EOF",
+	COMMENT: "COMMENT",
+
+	IDENT:  "IDENT",
+	NUMBER: "NUMBER",
+	FLOAT:  "FLOAT",
+	STRING: "STRING",
+	BOOL:   "BOOL",
+	NULL:   "NULL",
+
+	OR:  "||",
+	AND: "&&",
+	NOT: "!",
+
+	ASSIGN: "=",
+	EQ:     "==",
+	NEQ:    "!=",
+	LT:     "<",
+	LTE:    "<=",
+	GT:     ">",
+	GTE:    ">=",
+
+	ADD: "+",
+	SUB: "-",
+	MUL: "*",
+	DIV: "/",
+	MOD: "%",
+	POW: "^",
+
+	LCURLY: "{",
+	RCURLY: "}",
+	LPAREN: "(",
+	RPAREN: ")",
+	LBRACK: "[",
+	RBRACK: "]",
+	COMMA:  ",",
+	DOT:    ".",
+
+	TERMINATOR: "TERMINATOR",
+}
+
+// Lookup maps a string to its keyword token or IDENT if it's not a keyword.
+func Lookup(ident string) Token {
+	switch ident {
+	case "true", "false":
+		return BOOL
+	case "null":
+		return NULL
+	default:
+		return IDENT
+	}
+}
+
+// String returns the string representation corresponding to the token t.
+func (t Token) String() string {
+	if t >= 0 && int(t) < len(tokenNames) {
+		return tokenNames[t]
+	}
+	return "token(" + strconv.Itoa(int(t)) + ")"
+}
+
+// GoString returns the %#v format of t.
+func (t Token) GoString() string { return t.String() }
+
+// IsLiteral returns true if t is a literal token.
+func (t Token) IsLiteral() bool { return t > LITERAL && t < OR }
+
+// IsOperator returns true if t is an operator or delimiter token.
+func (t Token) IsOperator() bool { return t >= OR && t < TERMINATOR }
+
+// BinaryPrecedence returns the operator precedence of the binary operator
+// t. If t is not a binary operator, the result is LowestPrecedence.
+func (t Token) BinaryPrecedence() int {
+	switch t {
+	case OR:
+		return 1
+	case AND:
+		return 2
+	case EQ, NEQ, LT, LTE, GT, GTE:
+		return 3
+	case ADD, SUB:
+		return 4
+	case MUL, DIV, MOD:
+		return 5
+	case POW:
+		return 6
+	}
+
+	return LowestPrecedence
+}
+
+// Levels of precedence for operator tokens.
+const (
+	LowestPrecedence  = 0 // non-operators
+	UnaryPrecedence   = 7
+	HighestPrecedence = 8
+)
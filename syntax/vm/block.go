@@ -0,0 +1,177 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/internal/syntaxtags"
+	"github.com/jharvey10/test-repo/syntax/internal/value"
+)
+
+var (
+	goDefaulter   = reflect.TypeOf((*value.Defaulter)(nil)).Elem()
+	goValidator   = reflect.TypeOf((*value.Validator)(nil)).Elem()
+	goUnmarshaler = reflect.TypeOf((*value.Unmarshaler)(nil)).Elem()
+)
+
+// evaluateBlockOrBody decodes node, which must be an *ast.BlockStmt or an
+// ast.Body, into rv.
+func (vm *Evaluator) evaluateBlockOrBody(scope *Scope, assoc map[value.Value]ast.Node, node ast.Node, rv reflect.Value) error {
+	// Allocate pointers as needed.
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Addr().Type().Implements(goUnmarshaler) {
+		return rv.Addr().Interface().(value.Unmarshaler).UnmarshalSyntax(func(v any) error {
+			target := reflect.ValueOf(v)
+			if target.Kind() != reflect.Pointer || target.IsNil() {
+				panic(fmt.Sprintf("syntax/vm: UnmarshalSyntax called with non-pointer %T", v))
+			}
+			return vm.evaluateBlockOrBody(scope, assoc, node, target)
+		})
+	}
+
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("syntax/vm: blocks must be decoded into structs, got %s", rv.Type()))
+	}
+
+	if rv.Addr().Type().Implements(goDefaulter) {
+		rv.Set(reflect.Zero(rv.Type()))
+		rv.Addr().Interface().(value.Defaulter).SetToDefault()
+	}
+
+	fields := syntaxtags.Get(rv.Type())
+
+	var body ast.Body
+	switch node := node.(type) {
+	case *ast.BlockStmt:
+		if err := decodeLabel(node, fields, rv); err != nil {
+			return err
+		}
+		body = node.Body
+	case ast.Body:
+		body = node
+	default:
+		panic(fmt.Sprintf("syntax/vm: cannot decode %T into a struct", node))
+	}
+
+	if err := vm.decodeBody(scope, assoc, node, body, fields, rv); err != nil {
+		return err
+	}
+
+	if rv.Addr().Type().Implements(goValidator) {
+		if err := rv.Addr().Interface().(value.Validator).Validate(); err != nil {
+			return newError(node, err.Error())
+		}
+	}
+	return nil
+}
+
+func decodeLabel(block *ast.BlockStmt, fields []syntaxtags.Field, rv reflect.Value) error {
+	for _, f := range fields {
+		if !f.IsLabel() {
+			continue
+		}
+		if block.Label == "" {
+			return newError(block, fmt.Sprintf("block %q requires non-empty label", block.GetBlockName()))
+		}
+		rv.FieldByIndex(f.Index).SetString(block.Label)
+		return nil
+	}
+
+	if block.Label != "" {
+		return &Error{
+			StartPos: block.LabelPos.Position(),
+			EndPos:   block.LabelPos.Add(len(block.Label) + 1).Position(),
+			Message:  fmt.Sprintf("block %q does not support specifying labels", block.GetBlockName()),
+		}
+	}
+	return nil
+}
+
+func (vm *Evaluator) decodeBody(scope *Scope, assoc map[value.Value]ast.Node, node ast.Node, body ast.Body, fields []syntaxtags.Field, rv reflect.Value) error {
+	byName := make(map[string]syntaxtags.Field, len(fields))
+	for _, f := range fields {
+		if !f.IsLabel() {
+			byName[f.FullName()] = f
+		}
+	}
+
+	seen := make(map[string]bool, len(body))
+
+	for _, stmt := range body {
+		switch stmt := stmt.(type) {
+		case *ast.AttributeStmt:
+			name := stmt.Name.Name
+			f, ok := byName[name]
+			switch {
+			case !ok:
+				return newError(stmt.Name, fmt.Sprintf("unrecognized attribute name %q", name))
+			case f.IsBlock():
+				return newError(stmt.Name, fmt.Sprintf("%q must be a block, but is used as an attribute", name))
+			case seen[name]:
+				return newError(stmt.Name, fmt.Sprintf("attribute %q may only be provided once", name))
+			}
+			seen[name] = true
+
+			val, err := vm.evaluateExpr(scope, assoc, stmt.Value)
+			if err != nil {
+				return err
+			}
+			if err := value.Decode(val, rv.FieldByIndex(f.Index).Addr().Interface()); err != nil {
+				return makeError(stmt.Value, assoc, err)
+			}
+
+		case *ast.BlockStmt:
+			name := stmt.GetBlockName()
+			f, ok := byName[name]
+			switch {
+			case !ok:
+				return newError(stmt, fmt.Sprintf("unrecognized block name %q", name))
+			case f.IsAttr():
+				return newError(stmt, fmt.Sprintf("%q must be an attribute, but is used as a block", name))
+			}
+
+			field := rv.FieldByIndex(f.Index)
+			if field.Kind() == reflect.Slice {
+				// Slices of blocks allow the block to be repeated.
+				elem := reflect.New(field.Type().Elem()).Elem()
+				if err := vm.evaluateBlockOrBody(scope, assoc, stmt, elem); err != nil {
+					return err
+				}
+				if !seen[name] {
+					field.Set(reflect.MakeSlice(field.Type(), 0, 1))
+				}
+				field.Set(reflect.Append(field, elem))
+			} else {
+				if seen[name] {
+					return newError(stmt, fmt.Sprintf("block %q may only be specified once", name))
+				}
+				if err := vm.evaluateBlockOrBody(scope, assoc, stmt, field); err != nil {
+					return err
+				}
+			}
+			seen[name] = true
+
+		default:
+			panic(fmt.Sprintf("syntax/vm: unexpected ast.Stmt type %T", stmt))
+		}
+	}
+
+	for _, f := range fields {
+		if f.IsLabel() || f.IsOptional() || seen[f.FullName()] {
+			continue
+		}
+		kind := "attribute"
+		if f.IsBlock() {
+			kind = "block"
+		}
+		return newError(node, fmt.Sprintf("missing required %s %q", kind, f.FullName()))
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package vm_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+type server struct {
+	Address string            `syntax:"address,attr"`
+	Port    int               `syntax:"port,attr,optional"`
+	TLS     *tlsConfig        `syntax:"tls,block,optional"`
+	Routes  []route           `syntax:"route,block,optional"`
+	Headers map[string]string `syntax:"headers,attr,optional"`
+}
+
+func (s *server) SetToDefault() { *s = server{Port: 8080} }
+
+func (s *server) Validate() error {
+	if s.Port <= 0 {
+		return errors.New("port must be positive")
+	}
+	return nil
+}
+
+type tlsConfig struct {
+	CertFile string `syntax:"cert_file,attr"`
+}
+
+type route struct {
+	Path   string `syntax:",label"`
+	Target string `syntax:"target,attr"`
+}
+
+// upper decodes a block normally and upper-cases its label.
+type upper struct {
+	Name string `syntax:",label"`
+}
+
+func (u *upper) UnmarshalSyntax(f func(v any) error) error {
+	type plain upper
+	if err := f((*plain)(u)); err != nil {
+		return err
+	}
+	u.Name = "[" + u.Name + "]"
+	return nil
+}
+
+func parseBody(t *testing.T, input string) ast.Body {
+	t.Helper()
+	f, err := parser.ParseFile("test.alloy", []byte(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return f.Body
+}
+
+func TestEvaluate_Block(t *testing.T) {
+	body := parseBody(t, `
+		address = "localhost"
+		headers = { "X-Env" = "dev" }
+
+		tls {
+			cert_file = "/etc/cert.pem"
+		}
+
+		route "a" { target = "a" }
+		route "b" { target = "b" }
+	`)
+
+	var actual server
+	if err := vm.New(body).Evaluate(vm.NewScope(nil, nil), &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := server{
+		Address: "localhost",
+		Port:    8080,
+		TLS:     &tlsConfig{CertFile: "/etc/cert.pem"},
+		Routes:  []route{{Path: "a", Target: "a"}, {Path: "b", Target: "b"}},
+		Headers: map[string]string{"X-Env": "dev"},
+	}
+	if !reflect.DeepEqual(expect, actual) {
+		t.Errorf("expected %#v, got %#v", expect, actual)
+	}
+}
+
+func TestEvaluate_BlockUnmarshaler(t *testing.T) {
+	body := parseBody(t, `u "name" {}`)
+
+	var actual struct {
+		U upper `syntax:"u,block"`
+	}
+	if err := vm.New(body).Evaluate(vm.NewScope(nil, nil), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.U.Name != "[name]" {
+		t.Errorf("expected label to be rewritten, got %q", actual.U.Name)
+	}
+}
+
+func TestEvaluate_BlockErrors(t *testing.T) {
+	tt := []struct {
+		name       string
+		input      string
+		expect     string
+		start, end string
+	}{
+		{
+			name:   "bad attribute type",
+			input:  "address = \"a\"\nport = \"80\"",
+			expect: "expected number, got string",
+			start:  "test.alloy:2:8", end: "test.alloy:2:11",
+		},
+		{
+			name:   "missing attribute",
+			input:  "port = 80",
+			expect: `missing required attribute "address"`,
+			start:  "test.alloy:1:1", end: "test.alloy:1:9",
+		},
+		{
+			name:   "unknown attribute",
+			input:  "address = \"a\"\nprot = 80",
+			expect: `unrecognized attribute name "prot"`,
+			start:  "test.alloy:2:1", end: "test.alloy:2:4",
+		},
+		{
+			name:   "duplicate attribute",
+			input:  "address = \"a\"\naddress = \"b\"",
+			expect: `attribute "address" may only be provided once`,
+			start:  "test.alloy:2:1", end: "test.alloy:2:7",
+		},
+		{
+			name:   "nested block error",
+			input:  "address = \"a\"\ntls {\n  cert_file = true\n}",
+			expect: "expected string, got bool",
+			start:  "test.alloy:3:15", end: "test.alloy:3:18",
+		},
+		{
+			name:   "missing label",
+			input:  "address = \"a\"\nroute { target = \"x\" }",
+			expect: `block "route" requires non-empty label`,
+			start:  "test.alloy:2:1", end: "test.alloy:2:22",
+		},
+		{
+			name:   "unexpected label",
+			input:  "address = \"a\"\ntls \"x\" { cert_file = \"c\" }",
+			expect: `block "tls" does not support specifying labels`,
+			start:  "test.alloy:2:5", end: "test.alloy:2:7",
+		},
+		{
+			name:   "repeated block",
+			input:  "address = \"a\"\ntls { cert_file = \"a\" }\ntls { cert_file = \"b\" }",
+			expect: `block "tls" may only be specified once`,
+			start:  "test.alloy:3:1", end: "test.alloy:3:23",
+		},
+		{
+			name:   "validation",
+			input:  "address = \"a\"\nport = -1",
+			expect: "port must be positive",
+			start:  "test.alloy:1:1", end: "test.alloy:2:9",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			body := parseBody(t, tc.input)
+
+			var s server
+			err := vm.New(body).Evaluate(vm.NewScope(nil, nil), &s)
+
+			var vmErr *vm.Error
+			if !errors.As(err, &vmErr) {
+				t.Fatalf("expected *vm.Error, got %v", err)
+			}
+			if vmErr.Message != tc.expect {
+				t.Errorf("expected message %q, got %q", tc.expect, vmErr.Message)
+			}
+			if start := vmErr.StartPos.String(); start != tc.start {
+				t.Errorf("expected start %s, got %s", tc.start, start)
+			}
+			if end := vmErr.EndPos.String(); end != tc.end {
+				t.Errorf("expected end %s, got %s", tc.end, end)
+			}
+		})
+	}
+}
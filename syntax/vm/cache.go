@@ -0,0 +1,296 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+)
+
+// Cache evaluates the top-level blocks of a configuration file
+// incrementally across reloads. A block is only evaluated again when its
+// AST changed or when a value it references changed.
+//
+// Blocks are considered unchanged when they are the same AST node as in
+// the previous evaluation, so Cache should be used with files parsed by a
+// parser.Cache, which reuses the nodes of unchanged statements. A Cache
+// must not be used concurrently.
+type Cache struct {
+	blocks map[string]*cachedBlock
+	hasher *hasher // Reused to fingerprint the inputs of every block.
+}
+
+type cachedBlock struct {
+	node  *ast.BlockStmt
+	refs  [][]string // Identifier paths referenced by the block.
+	calls bool       // Whether the block calls any functions.
+
+	inputs *fingerprint // The referenced values when last evaluated.
+	seen   bool         // Whether the block was evaluated since the last Sweep.
+}
+
+// NewCache creates an empty Cache.
+func NewCache() *Cache {
+	return &Cache{blocks: make(map[string]*cachedBlock), hasher: newHasher()}
+}
+
+// Evaluate evaluates block into v, like New(block).Evaluate(scope, v).
+//
+// Evaluate returns false without evaluating block, leaving v untouched, if
+// the previous evaluation of a block with the same name and label
+// succeeded, was given the same AST node, and every identifier the block
+// references has the same value in scope. v must then still hold the
+// result of that evaluation. Blocks which call functions are always
+// evaluated, since functions such as env may return different results for
+// the same arguments.
+//
+// Values are compared by a hash of their contents taken when the block is
+// evaluated, so values changed in place are detected. Pointers and channels
+// must be identical, and blocks referencing non-nil functions are always
+// evaluated.
+func (c *Cache) Evaluate(scope *Scope, block *ast.BlockStmt, v any) (bool, error) {
+	id := strings.Join(block.Name, ".")
+	if block.Label != "" {
+		id += "." + block.Label
+	}
+	return c.EvaluateID(scope, id, block, v)
+}
+
+// EvaluateID is like Evaluate, but identifies block by id rather than by
+// its name and label. It allows evaluating copies of blocks, such as the
+// body of a labeled block decoded without its label.
+func (c *Cache) EvaluateID(scope *Scope, id string, block *ast.BlockStmt, v any) (bool, error) {
+	cached := c.blocks[id]
+	if cached == nil || cached.node != block {
+		cached = newCachedBlock(block)
+		c.blocks[id] = cached
+	}
+	cached.seen = true
+
+	values := make([]reflect.Value, len(cached.refs))
+	for i, ref := range cached.refs {
+		values[i] = resolve(scope, ref)
+	}
+	inputs, comparable := c.hasher.fingerprint(values)
+	if cached.inputs != nil && !cached.calls && comparable && inputs.sum == cached.inputs.sum {
+		return false, nil
+	}
+
+	cached.inputs = nil
+	if err := New(block).Evaluate(scope, v); err != nil {
+		return true, err
+	}
+	if comparable {
+		cached.inputs = &inputs
+	}
+	return true, nil
+}
+
+// Sweep drops every block which was not evaluated since the previous call
+// to Sweep. Calling Sweep after evaluating every block of a file removes
+// the blocks which were deleted from it.
+func (c *Cache) Sweep() {
+	for id, cached := range c.blocks {
+		if !cached.seen {
+			delete(c.blocks, id)
+		}
+		cached.seen = false
+	}
+}
+
+func newCachedBlock(block *ast.BlockStmt) *cachedBlock {
+	cached := &cachedBlock{node: block}
+	ast.Walk(cached, block)
+	return cached
+}
+
+// Visit implements ast.Visitor, collecting the references and calls in the
+// block.
+func (cb *cachedBlock) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.CallExpr:
+		cb.calls = true
+		return nil
+	case *ast.IdentifierExpr, *ast.AccessExpr:
+		if path := refPath(node.(ast.Expr)); path != nil {
+			cb.refs = append(cb.refs, path)
+			return nil
+		}
+	}
+	return cb
+}
+
+// refPath returns the identifiers of a reference such as a.b.c, or nil if
+// expr is not a reference.
+func refPath(expr ast.Expr) []string {
+	switch expr := expr.(type) {
+	case *ast.IdentifierExpr:
+		return []string{expr.Ident.Name}
+	case *ast.AccessExpr:
+		if path := refPath(expr.Value); path != nil {
+			return append(path, expr.Name.Name)
+		}
+	}
+	return nil
+}
+
+// resolve returns the value of a reference in scope. It follows the path
+// through maps as far as it can, so a reference into a large map only
+// depends on the element it refers to. An invalid Value is returned for
+// identifiers which are not in scope.
+func resolve(scope *Scope, path []string) reflect.Value {
+	val, ok := scope.Lookup(path[0])
+	if !ok {
+		return reflect.Value{}
+	}
+
+	rv := reflect.ValueOf(val)
+	for _, name := range path[1:] {
+		for rv.Kind() == reflect.Interface {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		elem := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !elem.IsValid() {
+			break
+		}
+		rv = elem
+	}
+	return rv
+}
+
+// fingerprint identifies the values referenced by a block when it was
+// evaluated. It is a hash of the values rather than a copy of them, so
+// that values changed in place after the evaluation are still detected.
+type fingerprint struct {
+	sum [sha256.Size]byte
+	// pins keeps the targets of the hashed pointers alive, so that their
+	// addresses can't be reused by different values.
+	pins []unsafe.Pointer
+}
+
+func newHasher() *hasher {
+	return &hasher{Hash: sha256.New(), ok: true}
+}
+
+// fingerprint returns the fingerprint of vals, resetting h. It returns
+// false if any of them can never be considered unchanged, such as a
+// non-nil function.
+func (h *hasher) fingerprint(vals []reflect.Value) (fingerprint, bool) {
+	h.Reset()
+	h.pins, h.ok = nil, true
+	for _, v := range vals {
+		h.value(v)
+	}
+	fp := fingerprint{pins: h.pins}
+	h.Sum(fp.sum[:0])
+	return fp, h.ok
+}
+
+// hasher writes values to a hash. Pointers and channels are written as
+// their address, since they refer to values such as receivers which may
+// have the same contents but behave differently.
+type hasher struct {
+	hash.Hash
+	pins []unsafe.Pointer
+	ok   bool // Unset once a value which can't be compared was written.
+	buf  [8]byte
+}
+
+func (h *hasher) uint(u uint64) {
+	binary.LittleEndian.PutUint64(h.buf[:], u)
+	h.Write(h.buf[:])
+}
+
+func (h *hasher) string(s string) {
+	h.uint(uint64(len(s)))
+	io.WriteString(h, s)
+}
+
+func (h *hasher) bool(b bool) {
+	if b {
+		h.uint(1)
+	} else {
+		h.uint(0)
+	}
+}
+
+func (h *hasher) value(v reflect.Value) {
+	h.bool(v.IsValid())
+	if !v.IsValid() {
+		return
+	}
+	// Types are written as the address of their descriptor, which is
+	// unique to each type and never freed.
+	h.uint(uint64(reflect.ValueOf(v.Type()).Pointer()))
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		p := v.UnsafePointer()
+		h.pins = append(h.pins, p)
+		h.uint(uint64(uintptr(p)))
+	case reflect.Func:
+		h.ok = h.ok && v.IsNil()
+	case reflect.Interface:
+		h.bool(v.IsNil())
+		if !v.IsNil() {
+			h.value(v.Elem())
+		}
+	case reflect.Map:
+		h.bool(v.IsNil())
+		h.uint(uint64(v.Len()))
+		// Entries are hashed separately and written in order of their
+		// hashes, since maps are unordered.
+		entries := make([][sha256.Size]byte, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			entry := newHasher()
+			entry.value(iter.Key())
+			entry.value(iter.Value())
+			h.pins = append(h.pins, entry.pins...)
+			h.ok = h.ok && entry.ok
+
+			var sum [sha256.Size]byte
+			entry.Sum(sum[:0])
+			entries = append(entries, sum)
+		}
+		slices.SortFunc(entries, func(a, b [sha256.Size]byte) int { return bytes.Compare(a[:], b[:]) })
+		for _, sum := range entries {
+			h.Write(sum[:])
+		}
+	case reflect.Slice:
+		h.bool(v.IsNil())
+		fallthrough
+	case reflect.Array:
+		h.uint(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			h.value(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			h.value(v.Field(i))
+		}
+	case reflect.Bool:
+		h.bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.uint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.uint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		h.uint(math.Float64bits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		h.uint(math.Float64bits(real(v.Complex())))
+		h.uint(math.Float64bits(imag(v.Complex())))
+	case reflect.String:
+		h.string(v.String())
+	}
+}
@@ -0,0 +1,236 @@
+package vm_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+type cachedComponent struct {
+	Label   string   `syntax:",label"`
+	Targets []string `syntax:"targets,attr"`
+}
+
+func TestCache_Evaluate(t *testing.T) {
+	const src = `component "a" {
+	targets = discovery.a.targets
+}
+
+component "b" {
+	targets = ["static"]
+}
+
+component "c" {
+	targets = array.concat(discovery.b.targets, ["x"])
+}
+`
+	discovery := func(a, b []string) map[string]any {
+		return map[string]any{
+			"a": map[string]any{"targets": a},
+			"b": map[string]any{"targets": b},
+		}
+	}
+
+	var (
+		pc = parser.NewCache()
+		vc = vm.NewCache()
+	)
+	// evaluate returns the labels of the blocks which were evaluated.
+	evaluate := func(src string, discovery map[string]any) string {
+		t.Helper()
+		f, err := pc.ParseFile("config.alloy", []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scope := vm.NewScope(nil, map[string]any{"discovery": discovery})
+
+		var evaluated []string
+		for _, stmt := range f.Body {
+			var c cachedComponent
+			ok, err := vc.Evaluate(scope, stmt.(*ast.BlockStmt), &c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok {
+				evaluated = append(evaluated, c.Label)
+			}
+		}
+		return strings.Join(evaluated, ",")
+	}
+
+	tt := []struct {
+		name      string
+		src       string
+		discovery map[string]any
+		expect    string
+	}{
+		{"first evaluation", src, discovery([]string{"a1"}, []string{"b1"}), "a,b,c"},
+		{"unchanged", src, discovery([]string{"a1"}, []string{"b1"}), "c"},
+		{"unreferenced value changed", src, discovery([]string{"a1"}, []string{"b2"}), "c"},
+		{"referenced value changed", src, discovery([]string{"a2"}, []string{"b2"}), "a,c"},
+		{"block changed", strings.Replace(src, `"static"`, `"changed"`, 1), discovery([]string{"a2"}, []string{"b2"}), "b,c"},
+	}
+	for _, tc := range tt {
+		if actual := evaluate(tc.src, tc.discovery); actual != tc.expect {
+			t.Errorf("%s: expected %q to be evaluated, got %q", tc.name, tc.expect, actual)
+		}
+	}
+}
+
+func TestCache_EvaluateChangedInPlace(t *testing.T) {
+	f, err := parser.ParseFile("config.alloy", []byte("component \"a\" {\n\ttargets = discovery.targets\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := f.Body[0].(*ast.BlockStmt)
+
+	targets := []string{"a1"}
+	discovery := map[string]any{"targets": targets}
+	scope := vm.NewScope(nil, map[string]any{"discovery": discovery})
+
+	c := vm.NewCache()
+	var v cachedComponent
+	if _, err := c.Evaluate(scope, block, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	targets[0] = "a2"
+	ok, err := c.Evaluate(scope, block, &v)
+	if err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("expected a block referencing a slice changed in place to be evaluated again")
+	}
+
+	discovery["targets"] = []string{"a3"}
+	if ok, err := c.Evaluate(scope, block, &v); err != nil {
+		t.Fatal(err)
+	} else if !ok || v.Targets[0] != "a3" {
+		t.Fatalf("expected a block referencing a map changed in place to be evaluated again, got %v", v.Targets)
+	}
+}
+
+func TestCache_EvaluateCapsules(t *testing.T) {
+	f, err := parser.ParseFile("config.alloy", []byte("component \"a\" {\n\ttargets = []\n\treceiver = recv\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := f.Body[0].(*ast.BlockStmt)
+
+	type withReceiver struct {
+		Label    string   `syntax:",label"`
+		Targets  []string `syntax:"targets,attr"`
+		Receiver appender `syntax:"receiver,attr"`
+	}
+
+	c := vm.NewCache()
+	for i, recv := range []*memoryAppender{{}, {}} {
+		var v withReceiver
+		ok, err := c.Evaluate(vm.NewScope(nil, map[string]any{"recv": recv}), block, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("evaluation %d: expected a different receiver to be evaluated again", i)
+		}
+	}
+}
+
+func TestCache_Sweep(t *testing.T) {
+	f, err := parser.ParseFile("config.alloy", []byte("component \"a\" {\n\ttargets = []\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := f.Body[0].(*ast.BlockStmt)
+
+	c := vm.NewCache()
+	evaluate := func() bool {
+		var v cachedComponent
+		ok, err := c.Evaluate(vm.NewScope(nil, nil), block, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	evaluate()
+	c.Sweep()
+	if evaluate() {
+		t.Error("expected a block evaluated before Sweep to be kept")
+	}
+	c.Sweep()
+	c.Sweep()
+	if !evaluate() {
+		t.Error("expected a block not evaluated between sweeps to be dropped")
+	}
+}
+
+// BenchmarkCache_Evaluate_10kBlocks reloads a file with 10k blocks where
+// one block changes between versions, evaluating it incrementally.
+func BenchmarkCache_Evaluate_10kBlocks(b *testing.B) {
+	benchmarkEvaluate(b, true)
+}
+
+// BenchmarkEvaluate_10kBlocks reloads the same files as
+// BenchmarkCache_Evaluate_10kBlocks, evaluating every block.
+func BenchmarkEvaluate_10kBlocks(b *testing.B) {
+	benchmarkEvaluate(b, false)
+}
+
+func benchmarkEvaluate(b *testing.B, cached bool) {
+	const n = 10000
+
+	versions := make([][]byte, 2)
+	for v := range versions {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			target := fmt.Sprintf("tenant-%d:9090", i)
+			if i == n/2 {
+				target = fmt.Sprintf("changed-%d:9090", v)
+			}
+			fmt.Fprintf(&sb, "component \"tenant_%d\" {\n\ttargets = [%q, discovery.shared]\n}\n\n", i, target)
+		}
+		versions[v] = []byte(sb.String())
+	}
+	scope := vm.NewScope(nil, map[string]any{
+		"discovery": map[string]any{"shared": "shared:9090"},
+	})
+
+	var (
+		pc     = parser.NewCache()
+		vc     = vm.NewCache()
+		values = make(map[*ast.BlockStmt]*cachedComponent, n)
+	)
+	i := 0
+	for b.Loop() {
+		i++
+		f, err := pc.ParseFile("config.alloy", versions[i%2])
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, stmt := range f.Body {
+			block := stmt.(*ast.BlockStmt)
+			if !cached {
+				var v cachedComponent
+				if err := vm.New(block).Evaluate(scope, &v); err != nil {
+					b.Fatal(err)
+				}
+				continue
+			}
+
+			v := values[block]
+			if v == nil {
+				v = new(cachedComponent)
+				values[block] = v
+			}
+			if _, err := vc.Evaluate(scope, block, v); err != nil {
+				b.Fatal(err)
+			}
+		}
+		vc.Sweep()
+	}
+}
@@ -0,0 +1,235 @@
+package vm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/alloytypes"
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+type appender interface {
+	Append(string)
+}
+
+type memoryAppender struct{ lines []string }
+
+func (a *memoryAppender) Append(s string) { a.lines = append(a.lines, s) }
+
+type exports struct {
+	Receiver appender `syntax:"receiver,attr"`
+	Queue    chan int `syntax:"queue,attr"`
+}
+
+func evaluate(t *testing.T, scope *vm.Scope, input string, v any) error {
+	t.Helper()
+	expr, err := parser.ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return vm.New(expr).Evaluate(scope, v)
+}
+
+func TestCapsules(t *testing.T) {
+	recv := &memoryAppender{}
+	queue := make(chan int)
+	scope := vm.NewScope(nil, map[string]any{
+		"component": exports{Receiver: recv, Queue: queue},
+		"secret":    alloytypes.Secret("hunter2"),
+	})
+
+	t.Run("capsules keep their identity", func(t *testing.T) {
+		var receivers []appender
+		if err := evaluate(t, scope, `[component.receiver, component.receiver]`, &receivers); err != nil {
+			t.Fatal(err)
+		}
+		receivers[0].Append("hello")
+		if len(recv.lines) != 1 || receivers[1] != appender(recv) {
+			t.Errorf("expected capsules to wrap the original appender")
+		}
+
+		var ch chan int
+		if err := evaluate(t, scope, `component.queue`, &ch); err != nil {
+			t.Fatal(err)
+		}
+		if ch != queue {
+			t.Errorf("expected the original channel")
+		}
+	})
+
+	t.Run("capsules are not data", func(t *testing.T) {
+		tt := map[string]string{
+			`component.receiver + 1`: `1:1: expected number, got capsule`,
+			`component.receiver.foo`: `1:1: expected object, got capsule`,
+			`"prefix" + secret`:      `1:12: expected string, got capsule`,
+		}
+		for input, expect := range tt {
+			var out any
+			err := evaluate(t, scope, input, &out)
+			if err == nil || err.Error() != expect {
+				t.Errorf("%s: expected error %q, got %v", input, expect, err)
+			}
+		}
+
+		var s string
+		if err := evaluate(t, scope, `secret`, &s); err == nil {
+			t.Errorf("expected decoding a secret into a string to fail")
+		}
+		var ch chan int
+		err := evaluate(t, scope, `component.receiver`, &ch)
+		if err == nil || !strings.Contains(err.Error(), `expected capsule("chan int")`) {
+			t.Errorf("expected capsule type mismatch, got %v", err)
+		}
+	})
+
+	t.Run("strings convert to secrets", func(t *testing.T) {
+		var secret alloytypes.Secret
+		if err := evaluate(t, scope, `"password"`, &secret); err != nil {
+			t.Fatal(err)
+		}
+		if secret.Reveal() != "password" {
+			t.Errorf("unexpected secret %q", secret.Reveal())
+		}
+	})
+}
+
+func TestFunctionValues(t *testing.T) {
+	scope := vm.NewScope(nil, map[string]any{
+		"add": func(a, b int) int { return a + b },
+		"apply": func(f func(int) int, values []int) []int {
+			out := make([]int, len(values))
+			for i, v := range values {
+				out[i] = f(v)
+			}
+			return out
+		},
+		"double": func(v int) int { return v * 2 },
+		"funcs":  map[string]any{"upper": strings.ToUpper},
+		"noop":   func() {},
+		"read": func(name string) (string, error) {
+			return "", errors.New("file does not exist")
+		},
+	})
+
+	t.Run("calls", func(t *testing.T) {
+		var out []int
+		if err := evaluate(t, scope, `[add(1, 2), apply(double, [1, 2, 3])[2]]`, &out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 2 || out[0] != 3 || out[1] != 6 {
+			t.Errorf("unexpected result %v", out)
+		}
+	})
+
+	t.Run("type-checked arguments", func(t *testing.T) {
+		var out any
+		err := evaluate(t, scope, `add(1, "2")`, &out)
+		if err == nil || err.Error() != `1:8: expected number, got string` {
+			t.Errorf("unexpected error %v", err)
+		}
+		err = evaluate(t, scope, `add(1)`, &out)
+		if err == nil || err.Error() != `1:1: expected 2 arguments, got 1` {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("decoding into Go functions", func(t *testing.T) {
+		var upper func(string) string
+		if err := evaluate(t, scope, `funcs.upper`, &upper); err != nil {
+			t.Fatal(err)
+		}
+		if upper("abc") != "ABC" {
+			t.Errorf("unexpected result %q", upper("abc"))
+		}
+
+		// Signatures which differ from the original are adapted.
+		var double func(int64) (float64, error)
+		if err := evaluate(t, scope, `double`, &double); err != nil {
+			t.Fatal(err)
+		}
+		if res, err := double(21); err != nil || res != 42 {
+			t.Errorf("unexpected result %v, %v", res, err)
+		}
+
+		var notFunc func() string
+		if err := evaluate(t, scope, `"text"`, &notFunc); err == nil {
+			t.Errorf("expected decoding a string into a function to fail")
+		}
+	})
+
+	t.Run("unsupported signatures", func(t *testing.T) {
+		var out any
+		err := evaluate(t, scope, `noop()`, &out)
+		if err == nil || err.Error() != `1:1: cannot call function of Go type func(): it must return a value, optionally followed by an error` {
+			t.Errorf("unexpected error %v", err)
+		}
+
+		var noResult func(string)
+		err = evaluate(t, scope, `funcs.upper`, &noResult)
+		if err == nil || err.Error() != `1:1: cannot decode function into Go type func(string): it must return a value, optionally followed by an error` {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("failing calls", func(t *testing.T) {
+		// A function which can fail can't be decoded into a Go function
+		// without an error result.
+		var read func(string) string
+		err := evaluate(t, scope, `read`, &read)
+		if err == nil || err.Error() != `1:1: cannot decode function into Go type func(string) string: the function can fail, so it must also return an error` {
+			t.Errorf("unexpected error %v", err)
+		}
+
+		var readErr func(string) (string, error)
+		if err := evaluate(t, scope, `read`, &readErr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readErr("missing"); err == nil || err.Error() != "file does not exist" {
+			t.Errorf("unexpected error %v", err)
+		}
+
+		// Failures of function values passed to functions are returned
+		// from the call.
+		var out any
+		err = evaluate(t, scope, `apply(funcs.upper, [1])`, &out)
+		if err == nil || err.Error() != `1:1: expected string, got number` {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestUnsupportedGoValues(t *testing.T) {
+	ids := map[int]string{1: "a"}
+	scope := vm.NewScope(nil, map[string]any{
+		"ids":     ids,
+		"complex": complex(1, 2),
+		"nested":  map[string]any{"ids": ids},
+	})
+
+	// Go values with no equivalent in the syntax are passed through as
+	// capsules.
+	var out any
+	if err := evaluate(t, scope, `nested.ids`, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := out.(map[int]string); !ok || got[1] != "a" {
+		t.Errorf("unexpected result %#v", out)
+	}
+	if err := evaluate(t, scope, `complex`, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != complex(1, 2) {
+		t.Errorf("unexpected result %#v", out)
+	}
+
+	err := evaluate(t, scope, `ids[1]`, &out)
+	if err == nil || err.Error() != `1:1: expected array or object, got capsule` {
+		t.Errorf("unexpected error %v", err)
+	}
+	var n int
+	if err := evaluate(t, scope, `complex`, &n); err == nil {
+		t.Errorf("expected decoding a complex number into an int to fail")
+	}
+}
@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jharvey10/test-repo/syntax/alloytypes"
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/internal/value"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// Error is an evaluation error. It points at the range of source which
+// caused the error.
+type Error struct {
+	StartPos token.Position
+	EndPos   token.Position
+	Message  string
+
+	// Hint optionally suggests how to fix the error.
+	Hint string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.StartPos, e.Message)
+}
+
+// Diagnostics converts e into diagnostics. It implements diag.Diagnoser.
+func (e *Error) Diagnostics() diag.Diagnostics {
+	return diag.Diagnostics{{
+		Severity: diag.SeverityLevelError,
+		StartPos: e.StartPos,
+		EndPos:   e.EndPos,
+		Message:  e.Message,
+		Hint:     e.Hint,
+	}}
+}
+
+// newError creates an Error spanning node.
+func newError(node ast.Node, msg string) *Error {
+	return &Error{
+		StartPos: ast.StartPos(node).Position(),
+		EndPos:   ast.EndPos(node).Position(),
+		Message:  msg,
+	}
+}
+
+// makeError converts a value-level error into an *Error. Element and field
+// errors are traced back through array and object literals, so the error
+// points at the innermost expression responsible for it. When the value
+// did not come from a literal, the path to the failing element is included
+// in the message instead.
+func makeError(node ast.Node, assoc map[value.Value]ast.Node, err error) error {
+	var vmErr *Error
+	if errors.As(err, &vmErr) {
+		return vmErr
+	}
+
+	var path strings.Builder
+
+	for {
+		switch e := err.(type) {
+		case value.ElementError:
+			if arr, ok := assoc[e.Value].(*ast.ArrayExpr); ok && path.Len() == 0 && e.Index < len(arr.Elements) {
+				node = arr.Elements[e.Index]
+			} else {
+				fmt.Fprintf(&path, "[%d]", e.Index)
+			}
+			err = e.Inner
+			continue
+
+		case value.FieldError:
+			if obj, ok := assoc[e.Value].(*ast.ObjectExpr); ok && path.Len() == 0 {
+				if field := findField(obj, e.Field); field != nil {
+					node = field.Value
+					err = e.Inner
+					continue
+				}
+			}
+			fmt.Fprintf(&path, ".%s", e.Field)
+			err = e.Inner
+			continue
+		}
+		break
+	}
+
+	msg := err.Error()
+	if path.Len() > 0 {
+		msg = strings.TrimPrefix(path.String(), ".") + ": " + msg
+	}
+	vmErr = newError(node, msg)
+	if te, ok := err.(value.TypeError); ok && te.Expected == value.TypeString {
+		if _, isSecret := te.Value.Interface().(alloytypes.Secret); isSecret {
+			vmErr.Hint = "secrets cannot be used as strings; use nonsensitive(secret) to convert one explicitly"
+		}
+	}
+	return vmErr
+}
+
+func findField(obj *ast.ObjectExpr, name string) *ast.ObjectField {
+	for _, f := range obj.Fields {
+		if f.Name.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// makeBinopError converts an error from a binary operation into an *Error.
+// Errors caused by a single operand point at that operand.
+func makeBinopError(expr *ast.BinaryExpr, assoc map[value.Value]ast.Node, lhs, rhs value.Value, err error) error {
+	var culprit value.Value
+	if te, ok := err.(value.TypeError); ok {
+		culprit = te.Value
+	} else if ve, ok := err.(value.Error); ok {
+		culprit = ve.Value
+	}
+
+	switch {
+	case culprit == value.Null:
+		return makeError(expr, assoc, err)
+	case culprit == lhs:
+		return makeError(expr.Left, assoc, err)
+	case culprit == rhs:
+		return makeError(expr.Right, assoc, err)
+	}
+	return makeError(expr, assoc, err)
+}
@@ -0,0 +1,302 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/jharvey10/test-repo/syntax/internal/value"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// evalBinop evaluates a non-logical binary operation. || and && are handled
+// by the Evaluator directly so they can short-circuit.
+func evalBinop(lhs value.Value, op token.Token, rhs value.Value) (value.Value, error) {
+	switch op {
+	case token.EQ, token.NEQ:
+		eq, err := valuesEqual(lhs, rhs)
+		if err != nil {
+			return value.Null, err
+		}
+		return value.Bool(eq == (op == token.EQ)), nil
+
+	case token.LT, token.LTE, token.GT, token.GTE:
+		cmp, err := compareValues(lhs, rhs)
+		if err != nil {
+			return value.Null, err
+		}
+		switch op {
+		case token.LT:
+			return value.Bool(cmp < 0), nil
+		case token.LTE:
+			return value.Bool(cmp <= 0), nil
+		case token.GT:
+			return value.Bool(cmp > 0), nil
+		default:
+			return value.Bool(cmp >= 0), nil
+		}
+
+	case token.ADD:
+		// + concatenates strings in addition to adding numbers.
+		if lhs.Type() == value.TypeString {
+			if rhs.Type() != value.TypeString {
+				return value.Null, value.TypeError{Value: rhs, Expected: value.TypeString}
+			}
+			return value.String(lhs.Text() + rhs.Text()), nil
+		}
+		if err := checkNumbers(lhs, rhs); err != nil {
+			return value.Null, err
+		}
+		return addNumbers(lhs.Number(), rhs.Number()), nil
+
+	case token.SUB, token.MUL, token.DIV, token.MOD, token.POW:
+		if err := checkNumbers(lhs, rhs); err != nil {
+			return value.Null, err
+		}
+		return evalArithmetic(op, lhs, rhs)
+	}
+
+	panic(fmt.Sprintf("syntax/vm: unexpected binary operator %s", op))
+}
+
+func checkNumbers(lhs, rhs value.Value) error {
+	if lhs.Type() != value.TypeNumber {
+		return value.TypeError{Value: lhs, Expected: value.TypeNumber}
+	}
+	if rhs.Type() != value.TypeNumber {
+		return value.TypeError{Value: rhs, Expected: value.TypeNumber}
+	}
+	return nil
+}
+
+// valuesEqual reports whether lhs and rhs are deeply equal. Values of
+// different types may only be compared against null.
+func valuesEqual(lhs, rhs value.Value) (bool, error) {
+	if lhs.Type() == value.TypeNull || rhs.Type() == value.TypeNull {
+		return lhs.Type() == rhs.Type(), nil
+	}
+	if lhs.Type() != rhs.Type() {
+		return false, value.TypeError{Value: rhs, Expected: lhs.Type()}
+	}
+
+	switch lhs.Type() {
+	case value.TypeNumber:
+		return compareNumbers(lhs.Number(), rhs.Number()) == 0, nil
+	case value.TypeString:
+		return lhs.Text() == rhs.Text(), nil
+	case value.TypeBool:
+		return lhs.Bool() == rhs.Bool(), nil
+
+	case value.TypeArray:
+		if lhs.Len() != rhs.Len() {
+			return false, nil
+		}
+		for i := 0; i < lhs.Len(); i++ {
+			eq, err := valuesEqual(lhs.Index(i), rhs.Index(i))
+			if err != nil {
+				return false, value.ElementError{Value: rhs, Index: i, Inner: err}
+			}
+			if !eq {
+				return false, nil
+			}
+		}
+		return true, nil
+
+	case value.TypeObject:
+		if lhs.Len() != rhs.Len() {
+			return false, nil
+		}
+		for _, key := range lhs.Keys() {
+			lval, _ := lhs.Key(key)
+			rval, ok := rhs.Key(key)
+			if !ok {
+				return false, nil
+			}
+			eq, err := valuesEqual(lval, rval)
+			if err != nil {
+				return false, value.FieldError{Value: rhs, Field: key, Inner: err}
+			}
+			if !eq {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	return false, value.Errorf(lhs, "values of type %s cannot be compared", lhs.Type())
+}
+
+// compareValues orders two numbers or two strings, returning -1, 0 or 1.
+func compareValues(lhs, rhs value.Value) (int, error) {
+	switch lhs.Type() {
+	case value.TypeNumber:
+		if rhs.Type() != value.TypeNumber {
+			return 0, value.TypeError{Value: rhs, Expected: value.TypeNumber}
+		}
+		return compareNumbers(lhs.Number(), rhs.Number()), nil
+
+	case value.TypeString:
+		if rhs.Type() != value.TypeString {
+			return 0, value.TypeError{Value: rhs, Expected: value.TypeString}
+		}
+		switch l, r := lhs.Text(), rhs.Text(); {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+
+	default:
+		return 0, value.Errorf(lhs, "cannot order values of type %s", lhs.Type())
+	}
+}
+
+// compareNumbers orders two numbers without losing precision for large
+// integers.
+func compareNumbers(a, b value.Number) int {
+	if a.Kind() != value.NumberKindFloat && b.Kind() != value.NumberKindFloat {
+		ai, aok := a.Int()
+		bi, bok := b.Int()
+		switch {
+		case aok && bok:
+			return cmp(ai, bi)
+		case !aok && !bok:
+			// Both values are above math.MaxInt64.
+			au, _ := a.Uint()
+			bu, _ := b.Uint()
+			return cmp(au, bu)
+		case !aok:
+			return 1
+		default:
+			return -1
+		}
+	}
+	return cmp(a.Float(), b.Float())
+}
+
+func cmp[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// addNumbers adds two numbers. Integer results which overflow an int64
+// are computed as floats instead.
+func addNumbers(a, b value.Number) value.Value {
+	if a.IsIntegral() && b.IsIntegral() {
+		ai, _ := a.Int()
+		bi, _ := b.Int()
+		sum := ai + bi
+		if (sum > ai) == (bi > 0) {
+			return value.Int(sum)
+		}
+	}
+	return value.Float(a.Float() + b.Float())
+}
+
+func evalArithmetic(op token.Token, lhs, rhs value.Value) (value.Value, error) {
+	a, b := lhs.Number(), rhs.Number()
+	integral := a.IsIntegral() && b.IsIntegral()
+	ai, _ := a.Int()
+	bi, _ := b.Int()
+
+	switch op {
+	case token.SUB:
+		if integral {
+			diff := ai - bi
+			if (diff < ai) == (bi > 0) {
+				return value.Int(diff), nil
+			}
+		}
+		return value.Float(a.Float() - b.Float()), nil
+
+	case token.MUL:
+		if integral {
+			if res, ok := mulInt(ai, bi); ok {
+				return value.Int(res), nil
+			}
+		}
+		return value.Float(a.Float() * b.Float()), nil
+
+	case token.DIV:
+		if b.Float() == 0 {
+			return value.Null, value.Errorf(rhs, "division by zero")
+		}
+		// Exact integer division stays an integer; anything else produces a
+		// float.
+		if integral && ai%bi == 0 && !(ai == math.MinInt64 && bi == -1) {
+			return value.Int(ai / bi), nil
+		}
+		return value.Float(a.Float() / b.Float()), nil
+
+	case token.MOD:
+		if b.Float() == 0 {
+			return value.Null, value.Errorf(rhs, "division by zero")
+		}
+		if integral {
+			if bi == -1 {
+				return value.Int(0), nil
+			}
+			return value.Int(ai % bi), nil
+		}
+		return value.Float(math.Mod(a.Float(), b.Float())), nil
+
+	case token.POW:
+		if integral && bi >= 0 {
+			if res, ok := powInt(ai, bi); ok {
+				return value.Int(res), nil
+			}
+		}
+		return value.Float(math.Pow(a.Float(), b.Float())), nil
+	}
+
+	panic(fmt.Sprintf("syntax/vm: unexpected arithmetic operator %s", op))
+}
+
+// mulInt multiplies a and b, reporting false if the result overflows.
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	res := a * b
+	if res/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return res, true
+}
+
+// powInt computes base^exp for a non-negative exp, reporting false if the
+// result overflows.
+func powInt(base, exp int64) (int64, bool) {
+	res := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			var ok bool
+			if res, ok = mulInt(res, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if bits.Len64(uint64(abs(base))) > 32 {
+				return 0, false
+			}
+			base *= base
+		}
+	}
+	return res, true
+}
+
+func abs(i int64) int64 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
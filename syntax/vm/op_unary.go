@@ -0,0 +1,46 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jharvey10/test-repo/syntax/internal/value"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// evalUnaryOp evaluates a unary operation.
+func evalUnaryOp(op token.Token, val value.Value) (value.Value, error) {
+	switch op {
+	case token.NOT:
+		if val.Type() != value.TypeBool {
+			return value.Null, value.TypeError{Value: val, Expected: value.TypeBool}
+		}
+		return value.Bool(!val.Bool()), nil
+
+	case token.SUB:
+		if val.Type() != value.TypeNumber {
+			return value.Null, value.TypeError{Value: val, Expected: value.TypeNumber}
+		}
+		num := val.Number()
+		switch num.Kind() {
+		case value.NumberKindFloat:
+			return value.Float(-num.Float()), nil
+		default:
+			if i, ok := num.Int(); ok && i != math.MinInt64 {
+				return value.Int(-i), nil
+			}
+			// The negation of math.MinInt64 only fits in a uint64, and
+			// 9223372036854775808 is the only uint64 above math.MaxInt64
+			// whose negation fits in an int64.
+			if num.Kind() == value.NumberKindInt {
+				return value.Uint(1 << 63), nil
+			}
+			if u, ok := num.Uint(); ok && u == 1<<63 {
+				return value.Int(math.MinInt64), nil
+			}
+			return value.Float(-num.Float()), nil
+		}
+	}
+
+	panic(fmt.Sprintf("syntax/vm: unexpected unary operator %s", op))
+}
@@ -0,0 +1,52 @@
+package vm
+
+import "github.com/jharvey10/test-repo/syntax/internal/stdlib"
+
+// Scope is a set of named values available to expressions. Identifiers
+// which are not found in a Scope are looked up in its Parent.
+type Scope struct {
+	// Parent optionally points to a parent Scope containing more
+	// variables. Variables defined in children scopes take precedence over
+	// variables of the same name found in parent scopes.
+	Parent *Scope
+
+	// Variables holds the list of available variable names that can be
+	// used when evaluating a node.
+	//
+	// Values in the Variables map should be considered immutable after
+	// passed to Evaluate; maps and slices will be copied by reference for
+	// performance optimizations.
+	Variables map[string]any
+}
+
+// NewScope creates a new Scope with the given parent and variables.
+func NewScope(parent *Scope, variables map[string]any) *Scope {
+	return &Scope{Parent: parent, Variables: variables}
+}
+
+// Lookup looks up a named identifier from the scope, all of the scope's
+// parents.
+func (s *Scope) Lookup(name string) (any, bool) {
+	for s != nil {
+		if val, ok := s.Variables[name]; ok {
+			return val, true
+		}
+		s = s.Parent
+	}
+	return nil, false
+}
+
+// names returns every identifier visible from the scope, including the
+// standard library.
+func (s *Scope) names() []string {
+	var names []string
+	for ; s != nil; s = s.Parent {
+		for name := range s.Variables {
+			names = append(names, name)
+		}
+	}
+	for name := range stdlib.Identifiers {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,307 @@
+// Package vm provides an expression evaluator for the configuration
+// language.
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/jharvey10/test-repo/syntax/ast"
+	"github.com/jharvey10/test-repo/syntax/diag"
+	"github.com/jharvey10/test-repo/syntax/internal/stdlib"
+	"github.com/jharvey10/test-repo/syntax/internal/value"
+	"github.com/jharvey10/test-repo/syntax/token"
+)
+
+// Evaluator evaluates AST nodes into Go values. Each Evaluator is bound to
+// a single AST node. To evaluate the node, call Evaluate.
+type Evaluator struct {
+	// node for the AST.
+	//
+	// Each Evaluator is bound to a single AST node. Caches and state are
+	// tied to the node and must be reset whenever it changes.
+	node ast.Node
+}
+
+// New creates a new Evaluator for the given AST node. The given node must
+// be an ast.Expr, an *ast.BlockStmt or an ast.Body.
+func New(node ast.Node) *Evaluator {
+	return &Evaluator{node: node}
+}
+
+// Evaluate evaluates the Evaluator's node into a Go value and decodes that
+// value into v. v must be a non-nil pointer.
+//
+// Blocks and bodies must be decoded into a struct whose fields use syntax
+// struct tags; see the syntaxtags package for the tag format.
+//
+// Identifiers in expressions are resolved against scope and its parents.
+// The returned error, if any, is an *Error which points at the node that
+// failed to evaluate.
+func (vm *Evaluator) Evaluate(scope *Scope, v any) error {
+	// Track a map that allows us to associate values with ast.Nodes so we
+	// can return decorated error messages.
+	assoc := make(map[value.Value]ast.Node)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		panic(fmt.Sprintf("syntax/vm: Evaluate called with non-pointer %T", v))
+	}
+
+	switch node := vm.node.(type) {
+	case *ast.BlockStmt, ast.Body:
+		return vm.evaluateBlockOrBody(scope, assoc, node, rv)
+
+	case ast.Expr:
+		val, err := vm.evaluateExpr(scope, assoc, node)
+		if err != nil {
+			return err
+		}
+		if err := value.Decode(val, v); err != nil {
+			return makeError(node, assoc, err)
+		}
+		return nil
+
+	default:
+		panic(fmt.Sprintf("syntax/vm: cannot evaluate %T", vm.node))
+	}
+}
+
+func (vm *Evaluator) evaluateExpr(scope *Scope, assoc map[value.Value]ast.Node, expr ast.Expr) (v value.Value, err error) {
+	defer func() {
+		if v.Type() == value.TypeArray || v.Type() == value.TypeObject {
+			if _, tracked := assoc[v]; !tracked {
+				assoc[v] = expr
+			}
+		}
+	}()
+
+	switch expr := expr.(type) {
+	case *ast.LiteralExpr:
+		return valueFromLiteral(expr)
+
+	case *ast.BinaryExpr:
+		// || and && short-circuit, so the right-hand side is only evaluated
+		// when it decides the result.
+		if expr.Kind == token.OR || expr.Kind == token.AND {
+			return vm.evaluateLogical(scope, assoc, expr)
+		}
+
+		lhs, err := vm.evaluateExpr(scope, assoc, expr.Left)
+		if err != nil {
+			return value.Null, err
+		}
+		rhs, err := vm.evaluateExpr(scope, assoc, expr.Right)
+		if err != nil {
+			return value.Null, err
+		}
+		res, err := evalBinop(lhs, expr.Kind, rhs)
+		if err != nil {
+			return value.Null, makeBinopError(expr, assoc, lhs, rhs, err)
+		}
+		return res, nil
+
+	case *ast.ArrayExpr:
+		vals := make([]value.Value, len(expr.Elements))
+		for i, element := range expr.Elements {
+			val, err := vm.evaluateExpr(scope, assoc, element)
+			if err != nil {
+				return value.Null, err
+			}
+			vals[i] = val
+		}
+		return value.Array(vals...), nil
+
+	case *ast.ObjectExpr:
+		fields := make(map[string]value.Value, len(expr.Fields))
+		for _, field := range expr.Fields {
+			if _, exists := fields[field.Name.Name]; exists {
+				return value.Null, newError(field.Name, fmt.Sprintf("field %q is defined more than once", field.Name.Name))
+			}
+			val, err := vm.evaluateExpr(scope, assoc, field.Value)
+			if err != nil {
+				return value.Null, err
+			}
+			fields[field.Name.Name] = val
+		}
+		return value.Object(fields), nil
+
+	case *ast.IdentifierExpr:
+		val, found := scope.Lookup(expr.Ident.Name)
+		if !found {
+			// Fall back to the standard library, so its identifiers are
+			// available in every scope unless shadowed.
+			val, found = stdlib.Identifiers[expr.Ident.Name]
+		}
+		if !found {
+			err := newError(expr, fmt.Sprintf("identifier %q does not exist", expr.Ident.Name))
+			err.Hint = diag.Suggest(expr.Ident.Name, scope.names())
+			return value.Null, err
+		}
+		return value.Encode(val), nil
+
+	case *ast.AccessExpr:
+		val, err := vm.evaluateExpr(scope, assoc, expr.Value)
+		if err != nil {
+			return value.Null, err
+		}
+		if val.Type() != value.TypeObject {
+			return value.Null, makeError(expr.Value, assoc, value.TypeError{Value: val, Expected: value.TypeObject})
+		}
+		field, ok := val.Key(expr.Name.Name)
+		if !ok {
+			return value.Null, newError(expr.Name, fmt.Sprintf("field %q does not exist", expr.Name.Name))
+		}
+		return field, nil
+
+	case *ast.IndexExpr:
+		val, err := vm.evaluateExpr(scope, assoc, expr.Value)
+		if err != nil {
+			return value.Null, err
+		}
+		idx, err := vm.evaluateExpr(scope, assoc, expr.Index)
+		if err != nil {
+			return value.Null, err
+		}
+		return evalIndex(expr, assoc, val, idx)
+
+	case *ast.ParenExpr:
+		return vm.evaluateExpr(scope, assoc, expr.Inner)
+
+	case *ast.UnaryExpr:
+		val, err := vm.evaluateExpr(scope, assoc, expr.Value)
+		if err != nil {
+			return value.Null, err
+		}
+		res, err := evalUnaryOp(expr.Kind, val)
+		if err != nil {
+			return value.Null, makeError(expr.Value, assoc, err)
+		}
+		return res, nil
+
+	case *ast.CallExpr:
+		fn, err := vm.evaluateExpr(scope, assoc, expr.Value)
+		if err != nil {
+			return value.Null, err
+		}
+		if fn.Type() != value.TypeFunction {
+			return value.Null, makeError(expr.Value, assoc, value.TypeError{Value: fn, Expected: value.TypeFunction})
+		}
+
+		args := make([]value.Value, len(expr.Args))
+		for i, arg := range expr.Args {
+			val, err := vm.evaluateExpr(scope, assoc, arg)
+			if err != nil {
+				return value.Null, err
+			}
+			args[i] = val
+		}
+
+		res, err := value.Call(fn, args)
+		if err != nil {
+			var argErr value.ArgError
+			if errors.As(err, &argErr) {
+				return value.Null, makeError(expr.Args[argErr.Index], assoc, argErr.Inner)
+			}
+			return value.Null, makeError(expr, assoc, err)
+		}
+		return res, nil
+
+	default:
+		panic(fmt.Sprintf("syntax/vm: unexpected ast.Expr type %T", expr))
+	}
+}
+
+func (vm *Evaluator) evaluateLogical(scope *Scope, assoc map[value.Value]ast.Node, expr *ast.BinaryExpr) (value.Value, error) {
+	lhs, err := vm.evaluateExpr(scope, assoc, expr.Left)
+	if err != nil {
+		return value.Null, err
+	}
+	if lhs.Type() != value.TypeBool {
+		return value.Null, makeError(expr.Left, assoc, value.TypeError{Value: lhs, Expected: value.TypeBool})
+	}
+	if expr.Kind == token.OR && lhs.Bool() || expr.Kind == token.AND && !lhs.Bool() {
+		return lhs, nil
+	}
+
+	rhs, err := vm.evaluateExpr(scope, assoc, expr.Right)
+	if err != nil {
+		return value.Null, err
+	}
+	if rhs.Type() != value.TypeBool {
+		return value.Null, makeError(expr.Right, assoc, value.TypeError{Value: rhs, Expected: value.TypeBool})
+	}
+	return rhs, nil
+}
+
+func evalIndex(expr *ast.IndexExpr, assoc map[value.Value]ast.Node, val, idx value.Value) (value.Value, error) {
+	switch val.Type() {
+	case value.TypeArray:
+		if idx.Type() != value.TypeNumber {
+			return value.Null, makeError(expr.Index, assoc, value.TypeError{Value: idx, Expected: value.TypeNumber})
+		}
+		i, ok := idx.Number().Int()
+		if !ok || idx.Number().Kind() == value.NumberKindFloat {
+			return value.Null, newError(expr.Index, fmt.Sprintf("array index must be a whole number, got %s", idx.Describe()))
+		}
+		if i < 0 || i >= int64(val.Len()) {
+			return value.Null, newError(expr.Index, fmt.Sprintf("index %d is out of range for array of length %d", i, val.Len()))
+		}
+		return val.Index(int(i)), nil
+
+	case value.TypeObject:
+		if idx.Type() != value.TypeString {
+			return value.Null, makeError(expr.Index, assoc, value.TypeError{Value: idx, Expected: value.TypeString})
+		}
+		// Indexing a missing key returns null, unlike field access, so
+		// objects can be probed for optional keys.
+		field, _ := val.Key(idx.Text())
+		return field, nil
+
+	default:
+		return value.Null, newError(expr.Value, fmt.Sprintf("expected array or object, got %s", val.Type()))
+	}
+}
+
+func valueFromLiteral(lit *ast.LiteralExpr) (value.Value, error) {
+	switch lit.Kind {
+	case token.NUMBER:
+		v, err := value.ParseNumber(lit.Value)
+		if err != nil {
+			return value.Null, newError(lit, fmt.Sprintf("invalid number %s: out of range", lit.Value))
+		}
+		return v, nil
+
+	case token.FLOAT:
+		v, err := value.ParseFloat(lit.Value)
+		if err != nil {
+			return value.Null, newError(lit, fmt.Sprintf("invalid number %s: out of range", lit.Value))
+		}
+		return v, nil
+
+	case token.STRING:
+		v, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return value.Null, newError(lit, fmt.Sprintf("invalid string %s: %s", lit.Value, err))
+		}
+		return value.String(v), nil
+
+	case token.BOOL:
+		switch lit.Value {
+		case "true":
+			return value.Bool(true), nil
+		case "false":
+			return value.Bool(false), nil
+		default:
+			return value.Null, newError(lit, fmt.Sprintf("invalid boolean literal %q", lit.Value))
+		}
+
+	case token.NULL:
+		return value.Null, nil
+
+	default:
+		panic(fmt.Sprintf("%v is not a valid token", lit.Kind))
+	}
+}
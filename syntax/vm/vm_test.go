@@ -0,0 +1,119 @@
+package vm_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jharvey10/test-repo/syntax/parser"
+	"github.com/jharvey10/test-repo/syntax/vm"
+)
+
+func TestEvaluate(t *testing.T) {
+	scope := vm.NewScope(nil, map[string]any{
+		"foobar": 42,
+		"obj":    map[string]any{"list": []string{"a", "b"}},
+	})
+
+	tt := []struct {
+		input  string
+		expect any
+	}{
+		// Literals.
+		{`15`, 15},
+		{`1.5`, 1.5},
+		{`"hello\n"`, "hello\n"},
+		{`true`, true},
+		{`null`, nil},
+		{`[1, 2, 3]`, []any{1, 2, 3}},
+		{`{ a = 1, "b" = "two" }`, map[string]any{"a": 1, "b": "two"}},
+
+		// Arithmetic.
+		{`1 + 2 * 3`, 7},
+		{`(1 + 2) * 3`, 9},
+		{`10 / 2`, 5},
+		{`10 / 4`, 2.5},
+		{`10 % 4`, 2},
+		{`2 ^ 10`, 1024},
+		{`-5 + 2`, -3},
+		{`9223372036854775807 + 1`, 9223372036854775808.0},
+		{`-9223372036854775808`, -9223372036854775808},
+		{`-(-9223372036854775808)`, uint64(9223372036854775808)},
+		{`-18446744073709551615`, -18446744073709551615.0},
+		{`"foo" + "bar"`, "foobar"},
+
+		// Comparison and logic.
+		{`1 == 1.0`, true},
+		{`"a" < "b"`, true},
+		{`1 != null`, true},
+		{`[1, 2] == [1, 2]`, true},
+		{`{a = 1} == {a = 2}`, false},
+		{`!true || 3 >= 3`, true},
+		{`false && nope`, false},
+		{`true || nope`, true},
+
+		// Scope access.
+		{`foobar`, 42},
+		{`obj.list[1]`, "b"},
+		{`obj["missing"]`, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			expr, err := parser.ParseExpression(tc.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			var actual any
+			if err := vm.New(expr).Evaluate(scope, &actual); err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if !reflect.DeepEqual(tc.expect, actual) {
+				t.Errorf("expected %#v, got %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestEvaluate_Errors(t *testing.T) {
+	scope := vm.NewScope(nil, map[string]any{
+		"list": []any{1, "two"},
+	})
+
+	tt := []struct {
+		input  string
+		into   any
+		expect string
+	}{
+		{`1 + "a"`, new(any), `1:5: expected number, got string`},
+		{`"a" + 1`, new(any), `1:7: expected string, got number`},
+		{`1 == "1"`, new(any), `1:6: expected number, got string`},
+		{`!1`, new(any), `1:2: expected bool, got number`},
+		{`1 / 0`, new(any), `1:5: division by zero`},
+		{`missing`, new(any), `1:1: identifier "missing" does not exist`},
+		{`{a = 1}.b`, new(any), `1:9: field "b" does not exist`},
+		{`[1, 2][5]`, new(any), `1:8: index 5 is out of range for array of length 2`},
+		{`{a = 1, a = 2}`, new(any), `1:9: field "a" is defined more than once`},
+		{`[1, "two", 3]`, new([]int), `1:5: expected number, got string`},
+		{`{ a = [true] }`, new(map[string][]string), `1:8: expected string, got bool`},
+		{`list`, new([]int), `1:1: [1]: expected number, got string`},
+		{`300`, new(int8), `1:1: 300 cannot be represented as int8`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			expr, err := parser.ParseExpression(tc.input)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			err = vm.New(expr).Evaluate(scope, tc.into)
+			if err == nil {
+				t.Fatalf("expected error %q, got none", tc.expect)
+			}
+			if err.Error() != tc.expect {
+				t.Errorf("expected error %q, got %q", tc.expect, err.Error())
+			}
+		})
+	}
+}